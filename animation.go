@@ -0,0 +1,366 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// An Animation changes a scene over time through a set of tracks, and divides the time from
+// Start to End evenly into Frames frames.
+type Animation struct {
+	Tracks []Track
+	Frames int
+	Start  float64
+	End    float64
+}
+
+// NewAnimation creates a new Animation without any tracks.
+func NewAnimation(frames int, start float64, end float64) *Animation {
+	return &Animation{Frames: frames, Start: start, End: end}
+}
+
+// FrameTime returns the time of a frame. The first frame is at Start, and the last is one frame
+// before End, so animations that end where they start loop seamlessly.
+func (a *Animation) FrameTime(frame int) float64 {
+	if a.Frames <= 0 {
+		return a.Start
+	}
+
+	return a.Start + (a.End-a.Start)*float64(frame)/float64(a.Frames)
+}
+
+// Apply sets everything the animation changes to its state at a moment in time.
+func (a *Animation) Apply(time float64) {
+	for _, track := range a.Tracks {
+		track.Apply(time)
+	}
+}
+
+// A Track animates part of a scene through a series of keys, each holding its state at a moment
+// in time. Before the first key and after the last, the state holds still.
+type Track interface {
+	// Apply sets the animated part of the scene to its state at a moment in time.
+	Apply(time float64)
+}
+
+// Returns the indexes of the keys to blend between at a moment in time, and how far to blend
+// from the first toward the second, with the first key's easing applied. Keys are sorted by time.
+func findKeys(n int, key func(i int) (float64, Easing), time float64) (i int, j int, t float64) {
+	if n == 0 {
+		return -1, -1, 0
+	}
+
+	j = sort.Search(n, func(i int) bool {
+		keyTime, _ := key(i)
+		return keyTime > time
+	})
+
+	if j == 0 {
+		return 0, 0, 0
+	} else if j == n {
+		return n - 1, n - 1, 0
+	}
+
+	i = j - 1
+	start, easing := key(i)
+	end, _ := key(j)
+	t = (time - start) / (end - start)
+	if easing != nil {
+		t = easing.Ease(t)
+	}
+
+	return i, j, t
+}
+
+// A CameraKey is a camera's viewpoint at a moment in time.
+type CameraKey struct {
+	Time float64
+	From Tuple
+	To   Tuple
+	Up   Tuple
+
+	// Easing shapes the change from this key to the next. Nil means a linear change.
+	Easing Easing
+}
+
+// A CameraTrack moves a camera between viewpoints.
+type CameraTrack struct {
+	Camera *Camera
+	Keys   []CameraKey
+}
+
+// NewCameraTrack creates a new CameraTrack through the given keys, which are sorted by time.
+func NewCameraTrack(camera *Camera, keys ...CameraKey) *CameraTrack {
+	sorted := append([]CameraKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	return &CameraTrack{camera, sorted}
+}
+
+// Apply points the camera from its viewpoint at a moment in time.
+func (tr *CameraTrack) Apply(time float64) {
+	i, j, t := findKeys(len(tr.Keys), func(i int) (float64, Easing) {
+		return tr.Keys[i].Time, tr.Keys[i].Easing
+	}, time)
+
+	if i < 0 {
+		return
+	}
+
+	a, b := tr.Keys[i], tr.Keys[j]
+	from := lerpTuple(a.From, b.From, t)
+	to := lerpTuple(a.To, b.To, t)
+	up := lerpTuple(a.Up, b.Up, t)
+	tr.Camera.Transform = NewViewTransform(from, to, up)
+}
+
+// A TransformKey is a shape's transform at a moment in time.
+type TransformKey struct {
+	Time      float64
+	Transform Transformation
+
+	// Easing shapes the change from this key to the next. Nil means a linear change.
+	Easing Easing
+}
+
+// A TransformTrack moves a shape between transforms, which are interpolated the same way as a
+// Motion's, so keys of a spinning shape must be less than half a turn apart.
+type TransformTrack struct {
+	Shape Shape
+	Keys  []TransformKey
+}
+
+// NewTransformTrack creates a new TransformTrack through the given keys, which are sorted by time.
+// As with NewKeyframeMotion, each key's transform must be invertible, and a shape can't be
+// mirrored at one key and not the next.
+func NewTransformTrack(shape Shape, keys ...TransformKey) (*TransformTrack, error) {
+	sorted := append([]TransformKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	err := checkTransformKeys(len(sorted), func(i int) (float64, Transformation) {
+		return sorted[i].Time, sorted[i].Transform
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransformTrack{shape, sorted}, nil
+}
+
+// Apply sets the shape's transform at a moment in time.
+func (tr *TransformTrack) Apply(time float64) {
+	i, j, t := findKeys(len(tr.Keys), func(i int) (float64, Easing) {
+		return tr.Keys[i].Time, tr.Keys[i].Easing
+	}, time)
+
+	switch {
+	case i < 0:
+	case i == j:
+		tr.Shape.SetTransform(tr.Keys[i].Transform)
+	default:
+		a := decomposeTransform(tr.Keys[i].Transform)
+		b := decomposeTransform(tr.Keys[j].Transform)
+		tr.Shape.SetTransform(a.interpolate(b, t))
+	}
+}
+
+// A LightKey is a light's position at a moment in time.
+type LightKey struct {
+	Time     float64
+	Position Tuple
+
+	// Easing shapes the change from this key to the next. Nil means a linear change.
+	Easing Easing
+}
+
+// A LightTrack moves a point light between positions.
+type LightTrack struct {
+	Light *PointLight
+	Keys  []LightKey
+}
+
+// NewLightTrack creates a new LightTrack through the given keys, which are sorted by time.
+func NewLightTrack(light *PointLight, keys ...LightKey) *LightTrack {
+	sorted := append([]LightKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	return &LightTrack{light, sorted}
+}
+
+// Apply sets the light's position at a moment in time.
+func (tr *LightTrack) Apply(time float64) {
+	i, j, t := findKeys(len(tr.Keys), func(i int) (float64, Easing) {
+		return tr.Keys[i].Time, tr.Keys[i].Easing
+	}, time)
+
+	if i >= 0 {
+		tr.Light.Position = lerpTuple(tr.Keys[i].Position, tr.Keys[j].Position, t)
+	}
+}
+
+// A MaterialKey is the value of a material parameter at a moment in time. Colors have three
+// values and other parameters have one.
+type MaterialKey struct {
+	Time  float64
+	Value []float64
+
+	// Easing shapes the change from this key to the next. Nil means a linear change.
+	Easing Easing
+}
+
+// A MaterialTrack changes one parameter of a material, which is one of MaterialParameters.
+type MaterialTrack struct {
+	Material  *Material
+	Parameter string
+	Keys      []MaterialKey
+}
+
+// MaterialParameters are the names of the material parameters a MaterialTrack can change.
+var MaterialParameters = []string{"color", "ambient", "diffuse", "specular", "shininess", "emission"}
+
+// NewMaterialTrack creates a new MaterialTrack through the given keys, which are sorted by time.
+func NewMaterialTrack(material *Material, parameter string, keys ...MaterialKey) (*MaterialTrack, error) {
+	size, ok := materialParameterSize(parameter)
+	if !ok {
+		return nil, fmt.Errorf("unknown material parameter '%s'", parameter)
+	}
+
+	for _, key := range keys {
+		if len(key.Value) != size {
+			return nil, fmt.Errorf("material %s takes %d values, got %d", parameter, size, len(key.Value))
+		}
+	}
+
+	sorted := append([]MaterialKey(nil), keys...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	return &MaterialTrack{material, parameter, sorted}, nil
+}
+
+// Apply sets the material parameter at a moment in time.
+func (tr *MaterialTrack) Apply(time float64) {
+	i, j, t := findKeys(len(tr.Keys), func(i int) (float64, Easing) {
+		return tr.Keys[i].Time, tr.Keys[i].Easing
+	}, time)
+
+	if i < 0 {
+		return
+	}
+
+	a, b := tr.Keys[i].Value, tr.Keys[j].Value
+	value := make([]float64, len(a))
+	for k := range value {
+		value[k] = lerp(t, a[k], b[k])
+	}
+
+	m := tr.Material
+	switch tr.Parameter {
+	case "color":
+		m.Color = NewColor(value[0], value[1], value[2])
+	case "emission":
+		m.Emission = NewColor(value[0], value[1], value[2])
+	case "ambient":
+		m.Ambient = value[0]
+	case "diffuse":
+		m.Diffuse = value[0]
+	case "specular":
+		m.Specular = value[0]
+	case "shininess":
+		m.Shininess = value[0]
+	}
+}
+
+// Returns the number of values a material parameter takes, and whether it's a known parameter.
+func materialParameterSize(parameter string) (int, bool) {
+	switch parameter {
+	case "color", "emission":
+		return 3, true
+	case "ambient", "diffuse", "specular", "shininess":
+		return 1, true
+	}
+
+	return 0, false
+}
+
+// Returns the point or vector a fraction t of the way from a to b.
+func lerpTuple(a Tuple, b Tuple, t float64) Tuple {
+	return a.Add(b.Subtract(a).Multiply(t))
+}
+
+// An Easing shapes the change between two keys of a track.
+type Easing interface {
+	// Ease maps the fraction of the time between two keys that has passed, from 0 to 1, to the
+	// fraction of the change that has been made.
+	Ease(t float64) float64
+}
+
+// LinearEasing changes at a constant rate.
+type LinearEasing struct{}
+
+// Ease returns t.
+func (LinearEasing) Ease(t float64) float64 {
+	return t
+}
+
+// SmoothstepEasing starts and ends gently, following 3t² - 2t³.
+type SmoothstepEasing struct{}
+
+// Ease returns the smoothstep of t.
+func (SmoothstepEasing) Ease(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
+
+// A BezierEasing follows a cubic Bézier curve from (0, 0) to (1, 1) with control points
+// (X1, Y1) and (X2, Y2), like the CSS cubic-bezier() timing function. X1 and X2 are between 0 and 1.
+type BezierEasing struct {
+	X1, Y1 float64
+	X2, Y2 float64
+}
+
+// NewBezierEasing creates a new BezierEasing.
+func NewBezierEasing(x1 float64, y1 float64, x2 float64, y2 float64) *BezierEasing {
+	return &BezierEasing{x1, y1, x2, y2}
+}
+
+// Ease returns the height of the curve at t.
+func (e *BezierEasing) Ease(t float64) float64 {
+	if t <= 0 || t >= 1 {
+		return t
+	}
+
+	// x increases along the curve, so the curve parameter where x = t can be found by bisection
+	low, high := 0.0, 1.0
+	s := t
+	for i := 0; i < 50; i++ {
+		x := cubicBezier(e.X1, e.X2, s)
+		if math.Abs(x-t) < 1e-9 {
+			break
+		}
+
+		if x < t {
+			low = s
+		} else {
+			high = s
+		}
+
+		s = (low + high) / 2
+	}
+
+	return cubicBezier(e.Y1, e.Y2, s)
+}
+
+// Returns one coordinate of a cubic Bézier curve from 0 to 1 with control points p1 and p2.
+func cubicBezier(p1 float64, p2 float64, s float64) float64 {
+	r := 1 - s
+	return 3*r*r*s*p1 + 3*r*s*s*p2 + s*s*s
+}
@@ -0,0 +1,139 @@
+package rt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnimation_FrameTime(t *testing.T) {
+	a := NewAnimation(4, 1, 3)
+	assert.Equal(t, 1.0, a.FrameTime(0))
+	assert.Equal(t, 1.5, a.FrameTime(1))
+	assert.Equal(t, 2.5, a.FrameTime(3))
+
+	assert.Equal(t, 1.0, NewAnimation(0, 1, 3).FrameTime(2))
+}
+
+func TestAnimation_Apply(t *testing.T) {
+	light := NewPointLight(NewPoint(0, 0, 0), NewColor(1, 1, 1))
+	s := NewSphere()
+	transform, err := NewTransformTrack(s, TransformKey{0, NewTranslation(0, 0, 0), nil}, TransformKey{1, NewTranslation(0, 2, 0), nil})
+	require.NoError(t, err)
+	a := NewAnimation(10, 0, 1)
+	a.Tracks = []Track{
+		NewLightTrack(light, LightKey{0, NewPoint(0, 0, 0), nil}, LightKey{1, NewPoint(4, 0, 0), nil}),
+		transform,
+	}
+
+	a.Apply(.25)
+	assert.True(t, light.Position.Equals(NewPoint(1, 0, 0)))
+	assert.True(t, s.Transform.Equals(NewTranslation(0, .5, 0)))
+
+	// the state holds still outside the keys
+	a.Apply(2)
+	assert.True(t, light.Position.Equals(NewPoint(4, 0, 0)))
+	assert.True(t, s.Transform.Equals(NewTranslation(0, 2, 0)))
+	a.Apply(-1)
+	assert.True(t, light.Position.Equals(NewPoint(0, 0, 0)))
+	assert.True(t, s.Transform.Equals(NewTranslation(0, 0, 0)))
+}
+
+func TestCameraTrack_Apply(t *testing.T) {
+	c := NewCamera(10, 10, 1)
+	up := NewVector(0, 1, 0)
+	track := NewCameraTrack(c,
+		CameraKey{2, NewPoint(0, 0, 5), Origin(), up, nil},
+		CameraKey{0, NewPoint(0, 0, -5), Origin(), up, SmoothstepEasing{}},
+		CameraKey{1, NewPoint(0, 0, -3), Origin(), up, nil},
+	)
+
+	assert.Equal(t, []float64{0, 1, 2}, []float64{track.Keys[0].Time, track.Keys[1].Time, track.Keys[2].Time})
+	track.Apply(.5)
+	assert.True(t, c.Transform.Equals(NewViewTransform(NewPoint(0, 0, -4), Origin(), up)))
+	track.Apply(1.5)
+	assert.True(t, c.Transform.Equals(NewViewTransform(NewPoint(0, 0, 1), Origin(), up)))
+
+	// easing shapes the change from a key to the next
+	track.Apply(.25)
+	from := NewPoint(0, 0, -5+2*SmoothstepEasing{}.Ease(.25))
+	assert.True(t, c.Transform.Equals(NewViewTransform(from, Origin(), up)))
+}
+
+func TestTransformTrack_Apply(t *testing.T) {
+	s := NewSphere()
+	track, err := NewTransformTrack(s,
+		TransformKey{0, NewRotationY(0), nil},
+		TransformKey{1, NewRotationY(math.Pi / 2), nil},
+	)
+
+	require.NoError(t, err)
+
+	// shapes turn rather than shrink between keys
+	track.Apply(.5)
+	assert.True(t, s.Transform.Equals(NewRotationY(math.Pi/4)))
+
+	// as with motions, shapes can't be flattened, or mirrored on the way between keys
+	_, err = NewTransformTrack(s, TransformKey{0, NewTransform(), nil}, TransformKey{1, NewScaling(1, 0, 1), nil})
+	assert.EqualError(t, err, "transform at time 1 is not invertible")
+	_, err = NewTransformTrack(s, TransformKey{1, NewScaling(-1, 1, 1), nil}, TransformKey{0, NewScaling(1, 1, 1), nil})
+	assert.EqualError(t, err, "transforms at times 0 and 1 can't differ in whether they mirror the shape")
+}
+
+func TestMaterialTrack_Apply(t *testing.T) {
+	m := NewMaterial()
+	color, err := NewMaterialTrack(m, "color", MaterialKey{0, []float64{1, 0, 0}, nil}, MaterialKey{1, []float64{0, 0, 1}, nil})
+	require.NoError(t, err)
+	ambient, err := NewMaterialTrack(m, "ambient", MaterialKey{0, []float64{0}, nil}, MaterialKey{1, []float64{1}, nil})
+	require.NoError(t, err)
+
+	color.Apply(.5)
+	ambient.Apply(.5)
+	assert.True(t, m.Color.Equals(NewColor(.5, 0, .5)))
+	assert.Equal(t, .5, m.Ambient)
+
+	for _, parameter := range MaterialParameters {
+		size, ok := materialParameterSize(parameter)
+		require.True(t, ok)
+		track, err := NewMaterialTrack(m, parameter, MaterialKey{0, make([]float64, size), nil})
+		require.NoError(t, err)
+		track.Apply(0)
+	}
+
+	assert.True(t, m.Emission.Equals(NewColor(0, 0, 0)))
+	assert.Zero(t, m.Shininess)
+
+	_, err = NewMaterialTrack(m, "glossiness", MaterialKey{0, []float64{1}, nil})
+	assert.EqualError(t, err, "unknown material parameter 'glossiness'")
+	_, err = NewMaterialTrack(m, "color", MaterialKey{0, []float64{1}, nil})
+	assert.EqualError(t, err, "material color takes 3 values, got 1")
+}
+
+func TestEasing(t *testing.T) {
+	easings := []Easing{LinearEasing{}, SmoothstepEasing{}, NewBezierEasing(.42, 0, .58, 1), NewBezierEasing(.25, .1, .25, 1)}
+	for _, e := range easings {
+		assert.InDelta(t, 0, e.Ease(0), EPSILON, "%T", e)
+		assert.InDelta(t, 1, e.Ease(1), EPSILON, "%T", e)
+
+		// easings never reverse
+		previous := 0.0
+		for i := 1; i <= 100; i++ {
+			value := e.Ease(float64(i) / 100)
+			assert.True(t, value >= previous, "%T", e)
+			previous = value
+		}
+	}
+
+	assert.Equal(t, .25, LinearEasing{}.Ease(.25))
+	assert.Equal(t, .5, SmoothstepEasing{}.Ease(.5))
+	assert.InDelta(t, .15625, SmoothstepEasing{}.Ease(.25), EPSILON)
+
+	// a symmetric curve passes through the middle, and a straight one is linear
+	assert.InDelta(t, .5, NewBezierEasing(.42, 0, .58, 1).Ease(.5), 1e-6)
+	assert.InDelta(t, .3, NewBezierEasing(1.0/3, 1.0/3, 2.0/3, 2.0/3).Ease(.3), 1e-6)
+
+	// ease-in starts slowly
+	assert.True(t, NewBezierEasing(.42, 0, 1, 1).Ease(.25) < .25)
+}
@@ -0,0 +1,257 @@
+package rt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// PassNames are the names of the auxiliary passes, or AOVs (arbitrary output variables), that
+// can be rendered along with the image for compositing, denoising, and debugging:
+//
+//	depth        distance from the camera to the surface along its view axis
+//	normal       world space surface normal, facing the camera, from -1 to 1
+//	albedo       surface color before lighting
+//	object-id    position of the object in the world's objects, counting from 1
+//	material-id  position of the object's material among the distinct materials of the world's
+//	             objects, counting from 1
+//	uv           surface texture coordinates, as red and green
+//	shadow       1 where the surface is in shadow from the world's light
+//	direct       light emitted or reflected straight from light sources by the first surface hit
+//	indirect     light reflected by the first surface after bouncing, which adds to direct to
+//	             give the image
+//	intersection-tests
+//	             number of tests of rays against shapes made rendering the pixel
+//	time         nanoseconds spent rendering the pixel
+//
+// Each pass stores its value in all three color channels unless it has more than one. Passes are
+// 0 where no surface is hit, except for direct, which holds the environment seen there, and the
+// costs, intersection-tests and time, which count the work of all of the pixel's samples,
+// including the work of the other passes averaged over them. Depth, IDs, and UV come from the
+// ray through the center of each pixel; the others are averaged over the pixel's samples like
+// the image.
+var PassNames = []string{"depth", "normal", "albedo", "object-id", "material-id", "uv", "shadow", "direct", "indirect", "intersection-tests", "time"}
+
+// RenderPasses renders the image along with the named auxiliary passes (see PassNames), each
+// into its own canvas, in a single pass over the image. The image is the same as RenderContext's.
+func (c *Camera) RenderPasses(ctx context.Context, world *World, names []string, progress func(RenderProgress)) (*Canvas, map[string]*Canvas, error) {
+	passes, err := newRenderPasses(c, world, names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	canvas, err := c.render(ctx, world, renderOptions{passes: passes}, progress)
+	return canvas, passes.canvases, err
+}
+
+// A renderPasses holds the canvases of the auxiliary passes being rendered.
+type renderPasses struct {
+	canvases map[string]*Canvas
+
+	// the IDs of the world's objects and their materials
+	objectIDs   map[Shape]int
+	materialIDs map[*Material]int
+}
+
+func newRenderPasses(c *Camera, world *World, names []string) (*renderPasses, error) {
+	passes := &renderPasses{
+		canvases:    map[string]*Canvas{},
+		objectIDs:   map[Shape]int{},
+		materialIDs: map[*Material]int{},
+	}
+
+	for _, name := range names {
+		known := false
+		for _, passName := range PassNames {
+			known = known || name == passName
+		}
+
+		if !known {
+			return nil, fmt.Errorf("unknown render pass '%s'", name)
+		}
+
+		passes.canvases[name] = NewCanvas(c.HSize, c.VSize)
+	}
+
+	if passes.wants("direct", "indirect") {
+		if _, ok := c.integrator().(SplitIntegrator); !ok {
+			return nil, fmt.Errorf("%T can't split direct and indirect light", c.integrator())
+		}
+	}
+
+	for i, object := range world.Objects {
+		passes.objectIDs[object] = i + 1
+		if material := object.GetMaterial(); material != nil && passes.materialIDs[material] == 0 {
+			passes.materialIDs[material] = len(passes.materialIDs) + 1
+		}
+	}
+
+	return passes, nil
+}
+
+// Returns true if any of the named passes are being rendered.
+func (p *renderPasses) wants(names ...string) bool {
+	for _, name := range names {
+		if _, ok := p.canvases[name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sets a pixel of a pass, if it's being rendered.
+func (p *renderPasses) write(name string, x int, y int, color Color) {
+	if canvas, ok := p.canvases[name]; ok {
+		canvas.WritePixel(x, y, color)
+	}
+}
+
+// Adds a weighted color to a pixel of a pass, if it's being rendered.
+func (p *renderPasses) add(name string, x int, y int, color Color, weight float64) {
+	if canvas, ok := p.canvases[name]; ok {
+		sum := canvas.PixelAt(x, y)
+		if sum == nil {
+			sum = NewColor(0, 0, 0)
+		}
+
+		canvas.WritePixel(x, y, sum.Add(color.Multiply(weight)))
+	}
+}
+
+// Renders the passes that come from the ray through the center of a pixel.
+func (p *renderPasses) renderPixel(c *Camera, world *World, x int, y int) {
+	if !p.wants("depth", "object-id", "material-id", "uv") {
+		return
+	}
+
+	ray := c.RayForPixel(x, y)
+	ray.Time = c.ShutterOpen
+	depth, objectID, materialID, uv := 0.0, 0.0, 0.0, NewColor(0, 0, 0)
+	if hit := world.Intersect(ray).Hit(); hit != nil {
+		info := hit.PrepareComputations(ray)
+		depth = -c.Transform.ApplyTo(info.Point).Z()
+		objectID = float64(p.objectIDs[hit.Object])
+		materialID = float64(p.materialIDs[hit.Object.GetMaterial()])
+		u, v := surfaceUV(info.Object, info.Point)
+		uv = NewColor(u, v, 0)
+	}
+
+	p.write("depth", x, y, NewColor(depth, depth, depth))
+	p.write("object-id", x, y, NewColor(objectID, objectID, objectID))
+	p.write("material-id", x, y, NewColor(materialID, materialID, materialID))
+	p.write("uv", x, y, uv)
+}
+
+// Returns the light arriving along a camera ray through a pixel, and adds the ray's share of the
+// pixel to the passes that are averaged over its samples.
+func (p *renderPasses) renderSample(world *World, integrator Integrator, ray *Ray, rng *rand.Rand, x int, y int, weight float64) Color {
+	if p.wants("normal", "albedo", "shadow") {
+		if hit := world.Intersect(ray).Hit(); hit != nil {
+			info := hit.PrepareComputations(ray)
+			p.add("normal", x, y, Color(info.NormalV), weight)
+			p.add("albedo", x, y, info.Object.GetMaterial().AlbedoAt(info.Object, info.Point), weight)
+			if world.Light != nil && world.isOccluded(info.OverPoint, world.Light.Position, info.Time, info.stats) {
+				p.add("shadow", x, y, NewColor(1, 1, 1), weight)
+			}
+		}
+
+		// pixels that miss every surface are still written
+		p.add("normal", x, y, NewColor(0, 0, 0), 0)
+		p.add("albedo", x, y, NewColor(0, 0, 0), 0)
+		p.add("shadow", x, y, NewColor(0, 0, 0), 0)
+	}
+
+	if !p.wants("direct", "indirect") {
+		return integrator.Radiance(world, ray, rng)
+	}
+
+	direct, indirect := integrator.(SplitIntegrator).RadianceSplit(world, ray, rng)
+	p.add("direct", x, y, direct, weight)
+	p.add("indirect", x, y, indirect, weight)
+	return direct.Add(indirect)
+}
+
+// Returns the texture coordinates of a point on a shape, which are spherical for spheres and
+// planar for other shapes.
+func surfaceUV(shape Shape, point Tuple) (u float64, v float64) {
+	objectPoint := shape.GetTransform().Inverse().ApplyTo(point)
+	if _, ok := unfrozenShape(shape).(*Sphere); ok {
+		return SphericalMap(objectPoint)
+	}
+
+	return PlanarMap(objectPoint)
+}
+
+// DisplayPass returns a copy of a pass's canvas for viewing: normals are mapped from [-1, 1] to
+// [0, 1], depth is scaled so that the nearest surfaces are brightest, each ID is given its own
+// color, and costs are drawn as a heatmap with a legend below (see Heatmap). Other passes are
+// returned as they are.
+func DisplayPass(name string, pass *Canvas) *Canvas {
+	if name == "intersection-tests" || name == "time" {
+		return Heatmap(pass)
+	}
+
+	display := NewCanvas(pass.Width(), pass.Height())
+	maxDepth := 0.0
+	if name == "depth" {
+		for y := 0; y < pass.Height(); y++ {
+			for x := 0; x < pass.Width(); x++ {
+				if color := pass.PixelAt(x, y); color != nil {
+					maxDepth = math.Max(maxDepth, color.Red())
+				}
+			}
+		}
+	}
+
+	for y := 0; y < pass.Height(); y++ {
+		for x := 0; x < pass.Width(); x++ {
+			color := pass.PixelAt(x, y)
+			if color == nil {
+				continue
+			}
+
+			switch name {
+			case "normal":
+				color = color.Add(NewColor(1, 1, 1)).Multiply(.5)
+			case "depth":
+				if d := color.Red(); d > 0 {
+					value := 1 - .9*d/maxDepth
+					color = NewColor(value, value, value)
+				}
+			case "object-id", "material-id":
+				color = idColor(int(color.Red()))
+			}
+
+			display.WritePixel(x, y, color)
+		}
+	}
+
+	return display
+}
+
+// Returns a color for an ID that is easy to tell from the colors of nearby IDs, or black for 0.
+func idColor(id int) Color {
+	if id <= 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	// step around the color wheel by the golden angle, so consecutive hues are far apart
+	hue := math.Mod(float64(id)*0.618033988749895, 1) * 6
+	x := 1 - math.Abs(math.Mod(hue, 2)-1)
+	switch int(hue) {
+	case 0:
+		return NewColor(1, x, 0)
+	case 1:
+		return NewColor(x, 1, 0)
+	case 2:
+		return NewColor(0, 1, x)
+	case 3:
+		return NewColor(0, x, 1)
+	case 4:
+		return NewColor(x, 0, 1)
+	}
+
+	return NewColor(1, 0, x)
+}
@@ -0,0 +1,117 @@
+package rt
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamera_RenderPasses(t *testing.T) {
+	w := NewDefaultWorld()
+	floor := NewPlane()
+	floor.SetTransform(NewTranslation(0, -1, 0))
+	w.AddObjects(floor)
+
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	image, passes, err := c.RenderPasses(context.Background(), w, PassNames, nil)
+	require.NoError(t, err)
+	assert.Len(t, passes, len(PassNames))
+
+	// the image is the same as an ordinary render
+	assert.Equal(t, c.Render(w), image)
+
+	// the middle pixel sees the front of the outer sphere
+	assert.True(t, NewColor(4, 4, 4).Equals(passes["depth"].PixelAt(5, 5)))
+	assert.True(t, NewColor(0, 0, -1).Equals(passes["normal"].PixelAt(5, 5)))
+	assert.True(t, NewColor(.8, 1, .6).Equals(passes["albedo"].PixelAt(5, 5)))
+	assert.Equal(t, NewColor(1, 1, 1), passes["object-id"].PixelAt(5, 5))
+	assert.Equal(t, NewColor(1, 1, 1), passes["material-id"].PixelAt(5, 5))
+	assert.InDelta(t, .5, passes["uv"].PixelAt(5, 5).Green(), 1e-5)
+	assert.Equal(t, NewColor(0, 0, 0), passes["shadow"].PixelAt(5, 5))
+
+	// the bottom row sees the floor, and the top corner sees nothing
+	assert.Equal(t, NewColor(3, 3, 3), passes["object-id"].PixelAt(0, 10))
+	assert.Equal(t, NewColor(3, 3, 3), passes["material-id"].PixelAt(0, 10))
+	for _, name := range PassNames {
+		if name != "direct" && name != "intersection-tests" && name != "time" {
+			assert.Equal(t, NewColor(0, 0, 0), passes[name].PixelAt(0, 0), name)
+		}
+	}
+
+	// even pixels that miss everything cost something
+	assert.True(t, passes["intersection-tests"].PixelAt(0, 0).Red() > 0)
+	assert.True(t, passes["time"].PixelAt(0, 0).Red() > 0)
+
+	// the sphere shadows part of the floor
+	shadowed := 0
+	for y := 0; y < 11; y++ {
+		for x := 0; x < 11; x++ {
+			if passes["object-id"].PixelAt(x, y).Red() == 3 && passes["shadow"].PixelAt(x, y).Red() > 0 {
+				shadowed++
+			}
+		}
+	}
+
+	assert.NotZero(t, shadowed)
+}
+
+func TestCamera_RenderPasses_direct(t *testing.T) {
+	world, c := newCornellBox(8)
+	c.Samples = 2
+	c.Integrator.(*PathTracer).MaxDepth = 3
+	for _, integrator := range []Integrator{NewWhittedIntegrator(), c.Integrator} {
+		c.Integrator = integrator
+		image, passes, err := c.RenderPasses(context.Background(), world, []string{"direct", "indirect"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, c.Render(world), image)
+
+		// direct and indirect light add up to the image
+		indirect := 0.0
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				sum := passes["direct"].PixelAt(x, y).Add(passes["indirect"].PixelAt(x, y))
+				assert.True(t, image.PixelAt(x, y).Equals(sum), "%T %d %d", integrator, x, y)
+				indirect += passes["indirect"].PixelAt(x, y).Red()
+			}
+		}
+
+		// with no ambient light, only the path tracer's bounces light the box indirectly
+		if _, ok := integrator.(*PathTracer); ok {
+			assert.NotZero(t, indirect)
+		} else {
+			assert.Zero(t, indirect)
+		}
+	}
+}
+
+func TestCamera_RenderPasses_errors(t *testing.T) {
+	c := NewCamera(4, 4, math.Pi/2)
+	_, _, err := c.RenderPasses(context.Background(), NewDefaultWorld(), []string{"depth", "motion"}, nil)
+	assert.EqualError(t, err, "unknown render pass 'motion'")
+}
+
+func TestDisplayPass(t *testing.T) {
+	pass := NewCanvas(3, 1)
+	pass.WritePixel(0, 0, NewColor(0, 0, 0))
+	pass.WritePixel(1, 0, NewColor(2, 2, 2))
+	pass.WritePixel(2, 0, NewColor(4, 4, 4))
+
+	// nearer surfaces are brighter, and empty pixels stay black
+	depth := DisplayPass("depth", pass)
+	assert.Equal(t, NewColor(0, 0, 0), depth.PixelAt(0, 0))
+	assert.True(t, NewColor(.55, .55, .55).Equals(depth.PixelAt(1, 0)))
+	assert.True(t, NewColor(.1, .1, .1).Equals(depth.PixelAt(2, 0)))
+
+	normal := DisplayPass("normal", pass)
+	assert.Equal(t, NewColor(.5, .5, .5), normal.PixelAt(0, 0))
+
+	ids := DisplayPass("object-id", pass)
+	assert.Equal(t, NewColor(0, 0, 0), ids.PixelAt(0, 0))
+	assert.NotEqual(t, ids.PixelAt(1, 0), ids.PixelAt(2, 0))
+
+	assert.Equal(t, pass, DisplayPass("albedo", pass))
+}
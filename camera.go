@@ -13,8 +13,39 @@ type Camera struct {
 	HalfHeight float64
 	PixelSize  float64
 	Transform  Transformation
+
+	// Samples is the number of samples taken along each axis of a pixel, so each pixel
+	// is the average of Samples * Samples rays spread evenly across it.
+	Samples int
+
+	// Threads is the number of goroutines used to render. Zero means one per CPU.
+	Threads int
+
+	// TileSize is the width and height, in pixels, of the square tiles the image is
+	// divided into for rendering. Zero means DefaultTileSize.
+	TileSize int
+
+	// Integrator computes the light arriving along each ray. Nil means a WhittedIntegrator.
+	Integrator Integrator
+
+	// ShutterOpen and ShutterClose are the times the shutter opens and closes. Each camera ray
+	// is traced at a time between them, so moving shapes are blurred along their motion.
+	ShutterOpen  float64
+	ShutterClose float64
+
+	// Sampler chooses where in each pixel, and when while the shutter is open, the pixel's rays
+	// are traced. Nil means a StratifiedSampler.
+	Sampler Sampler
+
+	// Seed seeds every random number drawn during a render. Each sample's numbers are seeded by
+	// the seed, the sample's pixel, and its index, so a render with the same seed is identical
+	// however many threads render it and in whatever order its tiles are rendered.
+	Seed int64
 }
 
+// DefaultTileSize is the tile size used when a Camera doesn't specify one.
+const DefaultTileSize = 16
+
 // NewCamera creates a new Camera
 func NewCamera(hSize int, vSize int, fov float64) *Camera {
 	camera := &Camera{
@@ -22,6 +53,7 @@ func NewCamera(hSize int, vSize int, fov float64) *Camera {
 		VSize:     vSize,
 		FOV:       fov,
 		Transform: NewTransform(),
+		Samples:   1,
 	}
 
 	halfView := math.Tan(camera.FOV / 2)
@@ -41,41 +73,19 @@ func NewCamera(hSize int, vSize int, fov float64) *Camera {
 
 // RayForPixel returns a Ray that starts at the camera and passes through the pixel at x, y on the canvas.
 func (c *Camera) RayForPixel(x int, y int) *Ray {
-	px := float64(x)
-	py := float64(y)
-	xOffset := (px + .5) * c.PixelSize
-	yOffset := (py + .5) * c.PixelSize
+	return c.rayForPixelOffset(x, y, .5, .5)
+}
+
+// Returns a Ray that starts at the camera and passes through the pixel at x, y, offset
+// from the pixel's corner by dx, dy (each between 0 and 1).
+func (c *Camera) rayForPixelOffset(x int, y int, dx float64, dy float64) *Ray {
+	xOffset := (float64(x) + dx) * c.PixelSize
+	yOffset := (float64(y) + dy) * c.PixelSize
 	worldX := c.HalfWidth - xOffset
 	worldY := c.HalfHeight - yOffset
-	pixel := c.Transform.Inverse().ApplyTo(NewPoint(worldX, worldY, -1))
-	origin := c.Transform.Inverse().ApplyTo(Origin())
+	inverse := c.Transform.Inverse()
+	pixel := inverse.ApplyTo(NewPoint(worldX, worldY, -1))
+	origin := inverse.ApplyTo(Origin())
 	direction := pixel.Subtract(origin).Normalize()
 	return NewRay(origin, direction)
 }
-
-// Render renders the specified world.
-func (c *Camera) Render(world *World) *Canvas {
-	pixelDoneCh := make(chan int)
-	image := NewCanvas(c.HSize, c.VSize)
-	for y := 0; y < c.VSize; y++ {
-		for x := 0; x < c.HSize; x++ {
-			// compute the pixels in parallel
-			go func(x, y int) {
-				ray := c.RayForPixel(x, y)
-				color := world.ColorAt(ray)
-				image.WritePixel(x, y, color)
-				pixelDoneCh <- 1
-			}(x, y)
-		}
-	}
-
-	// wait for all pixels to be written
-	for pixelsWritten := 0; pixelsWritten < c.HSize*c.VSize; {
-		select {
-		case <-pixelDoneCh:
-			pixelsWritten++
-		}
-	}
-
-	return image
-}
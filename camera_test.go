@@ -13,6 +13,8 @@ func TestNewCamera(t *testing.T) {
 	assert.Equal(t, 120, c.VSize)
 	assert.Equal(t, math.Pi/2, c.FOV)
 	assert.Equal(t, NewTransform(), c.Transform)
+	assert.Equal(t, 1, c.Samples)
+	assert.Equal(t, 0, c.Threads)
 }
 
 func TestCamera_GetPixelSize(t *testing.T) {
@@ -45,14 +47,3 @@ func TestCamera_RayForPixel(t *testing.T) {
 	assert.True(t, r.Origin.Equals(NewPoint(0, 2, -5)))
 	assert.True(t, r.Direction.Equals(NewVector(math.Sqrt2/2, 0, -math.Sqrt2/2)))
 }
-
-func TestCamera_Render(t *testing.T) {
-	w := NewDefaultWorld()
-	c := NewCamera(11, 11, math.Pi/2)
-	from := NewPoint(0, 0, -5)
-	to := Origin()
-	up := NewVector(0, 1, 0)
-	c.Transform = NewViewTransform(from, to, up)
-	image := c.Render(w)
-	assert.True(t, image.PixelAt(5, 5).Equals(NewColor(.38066, .47583, .2855)))
-}
@@ -1,8 +1,24 @@
 package rt
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"hash"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // A Canvas is a grid of pixels.
@@ -22,6 +38,195 @@ func NewCanvas(width int, height int) *Canvas {
 	return &Canvas{width, height, pixels}
 }
 
+// NewCanvasFromImage creates a new Canvas with the same pixels as an image, scaling each color
+// channel to [0, 1].
+func NewCanvasFromImage(img image.Image) *Canvas {
+	bounds := img.Bounds()
+	canvas := NewCanvas(bounds.Dx(), bounds.Dy())
+	for y := 0; y < canvas.height; y++ {
+		for x := 0; x < canvas.width; x++ {
+			pixel := color.NRGBA64Model.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA64)
+			canvas.pixels[y][x] = NewColor(float64(pixel.R)/0xffff, float64(pixel.G)/0xffff, float64(pixel.B)/0xffff)
+		}
+	}
+
+	return canvas
+}
+
+// LoadCanvas reads an image file, choosing the format (PPM, PNG, or Radiance HDR) from the
+// file's extension.
+func LoadCanvas(filename string) (*Canvas, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ppm":
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		canvas, err := ParsePPM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+
+		return canvas, nil
+	case ".png":
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+		img, err := png.Decode(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+
+		return NewCanvasFromImage(img), nil
+	case ".hdr":
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		canvas, err := ParseHDR(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+
+		return canvas, nil
+	}
+
+	return nil, fmt.Errorf("%s: unsupported image format; use .ppm, .png, or .hdr", filename)
+}
+
+// ParsePPM creates a new Canvas from a PPM image in either the plain (P3) or raw (P6) format.
+func ParsePPM(data []byte) (*Canvas, error) {
+	r := &ppmReader{data: data}
+	magic := r.token()
+	if magic != "P3" && magic != "P6" {
+		return nil, fmt.Errorf("not a PPM image")
+	}
+
+	var header [3]int
+	for i := range header {
+		value, err := strconv.Atoi(r.token())
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid PPM header")
+		}
+
+		header[i] = value
+	}
+
+	width, height, maxValue := header[0], header[1], header[2]
+	if maxValue > 65535 {
+		return nil, fmt.Errorf("invalid PPM maximum color value %d", maxValue)
+	}
+
+	// a raw image's samples start after the single whitespace character ending the header
+	r.pos++
+	sample := r.plainSample
+	if magic == "P6" {
+		sample = r.rawSample(maxValue > 255)
+	}
+
+	// a raw sample takes one or two bytes, and a plain one at least a digit and a space, so the
+	// header can't claim more pixels than there's data for, which would be allocated for nothing
+	samples := (len(data) - r.pos + 1) / 2
+	if magic == "P6" {
+		samples = len(data) - r.pos
+		if maxValue > 255 {
+			samples /= 2
+		}
+	}
+
+	if height > samples/3 || width > samples/3/height {
+		return nil, fmt.Errorf("PPM image is missing pixel data")
+	}
+
+	canvas := NewCanvas(width, height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var rgb [3]float64
+			for i := range rgb {
+				value, ok := sample()
+				if !ok {
+					return nil, fmt.Errorf("PPM image is missing pixel data")
+				}
+
+				rgb[i] = float64(value) / float64(maxValue)
+			}
+
+			canvas.pixels[y][x] = NewColor(rgb[0], rgb[1], rgb[2])
+		}
+	}
+
+	return canvas, nil
+}
+
+// A ppmReader reads the whitespace-separated tokens and samples of a PPM image.
+type ppmReader struct {
+	data []byte
+	pos  int
+}
+
+// Returns the next token, skipping whitespace and comments.
+func (r *ppmReader) token() string {
+	for r.pos < len(r.data) {
+		if r.data[r.pos] == '#' {
+			end := bytes.IndexByte(r.data[r.pos:], '\n')
+			if end < 0 {
+				r.pos = len(r.data)
+				break
+			}
+
+			r.pos += end
+		} else if !isPPMSpace(r.data[r.pos]) {
+			break
+		}
+
+		r.pos++
+	}
+
+	start := r.pos
+	for r.pos < len(r.data) && !isPPMSpace(r.data[r.pos]) {
+		r.pos++
+	}
+
+	return string(r.data[start:r.pos])
+}
+
+// Returns the next sample of a plain image.
+func (r *ppmReader) plainSample() (int, bool) {
+	value, err := strconv.Atoi(r.token())
+	return value, err == nil
+}
+
+// Returns a function that reads the next sample of a raw image, which is two bytes wide when wide is true.
+func (r *ppmReader) rawSample(wide bool) func() (int, bool) {
+	size := 1
+	if wide {
+		size = 2
+	}
+
+	return func() (int, bool) {
+		if r.pos+size > len(r.data) {
+			return 0, false
+		}
+
+		value := int(r.data[r.pos])
+		if wide {
+			value = value<<8 | int(r.data[r.pos+1])
+		}
+
+		r.pos += size
+		return value, true
+	}
+}
+
+func isPPMSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f'
+}
+
 // Width returns the width of the canvas in pixels.
 func (c *Canvas) Width() int {
 	return c.width
@@ -42,6 +247,75 @@ func (c *Canvas) WritePixel(x int, y int, color Color) {
 	c.pixels[y][x] = color
 }
 
+// ToneMap creates a new Canvas by applying a ToneMapper to every pixel of this one.
+func (c *Canvas) ToneMap(mapper ToneMapper) *Canvas {
+	mapped := NewCanvas(c.width, c.height)
+	for y, row := range c.pixels {
+		for x, color := range row {
+			if color != nil {
+				mapped.pixels[y][x] = mapper.Map(color)
+			}
+		}
+	}
+
+	return mapped
+}
+
+// Crop creates a new Canvas from the pixels within a rectangle of this one.
+func (c *Canvas) Crop(r image.Rectangle) *Canvas {
+	r = r.Intersect(image.Rect(0, 0, c.width, c.height))
+	cropped := NewCanvas(r.Dx(), r.Dy())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		copy(cropped.pixels[y-r.Min.Y], c.pixels[y][r.Min.X:r.Max.X])
+	}
+
+	return cropped
+}
+
+// Paste copies another canvas onto this one with its top left corner at a point, clipping the
+// parts that fall outside of this canvas.
+func (c *Canvas) Paste(other *Canvas, at image.Point) {
+	r := image.Rect(0, 0, other.width, other.height).Add(at).Intersect(image.Rect(0, 0, c.width, c.height))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		copy(c.pixels[y][r.Min.X:r.Max.X], other.pixels[y-at.Y][r.Min.X-at.X:])
+	}
+}
+
+// Returns a copy of the canvas.
+func (c *Canvas) clone() *Canvas {
+	clone := NewCanvas(c.width, c.height)
+	for y, row := range c.pixels {
+		copy(clone.pixels[y], row)
+	}
+
+	return clone
+}
+
+// Writes the canvas's size and the red, green, and blue of its pixels to a hash. Pixels that
+// were never written are black.
+func (c *Canvas) hash(h hash.Hash) {
+	buf := make([]byte, 8)
+	write := func(value uint64) {
+		binary.LittleEndian.PutUint64(buf, value)
+		h.Write(buf)
+	}
+
+	write(uint64(c.width))
+	write(uint64(c.height))
+	for _, row := range c.pixels {
+		for _, pixel := range row {
+			for i := 0; i < 3; i++ {
+				value := 0.0
+				if pixel != nil {
+					value = pixel[i]
+				}
+
+				write(math.Float64bits(value))
+			}
+		}
+	}
+}
+
 // ToPPM produces a PPM-formatted string from this canvas.
 func (c *Canvas) ToPPM() string {
 	builder := strings.Builder{}
@@ -66,3 +340,106 @@ func (c *Canvas) ToPPM() string {
 
 	return builder.String()
 }
+
+// ToImage converts this canvas to an 8-bit image, clamping each color channel to [0, 1].
+func (c *Canvas) ToImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, c.width, c.height))
+	for y, row := range c.pixels {
+		for x, pixel := range row {
+			if pixel == nil {
+				pixel = NewColor(0, 0, 0)
+			}
+
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: toByte(pixel.Red()),
+				G: toByte(pixel.Green()),
+				B: toByte(pixel.Blue()),
+				A: 255,
+			})
+		}
+	}
+
+	return img
+}
+
+// Save writes this canvas to a file, choosing the format (PPM or PNG) from the file's extension.
+func (c *Canvas) Save(filename string) error {
+	if err := CheckSaveFormat(filename); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ppm":
+		return ioutil.WriteFile(filename, []byte(c.ToPPM()), 0644)
+	case ".png":
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+
+		if err := png.Encode(file, c.ToImage()); err != nil {
+			file.Close()
+			return err
+		}
+
+		return file.Close()
+	}
+
+	return nil
+}
+
+// CheckSaveFormat returns an error if Save can't save an image in the format of a file's
+// extension, so that it can be checked before the image is rendered.
+func CheckSaveFormat(filename string) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ppm", ".png":
+		return nil
+	}
+
+	return fmt.Errorf("%s: unsupported image format; use .ppm or .png", filename)
+}
+
+// EncodeGIF writes canvases as the frames of a looping animated GIF, showing each frame for the
+// given duration. Colors are dithered to a fixed 256-color palette.
+func EncodeGIF(w io.Writer, frames []*Canvas, frameDuration time.Duration) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("animated GIF has no frames")
+	}
+
+	// GIF frame delays are in hundredths of a second
+	delay := int(frameDuration.Round(10*time.Millisecond) / (10 * time.Millisecond))
+	if delay < 1 {
+		delay = 1
+	}
+
+	animation := &gif.GIF{}
+	for _, frame := range frames {
+		img := frame.ToImage()
+		paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, image.Point{})
+		animation.Image = append(animation.Image, paletted)
+		animation.Delay = append(animation.Delay, delay)
+	}
+
+	return gif.EncodeAll(w, animation)
+}
+
+// SaveGIF writes canvases to a file as the frames of an animated GIF; see EncodeGIF.
+func SaveGIF(filename string, frames []*Canvas, frameDuration time.Duration) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := EncodeGIF(file, frames, frameDuration); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Converts a color channel to a byte the same way ToPPM does.
+func toByte(value float64) uint8 {
+	return uint8(math.Ceil(clamp(value*255, 0, 255)))
+}
@@ -1,9 +1,19 @@
 package rt
 
 import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewCanvas(t *testing.T) {
@@ -19,6 +29,42 @@ func TestCanvas_WritePixel_PixelAt(t *testing.T) {
 	assert.Equal(t, red, c.PixelAt(2, 3))
 }
 
+func TestCanvas_ToneMap(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.WritePixel(0, 0, NewColor(1, 1, 1))
+	mapped := c.ToneMap(NewReinhardToneMapper())
+	assert.Equal(t, 2, mapped.Width())
+	assert.Equal(t, 1, mapped.Height())
+	assert.True(t, mapped.PixelAt(0, 0).Equals(NewColor(.5, .5, .5)))
+	assert.Nil(t, mapped.PixelAt(1, 0))
+
+	// the original canvas is untouched
+	assert.True(t, c.PixelAt(0, 0).Equals(NewColor(1, 1, 1)))
+}
+
+func TestCanvas_Crop_Paste(t *testing.T) {
+	c := NewCanvas(4, 3)
+	c.WritePixel(1, 1, NewColor(1, 0, 0))
+	c.WritePixel(2, 2, NewColor(0, 1, 0))
+
+	cropped := c.Crop(image.Rect(1, 1, 3, 5))
+	assert.Equal(t, 2, cropped.Width())
+	assert.Equal(t, 2, cropped.Height())
+	assert.Equal(t, NewColor(1, 0, 0), cropped.PixelAt(0, 0))
+	assert.Equal(t, NewColor(0, 1, 0), cropped.PixelAt(1, 1))
+
+	// pasting clips at the edges
+	c = NewCanvas(4, 3)
+	c.Paste(cropped, image.Pt(2, 0))
+	assert.Equal(t, NewColor(1, 0, 0), c.PixelAt(2, 0))
+	assert.Equal(t, NewColor(0, 1, 0), c.PixelAt(3, 1))
+	assert.Nil(t, c.PixelAt(1, 0))
+	c.Paste(cropped, image.Pt(-1, 2))
+	assert.Nil(t, c.PixelAt(0, 2))
+	c.Paste(cropped, image.Pt(-1, -1))
+	assert.Equal(t, NewColor(0, 1, 0), c.PixelAt(0, 0))
+}
+
 func TestCanvas_ToPPM(t *testing.T) {
 	c := NewCanvas(5, 3)
 
@@ -48,3 +94,144 @@ func TestCanvas_ToPPM(t *testing.T) {
 
 	assert.Equal(t, expected, c.ToPPM())
 }
+
+func TestCanvas_ToImage(t *testing.T) {
+	c := NewCanvas(3, 2)
+	c.WritePixel(0, 0, NewColor(1.5, 0, 0))
+	c.WritePixel(1, 0, NewColor(0, .5, 0))
+	c.WritePixel(2, 1, NewColor(-.5, 0, 1))
+
+	img := c.ToImage()
+	assert.Equal(t, 3, img.Bounds().Dx())
+	assert.Equal(t, 2, img.Bounds().Dy())
+	assert.Equal(t, [4]uint8{255, 0, 0, 255}, nrgba(img.NRGBAAt(0, 0)))
+	assert.Equal(t, [4]uint8{0, 128, 0, 255}, nrgba(img.NRGBAAt(1, 0)))
+	assert.Equal(t, [4]uint8{0, 0, 255, 255}, nrgba(img.NRGBAAt(2, 1)))
+	assert.Equal(t, [4]uint8{0, 0, 0, 255}, nrgba(img.NRGBAAt(0, 1)))
+}
+
+func TestCanvas_Save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canvas")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewCanvas(5, 3)
+	c.WritePixel(1, 1, NewColor(1, 0, 0))
+
+	filename := filepath.Join(dir, "image.ppm")
+	require.NoError(t, c.Save(filename))
+	data, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, c.ToPPM(), string(data))
+
+	filename = filepath.Join(dir, "image.PNG")
+	require.NoError(t, c.Save(filename))
+	file, err := os.Open(filename)
+	require.NoError(t, err)
+	defer file.Close()
+	img, err := png.Decode(file)
+	require.NoError(t, err)
+	r, g, b, _ := img.At(1, 1).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b})
+
+	assert.Error(t, c.Save(filepath.Join(dir, "image.bmp")))
+}
+
+func TestEncodeGIF(t *testing.T) {
+	red, blue := NewCanvas(4, 2), NewCanvas(4, 2)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			red.WritePixel(x, y, NewColor(1, 0, 0))
+			blue.WritePixel(x, y, NewColor(0, 0, 1))
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeGIF(&buf, []*Canvas{red, blue}, 40*time.Millisecond))
+	decoded, err := gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	require.Len(t, decoded.Image, 2)
+	assert.Equal(t, []int{4, 4}, decoded.Delay)
+	assert.Equal(t, 4, decoded.Image[0].Bounds().Dx())
+	assert.Equal(t, color.RGBA{255, 0, 0, 255}, color.RGBAModel.Convert(decoded.Image[0].At(1, 1)))
+	assert.Equal(t, color.RGBA{0, 0, 255, 255}, color.RGBAModel.Convert(decoded.Image[1].At(1, 1)))
+
+	// frames are shown for at least a hundredth of a second
+	buf.Reset()
+	require.NoError(t, EncodeGIF(&buf, []*Canvas{red}, time.Millisecond))
+	decoded, err = gif.DecodeAll(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, decoded.Delay)
+
+	assert.EqualError(t, EncodeGIF(&buf, nil, time.Second), "animated GIF has no frames")
+}
+
+func TestLoadCanvas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canvas")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewCanvas(3, 2)
+	c.WritePixel(0, 0, NewColor(1, 0, 0))
+	c.WritePixel(2, 1, NewColor(0, 1, .2))
+	for _, name := range []string{"image.ppm", "image.png"} {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, c.Save(filename))
+		loaded, err := LoadCanvas(filename)
+		require.NoError(t, err, name)
+		assert.Equal(t, 3, loaded.Width(), name)
+		assert.Equal(t, 2, loaded.Height(), name)
+		assert.True(t, loaded.PixelAt(0, 0).Equals(NewColor(1, 0, 0)), name)
+		assert.True(t, loaded.PixelAt(1, 0).Equals(black), name)
+		assert.True(t, loaded.PixelAt(2, 1).Equals(NewColor(0, 1, 51.0/255)), name)
+	}
+
+	_, err = LoadCanvas(filepath.Join(dir, "missing.png"))
+	assert.Error(t, err)
+	_, err = LoadCanvas(filepath.Join(dir, "image.bmp"))
+	assert.EqualError(t, err, filepath.Join(dir, "image.bmp")+": unsupported image format; use .ppm, .png, or .hdr")
+}
+
+func TestParsePPM(t *testing.T) {
+	plain := "P3\n# a comment\n2 1 # another comment\n10\n10 0 0  0 5 10\n"
+	c, err := ParsePPM([]byte(plain))
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Width())
+	assert.Equal(t, 1, c.Height())
+	assert.True(t, c.PixelAt(0, 0).Equals(NewColor(1, 0, 0)))
+	assert.True(t, c.PixelAt(1, 0).Equals(NewColor(0, .5, 1)))
+
+	raw := append([]byte("P6 2 1 255\n"), 255, 0, 0, 0, 51, 255)
+	c, err = ParsePPM(raw)
+	require.NoError(t, err)
+	assert.True(t, c.PixelAt(0, 0).Equals(NewColor(1, 0, 0)))
+	assert.True(t, c.PixelAt(1, 0).Equals(NewColor(0, .2, 1)))
+
+	// samples are two bytes wide when the maximum value is over 255
+	wide := append([]byte("P6 1 1 65535\n"), 0xff, 0xff, 0x80, 0x00, 0, 0)
+	c, err = ParsePPM(wide)
+	require.NoError(t, err)
+	assert.True(t, c.PixelAt(0, 0).Equals(NewColor(1, float64(0x8000)/0xffff, 0)))
+
+	tests := map[string]string{
+		"P5 1 1 255\n\x00":  "not a PPM image",
+		"P3 1 x 255\n":      "invalid PPM header",
+		"P3 1 1 70000\n":    "invalid PPM maximum color value 70000",
+		"P3 2 1 255\n1 2 3": "PPM image is missing pixel data",
+		"P6 1 1 255\n\x01":  "PPM image is missing pixel data",
+
+		// which is found without allocating the pixels the header claims
+		"P6 1 1 65535\n\x01\x02\x03":                 "PPM image is missing pixel data",
+		"P6 3000000000 3000000000 255\n\x01\x02\x03": "PPM image is missing pixel data",
+		"P3 1 9223372036854775807 255\n1 2 3":        "PPM image is missing pixel data",
+	}
+
+	for data, expected := range tests {
+		_, err := ParsePPM([]byte(data))
+		assert.EqualError(t, err, expected, data)
+	}
+}
+
+func nrgba(c color.NRGBA) [4]uint8 {
+	return [4]uint8{c.R, c.G, c.B, c.A}
+}
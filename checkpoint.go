@@ -0,0 +1,219 @@
+package rt
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// A Checkpoint records the finished tiles of a render, so that a render that is interrupted can be
+// resumed without rendering them again.
+type Checkpoint struct {
+	// SceneHash is a hash of the world being rendered, including the images it samples.
+	SceneHash string
+
+	// Settings describes the camera and tiles the world is rendered with.
+	Settings string
+
+	Width  int
+	Height int
+
+	// Tiles records which of the render's tiles are finished, in the order they're rendered.
+	Tiles []bool
+
+	// Pixels holds the red, green, and blue of every pixel, a row at a time. The pixels of
+	// unfinished tiles are 0.
+	Pixels []float64
+}
+
+// NewCheckpoint creates a new Checkpoint for a render of a world that hasn't started.
+func NewCheckpoint(c *Camera, world *World) (*Checkpoint, error) {
+	data, err := json.Marshal(world)
+	if err != nil {
+		return nil, err
+	}
+
+	// scene files refer to images by name, so the images themselves are hashed as well, to
+	// notice when one is edited
+	hash := sha256.New()
+	hash.Write(data)
+	err = world.visitImages(func(file string, canvas **Canvas) error {
+		if *canvas != nil {
+			(*canvas).hash(hash)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := json.Marshal(struct {
+		Camera   *Camera `json:"camera"`
+		TileSize int     `json:"tileSize"`
+	}{c, c.tileSize()})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Checkpoint{
+		SceneHash: hex.EncodeToString(hash.Sum(nil)),
+		Settings:  string(settings),
+		Width:     c.HSize,
+		Height:    c.VSize,
+		Tiles:     make([]bool, len(c.tiles())),
+		Pixels:    make([]float64, c.HSize*c.VSize*3),
+	}, nil
+}
+
+// LoadCheckpoint reads a checkpoint from a file.
+func LoadCheckpoint(filename string) (*Checkpoint, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	var checkpoint Checkpoint
+	if err := gob.NewDecoder(file).Decode(&checkpoint); err != nil {
+		return nil, fmt.Errorf("%s: invalid checkpoint: %v", filename, err)
+	}
+
+	if len(checkpoint.Pixels) != checkpoint.Width*checkpoint.Height*3 {
+		return nil, fmt.Errorf("%s: invalid checkpoint: expected %d pixels", filename, checkpoint.Width*checkpoint.Height)
+	}
+
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint writes a checkpoint to a file. The file is replaced only once the checkpoint is
+// completely written, so a render killed while saving leaves the previous checkpoint intact.
+func SaveCheckpoint(filename string, checkpoint *Checkpoint) error {
+	file, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+
+	if err := gob.NewEncoder(file).Encode(checkpoint); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return os.Rename(file.Name(), filename)
+}
+
+// TilesDone returns the number of finished tiles.
+func (cp *Checkpoint) TilesDone() int {
+	done := 0
+	for _, finished := range cp.Tiles {
+		if finished {
+			done++
+		}
+	}
+
+	return done
+}
+
+// RenderCheckpointed renders the specified world as RenderContext does, starting from the tiles
+// already finished in a checkpoint and recording each tile in it as it's finished. After a tile
+// is finished, save is called with the checkpoint if it hasn't been called in the last interval,
+// and it's called once more when the render stops. If save returns an error, the render stops
+// and returns it.
+//
+// The checkpoint must have been created for the same world and camera settings.
+func (c *Camera) RenderCheckpointed(ctx context.Context, world *World, checkpoint *Checkpoint, interval time.Duration, save func(*Checkpoint) error, progress func(RenderProgress)) (*Canvas, error) {
+	expected, err := NewCheckpoint(c, world)
+	if err != nil {
+		return nil, err
+	}
+
+	if checkpoint.SceneHash != expected.SceneHash {
+		return nil, fmt.Errorf("checkpoint is for a different scene")
+	}
+
+	if checkpoint.Settings != expected.Settings || len(checkpoint.Tiles) != len(expected.Tiles) ||
+		len(checkpoint.Pixels) != len(expected.Pixels) {
+		return nil, fmt.Errorf("checkpoint was made with different render settings")
+	}
+
+	cp := &checkpointer{checkpoint: checkpoint, interval: interval, save: save, lastSave: time.Now()}
+	return c.render(ctx, world, renderOptions{checkpoint: cp}, progress)
+}
+
+// A checkpointer keeps a checkpoint up to date during a render.
+type checkpointer struct {
+	checkpoint *Checkpoint
+	interval   time.Duration
+	save       func(*Checkpoint) error
+	lastSave   time.Time
+	err        error
+
+	// cancel stops the render when the checkpoint can't be saved
+	cancel func()
+}
+
+// Copies a tile from the checkpoint to the canvas, if it's finished. Returns true if it is.
+func (cp *checkpointer) restore(canvas *Canvas, tile image.Rectangle, index int) bool {
+	if !cp.checkpoint.Tiles[index] {
+		return false
+	}
+
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			i := (y*canvas.width + x) * 3
+			pixels := cp.checkpoint.Pixels
+			canvas.WritePixel(x, y, NewColor(pixels[i], pixels[i+1], pixels[i+2]))
+		}
+	}
+
+	return true
+}
+
+// Copies a finished tile from the canvas to the checkpoint, saving it if it's due.
+func (cp *checkpointer) record(canvas *Canvas, tile image.Rectangle, index int) {
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			i := (y*canvas.width + x) * 3
+			color := canvas.PixelAt(x, y)
+			copy(cp.checkpoint.Pixels[i:i+3], color[:3])
+		}
+	}
+
+	cp.checkpoint.Tiles[index] = true
+	if cp.err == nil && time.Since(cp.lastSave) >= cp.interval {
+		cp.saveNow()
+	}
+}
+
+// Saves the checkpoint, stopping the render if it can't be saved.
+func (cp *checkpointer) saveNow() {
+	cp.lastSave = time.Now()
+	if cp.err = cp.save(cp.checkpoint); cp.err != nil {
+		cp.cancel()
+	}
+}
+
+// Saves the checkpoint one last time, once the render has stopped, and returns the first error
+// from saving it.
+func (cp *checkpointer) finish() error {
+	if cp.err == nil {
+		cp.saveNow()
+	}
+
+	return cp.err
+}
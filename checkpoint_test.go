@@ -0,0 +1,148 @@
+package rt
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamera_RenderCheckpointed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "render.checkpoint")
+	save := func(checkpoint *Checkpoint) error {
+		return SaveCheckpoint(filename, checkpoint)
+	}
+
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.Samples = 2
+	c.Threads = 1
+	c.TileSize = 4
+
+	// interrupt the render after the third tile
+	checkpoint, err := NewCheckpoint(c, w)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = c.RenderCheckpointed(ctx, w, checkpoint, 0, save, func(p RenderProgress) {
+		if p.TilesDone == 3 {
+			cancel()
+		}
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	// resuming renders only the remaining tiles, and finishes the same image
+	checkpoint, err = LoadCheckpoint(filename)
+	require.NoError(t, err)
+	assert.Equal(t, 3, checkpoint.TilesDone())
+	var tilesDone []int
+	image, err := c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, func(p RenderProgress) {
+		tilesDone = append(tilesDone, p.TilesDone)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 5, 6, 7, 8, 9}, tilesDone)
+	assert.Equal(t, c.Render(w), image)
+
+	checkpoint, err = LoadCheckpoint(filename)
+	require.NoError(t, err)
+	assert.Equal(t, 9, checkpoint.TilesDone())
+
+	// a finished checkpoint needs no more rendering
+	image, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, nil)
+	require.NoError(t, err)
+	assert.Equal(t, c.Render(w), image)
+}
+
+func TestCamera_RenderCheckpointed_mismatch(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	checkpoint, err := NewCheckpoint(c, w)
+	require.NoError(t, err)
+	save := func(*Checkpoint) error { return nil }
+
+	w.Objects[0].GetMaterial().Color = NewColor(1, 0, 0)
+	_, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, nil)
+	assert.EqualError(t, err, "checkpoint is for a different scene")
+
+	w = NewDefaultWorld()
+	c.Samples = 3
+	_, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, nil)
+	assert.EqualError(t, err, "checkpoint was made with different render settings")
+
+	// the number of threads doesn't change the image
+	c.Samples = 1
+	c.Threads = 2
+	_, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, nil)
+	assert.NoError(t, err)
+}
+
+func TestCamera_RenderCheckpointed_images(t *testing.T) {
+	texture, environment := NewCanvas(2, 2), NewCanvas(4, 2)
+	image := NewUVImagePattern(texture)
+	image.File = "texture.png"
+	sky := NewImageEnvironment(environment)
+	sky.File = "sky.hdr"
+	w := NewDefaultWorld()
+	w.Objects[0].GetMaterial().Pattern = NewTextureMapPattern(image, SphericalMapping)
+	w.Environment = sky
+	c := NewCamera(11, 11, math.Pi/2)
+	checkpoint, err := NewCheckpoint(c, w)
+	require.NoError(t, err)
+	save := func(*Checkpoint) error { return nil }
+
+	// scenes refer to images by file name, so an image edited under the same name is a
+	// different scene
+	texture.WritePixel(1, 1, NewColor(1, 0, 0))
+	_, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, nil)
+	assert.EqualError(t, err, "checkpoint is for a different scene")
+
+	checkpoint, err = NewCheckpoint(c, w)
+	require.NoError(t, err)
+	environment.WritePixel(0, 0, NewColor(2, 2, 2))
+	_, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, nil)
+	assert.EqualError(t, err, "checkpoint is for a different scene")
+
+	checkpoint, err = NewCheckpoint(c, w)
+	require.NoError(t, err)
+	_, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, save, nil)
+	assert.NoError(t, err)
+}
+
+func TestCamera_RenderCheckpointed_saveError(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Threads = 1
+	c.TileSize = 4
+	checkpoint, err := NewCheckpoint(c, w)
+	require.NoError(t, err)
+
+	tiles := 0
+	_, err = c.RenderCheckpointed(context.Background(), w, checkpoint, 0, func(*Checkpoint) error {
+		return errors.New("disk full")
+	}, func(RenderProgress) {
+		tiles++
+	})
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, 1, tiles)
+}
+
+func TestLoadCheckpoint_invalid(t *testing.T) {
+	file, err := ioutil.TempFile("", "rt")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("not a checkpoint")
+	file.Close()
+
+	_, err = LoadCheckpoint(file.Name())
+	assert.Contains(t, err.Error(), "invalid checkpoint")
+}
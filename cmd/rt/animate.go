@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jefflinse/go-ray-tracer"
+)
+
+func runAnimate(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("animate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: rt animate [flags] scene.yaml|scene.json")
+		flags.PrintDefaults()
+	}
+
+	var rf renderFlags
+	rf.register(flags)
+	rf.registerListen(flags)
+	flags.Lookup("o").Usage = "output `file`: a .gif, or a name for numbered frames with a verb like %04d, such as frame-%04d.png"
+	fps := flags.Float64("fps", 0, "GIF frames per second (default one frame per animation frame time, with times in seconds)")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+
+	if len(positional) != 1 || rf.output == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rt animate: %v\n", err)
+		return exitError
+	}
+
+	gif := strings.ToLower(filepath.Ext(rf.output)) == ".gif"
+	if !gif && !hasFrameVerb(rf.output) {
+		return fail(fmt.Errorf("output %q must be a .gif or contain a frame number verb such as %%04d", rf.output))
+	}
+
+	if !gif {
+		if err := rt.CheckSaveFormat(fmt.Sprintf(rf.output, 0)); err != nil {
+			return fail(err)
+		}
+	}
+
+	if *fps < 0 {
+		return fail(fmt.Errorf("fps must be positive"))
+	}
+
+	toneMapper, err := rf.toneMapper()
+	if err != nil {
+		return fail(err)
+	}
+
+	scene, err := rt.LoadScene(positional[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	animation := scene.Animation
+	if animation == nil {
+		return fail(fmt.Errorf("%s: scene has no animation", positional[0]))
+	}
+
+	co, err := rf.coordinator(positional[0], stderr)
+	if err != nil {
+		return fail(err)
+	}
+
+	if co != nil {
+		defer co.Close()
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var frames []*rt.Canvas
+	start := time.Now()
+	for frame := 0; frame < animation.Frames; frame++ {
+		animation.Apply(animation.FrameTime(frame))
+		camera, err := rf.configure(scene.Camera)
+		if err != nil {
+			return fail(err)
+		}
+
+		var bar *progressBar
+		var progress func(rt.RenderProgress)
+		if !rf.quiet {
+			fmt.Fprintf(stderr, "frame %d/%d\n", frame+1, animation.Frames)
+			bar = newProgressBar(stderr)
+			progress = bar.update
+		}
+
+		var canvas *rt.Canvas
+		if co != nil {
+			canvas, err = co.Render(ctx, camera, scene.World, progress)
+		} else {
+			canvas, err = camera.RenderContext(ctx, scene.World, progress)
+		}
+
+		if bar != nil {
+			bar.finish()
+		}
+
+		if err != nil {
+			return fail(fmt.Errorf("render stopped early (%v) at frame %d", err, frame+1))
+		}
+
+		if toneMapper != nil {
+			canvas = canvas.ToneMap(toneMapper)
+		}
+
+		if gif {
+			frames = append(frames, canvas)
+		} else if err := canvas.Save(fmt.Sprintf(rf.output, frame)); err != nil {
+			return fail(err)
+		}
+	}
+
+	if gif {
+		frameDuration := time.Duration((animation.End - animation.Start) / float64(animation.Frames) * float64(time.Second))
+		if *fps > 0 {
+			frameDuration = time.Duration(float64(time.Second) / *fps)
+		}
+
+		if err := rt.SaveGIF(rf.output, frames, frameDuration); err != nil {
+			return fail(err)
+		}
+	}
+
+	if !rf.quiet {
+		fmt.Fprintf(stderr, "rendered %d frames in %v\n", animation.Frames, time.Since(start).Round(time.Millisecond))
+	}
+
+	return exitOK
+}
+
+// Returns whether a file name has a verb to format with the frame number, and nothing else to format.
+func hasFrameVerb(name string) bool {
+	formatted := fmt.Sprintf(name, 0)
+	return formatted != name && !strings.Contains(formatted, "%!")
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAnimatedScene = `
+- add: animation
+  frames: 3
+  end: .3
+- add: camera
+  width: 8
+  height: 4
+  field-of-view: 1
+  from: [0, 0, -5]
+  to: [0, 0, 0]
+  up: [0, 1, 0]
+- add: light
+  at: [-10, 10, -10]
+- add: sphere
+  animate:
+    - time: 0
+      transform:
+        - [translate, -1, 0, 0]
+    - time: .3
+      transform:
+        - [translate, 1, 0, 0]
+`
+
+func TestRunAnimate(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testAnimatedScene), 0644))
+
+	// numbered frames
+	var stdout, stderr bytes.Buffer
+	frames := filepath.Join(dir, "frame-%02d.png")
+	status := run([]string{"animate", scene, "-o", frames, "-resolution", "16x8"}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	assert.Contains(t, stderr.String(), "frame 3/3")
+	assert.Contains(t, stderr.String(), "rendered 3 frames")
+
+	var first, last []byte
+	for i, name := range []string{"frame-00.png", "frame-01.png", "frame-02.png"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, 16, img.Bounds().Dx())
+		if i == 0 {
+			first = data
+		}
+
+		last = data
+	}
+
+	// the sphere moves between frames
+	assert.NotEqual(t, first, last)
+
+	// an animated GIF, with each frame shown for a tenth of a second
+	stderr.Reset()
+	output := filepath.Join(dir, "out.gif")
+	status = run([]string{"animate", "-q", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	assert.Empty(t, stderr.String())
+
+	file, err := os.Open(output)
+	require.NoError(t, err)
+	defer file.Close()
+	animation, err := gif.DecodeAll(file)
+	require.NoError(t, err)
+	assert.Len(t, animation.Image, 3)
+	assert.Equal(t, []int{10, 10, 10}, animation.Delay)
+
+	// or at a chosen frame rate
+	status = run([]string{"animate", "-q", "-fps", "25", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	file2, err := os.Open(output)
+	require.NoError(t, err)
+	defer file2.Close()
+	animation, err = gif.DecodeAll(file2)
+	require.NoError(t, err)
+	assert.Equal(t, []int{4, 4, 4}, animation.Delay)
+}
+
+func TestRunAnimate_errors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	animated := filepath.Join(dir, "animated.yaml")
+	require.NoError(t, ioutil.WriteFile(animated, []byte(testAnimatedScene), 0644))
+	still := filepath.Join(dir, "still.yaml")
+	require.NoError(t, ioutil.WriteFile(still, []byte(testScene), 0644))
+
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+	}{
+		{[]string{animated}, exitUsage, "usage: rt animate"},
+		{[]string{"-o", "frame.png", animated}, exitError, `output "frame.png" must be a .gif or contain a frame number verb such as %04d`},
+		{[]string{"-o", "frame-%d-%d.png", animated}, exitError, "must be a .gif or contain a frame number verb"},
+		{[]string{"-o", "frame-%04d.jpg", filepath.Join(dir, "missing.yaml")}, exitError, "frame-0000.jpg: unsupported image format"},
+		{[]string{"-o", "out.gif", "-fps", "-1", animated}, exitError, "fps must be positive"},
+		{[]string{"-o", "out.gif", still}, exitError, "scene has no animation"},
+		{[]string{"-o", "out.gif", "-samples", "-2", animated}, exitError, "samples must be positive"},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(append([]string{"animate"}, test.args...), &stdout, &stderr)
+		assert.Equal(t, test.status, status, test.args)
+		assert.Contains(t, stderr.String(), test.expected, test.args)
+	}
+}
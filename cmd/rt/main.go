@@ -0,0 +1,68 @@
+// Command rt renders scene description files.
+//
+// Usage:
+//
+//	rt render [flags] scene.yaml
+//	rt animate [flags] scene.yaml
+//	rt preview [flags] scene.yaml
+//	rt worker [flags] address
+//
+// Run "rt <command> -h" for the list of a command's flags.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit statuses.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// A command is a subcommand of rt.
+type command struct {
+	name        string
+	description string
+	run         func(args []string, stdout io.Writer, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"render", "render a scene to an image file", runRender},
+	{"animate", "render an animated scene's frames to numbered image files or a GIF", runAnimate},
+	{"preview", "render a scene progressively, watching it converge in a browser", runPreview},
+	{"worker", "render tiles for a render or animate command run with -listen", runWorker},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return exitUsage
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+
+	fmt.Fprintf(stderr, "rt: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: rt <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+}
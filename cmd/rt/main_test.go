@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jefflinse/go-ray-tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScene = `
+- add: camera
+  width: 40
+  height: 20
+  field-of-view: 1
+  from: [0, 0, -5]
+  to: [0, 0, 0]
+  up: [0, 1, 0]
+- add: light
+  at: [-10, 10, -10]
+- add: sphere
+`
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rt")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestRun_usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitUsage, run(nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "usage: rt")
+
+	stderr.Reset()
+	assert.Equal(t, exitUsage, run([]string{"paint"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `unknown command "paint"`)
+}
+
+func TestRunRender(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+	output := filepath.Join(dir, "out.png")
+
+	// flags may come before or after the scene file
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"render", "-resolution", "20x10", scene, "-samples", "2", "-o", output, "-tonemap", "aces"}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	assert.Contains(t, stderr.String(), "100%")
+	assert.Contains(t, stderr.String(), "rendered 20x10 at 4 samples/pixel")
+
+	file, err := os.Open(output)
+	require.NoError(t, err)
+	defer file.Close()
+	img, err := png.Decode(file)
+	require.NoError(t, err)
+	assert.Equal(t, 20, img.Bounds().Dx())
+	assert.Equal(t, 10, img.Bounds().Dy())
+
+	// PPM output, quietly
+	stderr.Reset()
+	output = filepath.Join(dir, "out.ppm")
+	status = run([]string{"render", "-q", "-threads", "2", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	assert.Empty(t, stderr.String())
+	data, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "P3\n40 20\n255\n"))
+
+	// path tracing, with statistics
+	stderr.Reset()
+	status = run([]string{"render", "-q", "-stats", "-resolution", "8x4", "-integrator", "path", "-max-depth", "2", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	assert.Contains(t, stderr.String(), "32 primary")
+	assert.Contains(t, stderr.String(), "intersection tests:")
+	assert.Contains(t, stderr.String(), "tiles: 1 in")
+}
+
+func TestRunRender_passes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+	output := filepath.Join(dir, "out.png")
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"render", "-q", "-passes", "depth,normal,direct", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	for _, name := range []string{"out.png", "out.depth.png", "out.normal.png", "out.direct.png"} {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+
+	// denoising renders the guide passes without saving them
+	require.NoError(t, os.Remove(filepath.Join(dir, "out.normal.png")))
+	status = run([]string{"render", "-q", "-denoise", "-passes", "depth", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	_, err := os.Stat(filepath.Join(dir, "out.normal.png"))
+	assert.True(t, os.IsNotExist(err))
+
+	status = run([]string{"render", "-q", "-passes", "depth,velocity", "-o", output, scene}, &stdout, &stderr)
+	assert.Equal(t, exitError, status)
+	assert.Contains(t, stderr.String(), "unknown render pass 'velocity'")
+}
+
+func TestRunRender_heatmap(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+	output := filepath.Join(dir, "out.png")
+
+	// the heatmap has a legend below the image
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"render", "-q", "-heatmap", "intersection-tests", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	heatmap, err := rt.LoadCanvas(output)
+	require.NoError(t, err)
+	assert.Equal(t, 40, heatmap.Width())
+	assert.Equal(t, 20+19, heatmap.Height())
+
+	// as do cost passes
+	status = run([]string{"render", "-q", "-passes", "time", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	pass, err := rt.LoadCanvas(filepath.Join(dir, "out.time.png"))
+	require.NoError(t, err)
+	assert.Equal(t, 20+19, pass.Height())
+
+	status = run([]string{"render", "-q", "-heatmap", "memory", "-o", output, scene}, &stdout, &stderr)
+	assert.Equal(t, exitError, status)
+	assert.Contains(t, stderr.String(), "unknown heatmap metric 'memory'")
+}
+
+func TestRunRender_checkpoint(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+	output := filepath.Join(dir, "out.png")
+	checkpointFile := filepath.Join(dir, "render.checkpoint")
+
+	// the checkpoint is removed once the render is finished
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"render", "-q", "-checkpoint", checkpointFile, "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	_, err := os.Stat(checkpointFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// resume a render that has half its tiles finished
+	loaded, err := rt.LoadScene(scene)
+	require.NoError(t, err)
+	checkpoint, err := rt.NewCheckpoint(loaded.Camera, loaded.World)
+	require.NoError(t, err)
+	for i := 0; i < len(checkpoint.Tiles)/2; i++ {
+		checkpoint.Tiles[i] = true
+	}
+
+	require.NoError(t, rt.SaveCheckpoint(checkpointFile, checkpoint))
+	status = run([]string{"render", "-q", "-checkpoint", checkpointFile, "-resume", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	_, err = os.Stat(checkpointFile)
+	assert.True(t, os.IsNotExist(err))
+
+	// but not once the scene has changed
+	require.NoError(t, rt.SaveCheckpoint(checkpointFile, checkpoint))
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene+"  transform:\n    - [scale, 2, 2, 2]\n"), 0644))
+	status = run([]string{"render", "-q", "-checkpoint", checkpointFile, "-resume", "-o", output, scene}, &stdout, &stderr)
+	assert.Equal(t, exitError, status)
+	assert.Contains(t, stderr.String(), "can't resume from "+checkpointFile+": checkpoint is for a different scene")
+}
+
+func TestRunRender_distributed(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+	output := filepath.Join(dir, "out.ppm")
+
+	// find a free port for the coordinator
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	var stdout, stderr bytes.Buffer
+	rendered := make(chan int, 1)
+	go func() {
+		rendered <- run([]string{"render", "-listen", addr, "-o", output, scene}, &stdout, &stderr)
+	}()
+
+	// workers keep trying to connect until the coordinator is listening, or the render is
+	// finished without them
+	finished := make(chan struct{})
+	workers := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			for {
+				var stdout, stderr bytes.Buffer
+				status := run([]string{"worker", "-q", "-threads", "2", addr}, &stdout, &stderr)
+				if !strings.Contains(stderr.String(), "connection refused") {
+					workers <- status
+					return
+				}
+
+				select {
+				case <-finished:
+					workers <- exitOK
+					return
+				case <-time.After(10 * time.Millisecond):
+				}
+			}
+		}()
+	}
+
+	require.Equal(t, exitOK, <-rendered)
+	close(finished)
+	assert.Contains(t, stderr.String(), "rendering on the workers that connect to "+addr)
+	assert.Equal(t, exitOK, <-workers)
+	assert.Equal(t, exitOK, <-workers)
+
+	// the image is the same as a local render's
+	distributed, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	status := run([]string{"render", "-q", "-o", output, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status)
+	local, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, string(local), string(distributed))
+}
+
+func TestRunRender_crop(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+	full := filepath.Join(dir, "full.ppm")
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, exitOK, run([]string{"render", "-q", "-o", full, scene}, &stdout, &stderr), stderr.String())
+
+	// the window alone
+	cropped := filepath.Join(dir, "cropped.ppm")
+	status := run([]string{"render", "-q", "-crop", ".25,.25,.75,.75", "-o", cropped, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	data, err := ioutil.ReadFile(cropped)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "P3\n20 10\n255\n"))
+
+	// pasted into the full render, which it matches
+	pasted := filepath.Join(dir, "pasted.ppm")
+	status = run([]string{"render", "-q", "-crop", ".25,.25,.75,.75", "-into", full, "-o", pasted, scene}, &stdout, &stderr)
+	require.Equal(t, exitOK, status, stderr.String())
+	expected, err := ioutil.ReadFile(full)
+	require.NoError(t, err)
+	data, err = ioutil.ReadFile(pasted)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(data))
+}
+
+func TestRenderFlags_configure(t *testing.T) {
+	camera := rt.NewCamera(40, 20, 1)
+	camera.Integrator = rt.NewPathTracer()
+	camera.ShutterClose = .5
+	camera.Seed = 7
+	rf := renderFlags{resolution: "20x10", maxDepth: 2}
+	configured, err := rf.configure(camera)
+	require.NoError(t, err)
+	assert.Equal(t, 20, configured.HSize)
+	assert.Equal(t, .5, configured.ShutterClose)
+	assert.Equal(t, int64(7), configured.Seed)
+	assert.Equal(t, &rt.PathTracer{MaxDepth: 2, RouletteDepth: 3}, configured.Integrator)
+
+	rf = renderFlags{integrator: "whitted", maxDepth: -1, sampler: "halton", seed: "-3"}
+	configured, err = rf.configure(camera)
+	require.NoError(t, err)
+	assert.Equal(t, rt.NewWhittedIntegrator(), configured.Integrator)
+	assert.Equal(t, rt.NewHaltonSampler(), configured.Sampler)
+	assert.Equal(t, int64(-3), configured.Seed)
+}
+
+func TestRunRender_errors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+	bad := filepath.Join(dir, "bad.yaml")
+	require.NoError(t, ioutil.WriteFile(bad, []byte("- add: teapot\n"), 0644))
+
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+	}{
+		{[]string{scene}, exitUsage, "usage: rt render"},
+		{[]string{"-o", "out.png"}, exitUsage, "usage: rt render"},
+		{[]string{"-bogus", scene}, exitUsage, "flag provided but not defined"},
+		{[]string{"-o", "out.png", filepath.Join(dir, "missing.yaml")}, exitError, "no such file"},
+		{[]string{"-o", "out.png", bad}, exitError, "bad.yaml:1:8: unknown object type 'teapot'"},
+		{[]string{"-o", "out.png", "-resolution", "big", scene}, exitError, `invalid resolution "big"`},
+		{[]string{"-o", "out.png", "-tonemap", "sepia", scene}, exitError, `unknown tone mapping operator "sepia"`},
+		{[]string{"-o", "out.png", "-integrator", "radiosity", scene}, exitError, "unknown integrator 'radiosity'"},
+		{[]string{"-o", "out.png", "-max-depth", "3", scene}, exitError, "max depth requires the path integrator"},
+		{[]string{"-o", "out.png", "-sampler", "sobol", scene}, exitError, "unknown sampler 'sobol'"},
+		{[]string{"-o", "out.png", "-seed", "lucky", scene}, exitError, `invalid seed "lucky"`},
+		{[]string{"-q", "-o", filepath.Join(dir, "out.gif"), scene}, exitError, "unsupported image format"},
+		{[]string{"-o", "out.jpg", filepath.Join(dir, "missing.yaml")}, exitError, "out.jpg: unsupported image format"},
+		{[]string{"-o", "out.png", "-resume", scene}, exitError, "-resume requires -checkpoint"},
+		{[]string{"-o", "out.png", "-checkpoint", "out.checkpoint", "-denoise", scene}, exitError, "-checkpoint can't be combined"},
+		{[]string{"-o", "out.png", "-checkpoint", filepath.Join(dir, "missing.checkpoint"), "-resume", scene}, exitError, "no such file"},
+		{[]string{"-o", "out.png", "-listen", "localhost:0", "-denoise", scene}, exitError, "-listen can't be combined"},
+		{[]string{"-o", "out.png", "-crop", "left", scene}, exitError, `invalid crop window "left"`},
+		{[]string{"-o", "out.png", "-crop", "0,0,1,1", "-denoise", scene}, exitError, "-crop can't be combined"},
+		{[]string{"-o", "out.png", "-into", "prior.png", scene}, exitError, "-into requires -crop"},
+		{[]string{"-o", "out.png", "-heatmap", "time", "-denoise", scene}, exitError, "-heatmap can't be combined"},
+		{[]string{"-o", "out.png", "-crop", "2,2,3,3", scene}, exitError, "is outside the 40x20 image"},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(append([]string{"render"}, test.args...), &stdout, &stderr)
+		assert.Equal(t, test.status, status, test.args)
+		assert.Contains(t, stderr.String(), test.expected, test.args)
+	}
+}
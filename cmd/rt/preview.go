@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jefflinse/go-ray-tracer"
+)
+
+// The page served by a previewServer, which reloads the image whenever the render is updated.
+const previewPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>rt preview</title>
+<style>
+body { background: #222; color: #ccc; font-family: sans-serif; text-align: center; }
+img { image-rendering: pixelated; max-width: 100%; }
+</style>
+</head>
+<body>
+<p id="status">waiting for the first pass</p>
+<img id="image" alt="">
+<script>
+var events = new EventSource("/events");
+events.onmessage = function(event) {
+	var update = JSON.parse(event.data);
+	document.getElementById("image").src = "/image.png?pass=" + update.pass;
+	document.getElementById("status").textContent = update.done
+		? "finished " + update.samples + " samples/pixel in " + update.elapsed
+		: "pass " + update.pass + ", " + update.samples + " samples/pixel, " + update.elapsed;
+	if (update.done) {
+		events.close();
+	}
+};
+</script>
+</body>
+</html>
+`
+
+// The state of a render sent to a preview page.
+type previewStatus struct {
+	Pass    int    `json:"pass"`
+	Samples int    `json:"samples"`
+	Elapsed string `json:"elapsed"`
+	Done    bool   `json:"done"`
+}
+
+// A previewServer serves the latest image of a progressive render as a PNG, along with a page
+// that watches it converge, using server-sent events to learn when the image changes.
+type previewServer struct {
+	toneMapper rt.ToneMapper
+
+	mutex    sync.Mutex
+	image    []byte
+	status   *previewStatus
+	watchers map[chan struct{}]bool
+}
+
+func newPreviewServer(toneMapper rt.ToneMapper) *previewServer {
+	return &previewServer{toneMapper: toneMapper, watchers: map[chan struct{}]bool{}}
+}
+
+// Replaces the image being served with the one from a pass of the render.
+func (s *previewServer) update(u rt.ProgressiveUpdate) {
+	canvas := u.Image
+	if s.toneMapper != nil {
+		canvas = canvas.ToneMap(s.toneMapper)
+	}
+
+	var image bytes.Buffer
+	if err := png.Encode(&image, canvas.ToImage()); err != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.image = image.Bytes()
+	s.status = &previewStatus{Pass: u.Pass, Samples: u.Samples, Elapsed: u.Elapsed.Round(time.Millisecond).String()}
+	s.notify()
+}
+
+// Tells the preview pages that the render is finished.
+func (s *previewServer) finish() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.status != nil {
+		status := *s.status
+		status.Done = true
+		s.status = &status
+		s.notify()
+	}
+}
+
+// Wakes up the event streams. The mutex must be held.
+func (s *previewServer) notify() {
+	for watcher := range s.watchers {
+		select {
+		case watcher <- struct{}{}:
+		default:
+			// the watcher hasn't caught up with the last update yet, and will see this one too
+		}
+	}
+}
+
+func (s *previewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, previewPage)
+	case "/image.png":
+		s.mutex.Lock()
+		image := s.image
+		s.mutex.Unlock()
+		if image == nil {
+			http.Error(w, "no image has been rendered yet", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(image)
+	case "/events":
+		s.serveEvents(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Streams the render's status as server-sent events, one each time the image changes, until the
+// render is finished or the client goes away.
+func (s *previewServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	watcher := make(chan struct{}, 1)
+	s.mutex.Lock()
+	s.watchers[watcher] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.watchers, watcher)
+		s.mutex.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		s.mutex.Lock()
+		status := s.status
+		s.mutex.Unlock()
+		if status != nil {
+			data, _ := json.Marshal(status)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+			if status.Done {
+				return
+			}
+		}
+
+		select {
+		case <-watcher:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func runPreview(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("preview", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: rt preview [flags] scene.yaml|scene.json")
+		flags.PrintDefaults()
+	}
+
+	var rf renderFlags
+	rf.register(flags)
+	flags.Lookup("o").Usage = "also save the finished image to `file`"
+	addr := flags.String("addr", "localhost:8080", "`address` to serve the preview on")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rt preview: %v\n", err)
+		return exitError
+	}
+
+	if rf.output != "" {
+		if err := rt.CheckSaveFormat(rf.output); err != nil {
+			return fail(err)
+		}
+	}
+
+	toneMapper, err := rf.toneMapper()
+	if err != nil {
+		return fail(err)
+	}
+
+	scene, err := rt.LoadScene(positional[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	camera, err := rf.configure(scene.Camera)
+	if err != nil {
+		return fail(err)
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fail(err)
+	}
+
+	preview := newPreviewServer(toneMapper)
+	server := &http.Server{Handler: preview}
+	go server.Serve(listener)
+	defer server.Close()
+	fmt.Fprintf(stdout, "serving the preview at http://%s/ (interrupt to stop)\n", listener.Addr())
+
+	// an interrupt stops the render, or the server once the render is finished
+	ctx, stop := interruptContext()
+	defer stop()
+
+	samples := camera.Samples * camera.Samples
+	if samples < 1 {
+		samples = 1
+	}
+
+	canvas, renderErr := camera.RenderProgressive(ctx, scene.World, samples, func(u rt.ProgressiveUpdate) {
+		preview.update(u)
+		if !rf.quiet {
+			fmt.Fprintf(stderr, "\rpass %d, %d samples/pixel, elapsed %v ", u.Pass, u.Samples, u.Elapsed.Round(time.Second))
+		}
+	})
+
+	preview.finish()
+	if !rf.quiet {
+		fmt.Fprintln(stderr)
+	}
+
+	if rf.output != "" {
+		if toneMapper != nil {
+			canvas = canvas.ToneMap(toneMapper)
+		}
+
+		if err := canvas.Save(rf.output); err != nil {
+			return fail(err)
+		}
+	}
+
+	if renderErr != nil {
+		if rf.output != "" {
+			return fail(fmt.Errorf("render stopped early (%v); the partial image was saved to %s", renderErr, rf.output))
+		}
+
+		return fail(fmt.Errorf("render stopped early (%v)", renderErr))
+	}
+
+	<-ctx.Done()
+	return exitOK
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jefflinse/go-ray-tracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewServer(t *testing.T) {
+	preview := newPreviewServer(rt.NewReinhardToneMapper())
+	server := httptest.NewServer(preview)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/")
+	require.NoError(t, err)
+	page, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	require.NoError(t, err)
+	assert.Contains(t, string(page), `new EventSource("/events")`)
+
+	// there's no image until the first pass is finished
+	response, err = http.Get(server.URL + "/image.png")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	response, err = http.Get(server.URL + "/events")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	events := bufio.NewReader(response.Body)
+
+	canvas := rt.NewCanvas(4, 2)
+	canvas.WritePixel(0, 0, rt.NewColor(1, 1, 1))
+	preview.update(rt.ProgressiveUpdate{Pass: 5, Samples: 1, Image: canvas, Elapsed: 1500 * time.Millisecond})
+	event, err := events.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, `data: {"pass":5,"samples":1,"elapsed":"1.5s","done":false}`+"\n", event)
+
+	response, err = http.Get(server.URL + "/image.png")
+	require.NoError(t, err)
+	img, err := png.Decode(response.Body)
+	response.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, 4, img.Bounds().Dx())
+	r, _, _, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0x8080), r, "tone mapped")
+
+	// the stream ends once the render is finished
+	preview.finish()
+	rest, err := ioutil.ReadAll(events)
+	require.NoError(t, err)
+	assert.Equal(t, "\n"+`data: {"pass":5,"samples":1,"elapsed":"1.5s","done":true}`+"\n\n", string(rest))
+
+	response, err = http.Get(server.URL + "/favicon.ico")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
+
+func TestRunPreview_errors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	scene := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(scene, []byte(testScene), 0644))
+
+	tests := []struct {
+		args     []string
+		status   int
+		expected string
+	}{
+		{nil, exitUsage, "usage: rt preview"},
+		{[]string{"-addr", "nowhere:-1", scene}, exitError, "rt preview: listen tcp"},
+		{[]string{filepath.Join(dir, "missing.yaml")}, exitError, "no such file"},
+		{[]string{"-o", "out.jpg", filepath.Join(dir, "missing.yaml")}, exitError, "out.jpg: unsupported image format"},
+	}
+
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		status := run(append([]string{"preview"}, test.args...), &stdout, &stderr)
+		assert.Equal(t, test.status, status, test.args)
+		assert.Contains(t, stderr.String(), test.expected, test.args)
+	}
+}
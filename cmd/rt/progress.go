@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jefflinse/go-ray-tracer"
+)
+
+// A progressBar draws a single-line progress bar on a terminal.
+type progressBar struct {
+	w       io.Writer
+	width   int
+	percent int
+}
+
+func newProgressBar(w io.Writer) *progressBar {
+	return &progressBar{w: w, width: 40, percent: -1}
+}
+
+// Redraws the bar if the completed percentage has changed.
+func (b *progressBar) update(p rt.RenderProgress) {
+	percent := 100
+	if p.TilesTotal > 0 {
+		percent = p.TilesDone * 100 / p.TilesTotal
+	}
+
+	if percent == b.percent {
+		return
+	}
+
+	b.percent = percent
+	filled := b.width * percent / 100
+	fmt.Fprintf(b.w, "\r[%s%s] %3d%%  elapsed %v  eta %v  %.0f rays/s ",
+		strings.Repeat("#", filled), strings.Repeat(" ", b.width-filled), percent,
+		p.Elapsed.Round(time.Second), p.ETA.Round(time.Second), p.RaysPerSecond)
+}
+
+// Ends the line the bar is drawn on.
+func (b *progressBar) finish() {
+	fmt.Fprintln(b.w)
+}
+
+// Prints the statistics of a render.
+func printStats(w io.Writer, stats *rt.RenderStats) {
+	fmt.Fprintf(w, "rays: %d (%d primary, %d shadow, %d reflection)\n",
+		stats.Rays(), stats.PrimaryRays, stats.ShadowRays, stats.ReflectionRays)
+
+	shapes := make([]string, 0, len(stats.IntersectionTests))
+	for shape := range stats.IntersectionTests {
+		shapes = append(shapes, shape)
+	}
+
+	sort.Strings(shapes)
+	for i, shape := range shapes {
+		shapes[i] = fmt.Sprintf("%d %s", stats.IntersectionTests[shape], shape)
+	}
+
+	fmt.Fprintf(w, "intersection tests: %d (%s)\n", stats.TotalIntersectionTests(), strings.Join(shapes, ", "))
+	if len(stats.Tiles) == 0 {
+		return
+	}
+
+	total, slowest := stats.TotalTileTime(), stats.SlowestTile()
+	fmt.Fprintf(w, "tiles: %d in %v of thread time, %v on average, slowest %v at %v\n",
+		len(stats.Tiles), total.Round(time.Millisecond), (total / time.Duration(len(stats.Tiles))).Round(time.Microsecond),
+		slowest.Elapsed.Round(time.Microsecond), slowest.Tile)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jefflinse/go-ray-tracer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	bar := newProgressBar(&out)
+	bar.width = 10
+
+	bar.update(rt.RenderProgress{TilesDone: 1, TilesTotal: 4, Elapsed: time.Second, ETA: 3 * time.Second, RaysPerSecond: 1000})
+	assert.Equal(t, "\r[##        ]  25%  elapsed 1s  eta 3s  1000 rays/s ", out.String())
+
+	// the bar is only redrawn when the percentage changes
+	out.Reset()
+	bar.update(rt.RenderProgress{TilesDone: 2, TilesTotal: 8})
+	assert.Empty(t, out.String())
+
+	bar.update(rt.RenderProgress{TilesDone: 8, TilesTotal: 8})
+	bar.finish()
+	assert.True(t, strings.HasPrefix(out.String(), "\r[##########] 100%"))
+	assert.True(t, strings.HasSuffix(out.String(), "\n"))
+}
+
+func TestPrintStats(t *testing.T) {
+	stats := rt.NewRenderStats()
+	stats.PrimaryRays = 100
+	stats.ShadowRays = 40
+	stats.ReflectionRays = 10
+	stats.IntersectionTests["sphere"] = 300
+	stats.IntersectionTests["plane"] = 150
+	stats.Tiles = []rt.TileTime{
+		{Tile: image.Rect(0, 0, 16, 16), Elapsed: 2 * time.Millisecond},
+		{Tile: image.Rect(16, 0, 32, 16), Elapsed: 4 * time.Millisecond},
+	}
+
+	var out bytes.Buffer
+	printStats(&out, stats)
+	assert.Equal(t, `rays: 150 (100 primary, 40 shadow, 10 reflection)
+intersection tests: 450 (150 plane, 300 sphere)
+tiles: 2 in 6ms of thread time, 3ms on average, slowest 4ms at (16,0)-(32,16)
+`, out.String())
+}
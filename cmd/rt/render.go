@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jefflinse/go-ray-tracer"
+)
+
+// Flags shared by the commands that render scenes.
+type renderFlags struct {
+	output     string
+	resolution string
+	samples    int
+	integrator string
+	maxDepth   int
+	sampler    string
+	seed       string
+	threads    int
+	toneMap    string
+	exposure   float64
+	whitePoint float64
+	quiet      bool
+	listen     string
+}
+
+func (rf *renderFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&rf.output, "o", "", "output image `file`; the format (.png or .ppm) is chosen from the extension")
+	flags.StringVar(&rf.resolution, "resolution", "", "override the camera resolution, as `WIDTHxHEIGHT`")
+	flags.IntVar(&rf.samples, "samples", 0, "override the number of samples along each axis of a pixel")
+	flags.StringVar(&rf.integrator, "integrator", "", "override the scene's `integrator`: whitted or path")
+	flags.IntVar(&rf.maxDepth, "max-depth", -1, "override the largest number of bounces taken by the path integrator")
+	flags.StringVar(&rf.sampler, "sampler", "", "override the scene's `sampler`: stratified or halton")
+	flags.StringVar(&rf.seed, "seed", "", "override the scene's random `seed`; renders with the same seed are identical")
+	flags.IntVar(&rf.threads, "threads", 0, "number of render threads (default one per CPU)")
+	flags.StringVar(&rf.toneMap, "tonemap", "none", "tone mapping `operator`: none, exposure, reinhard, reinhard-extended, or aces")
+	flags.Float64Var(&rf.exposure, "exposure", 0, "exposure adjustment in stops, applied before tone mapping")
+	flags.Float64Var(&rf.whitePoint, "white-point", 4, "luminance mapped to white by the reinhard-extended operator")
+	flags.BoolVar(&rf.quiet, "q", false, "don't print progress or statistics")
+}
+
+// Registers the flag that renders on workers, for the commands that support it.
+func (rf *renderFlags) registerListen(flags *flag.FlagSet) {
+	flags.StringVar(&rf.listen, "listen", "", "render on the workers (see rt worker) that connect to `address`, instead of locally")
+}
+
+// Returns a coordinator for the workers that connect to the -listen address, or nil if it isn't
+// set. Workers load the scene's images relative to the scene file.
+func (rf *renderFlags) coordinator(sceneFile string, stderr io.Writer) (*rt.Coordinator, error) {
+	if rf.listen == "" {
+		return nil, nil
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(sceneFile))
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", rf.listen)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rf.quiet {
+		fmt.Fprintf(stderr, "rendering on the workers that connect to %s\n", listener.Addr())
+	}
+
+	co := rt.NewCoordinator(listener)
+	co.Dir = dir
+	return co, nil
+}
+
+// Applies the flag overrides to a scene's camera.
+func (rf *renderFlags) configure(camera *rt.Camera) (*rt.Camera, error) {
+	if rf.resolution != "" {
+		var width, height int
+		if _, err := fmt.Sscanf(rf.resolution, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+			return nil, fmt.Errorf("invalid resolution %q; expected WIDTHxHEIGHT", rf.resolution)
+		}
+
+		resized := rt.NewCamera(width, height, camera.FOV)
+		resized.Transform = camera.Transform
+		resized.Samples = camera.Samples
+		resized.Integrator = camera.Integrator
+		resized.ShutterOpen, resized.ShutterClose = camera.ShutterOpen, camera.ShutterClose
+		resized.Sampler, resized.Seed = camera.Sampler, camera.Seed
+		camera = resized
+	}
+
+	if rf.samples < 0 {
+		return nil, fmt.Errorf("samples must be positive")
+	} else if rf.samples > 0 {
+		camera.Samples = rf.samples
+	}
+
+	if rf.integrator != "" {
+		integrator, err := rt.NewIntegrator(rf.integrator)
+		if err != nil {
+			return nil, err
+		}
+
+		camera.Integrator = integrator
+	}
+
+	if rf.maxDepth >= 0 {
+		pt, ok := camera.Integrator.(*rt.PathTracer)
+		if !ok {
+			return nil, fmt.Errorf("max depth requires the path integrator")
+		}
+
+		pt.MaxDepth = rf.maxDepth
+	}
+
+	if rf.sampler != "" {
+		sampler, err := rt.NewSampler(rf.sampler)
+		if err != nil {
+			return nil, err
+		}
+
+		camera.Sampler = sampler
+	}
+
+	if rf.seed != "" {
+		seed, err := strconv.ParseInt(rf.seed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed %q", rf.seed)
+		}
+
+		camera.Seed = seed
+	}
+
+	if rf.threads < 0 {
+		return nil, fmt.Errorf("threads must be positive")
+	}
+
+	camera.Threads = rf.threads
+	return camera, nil
+}
+
+// Returns the tone mapper selected by the flags, or nil if none is.
+func (rf *renderFlags) toneMapper() (rt.ToneMapper, error) {
+	var exposure rt.ToneMapper
+	if rf.exposure != 0 {
+		exposure = rt.NewExposureToneMapper(rf.exposure)
+	}
+
+	var operator rt.ToneMapper
+	switch rf.toneMap {
+	case "none", "exposure":
+		return exposure, nil
+	case "reinhard":
+		operator = rt.NewReinhardToneMapper()
+	case "reinhard-extended":
+		if rf.whitePoint <= 0 {
+			return nil, fmt.Errorf("white point must be positive")
+		}
+
+		operator = rt.NewExtendedReinhardToneMapper(rf.whitePoint)
+	case "aces":
+		operator = rt.NewACESToneMapper()
+	default:
+		return nil, fmt.Errorf("unknown tone mapping operator %q", rf.toneMap)
+	}
+
+	if exposure == nil {
+		return operator, nil
+	}
+
+	return chainedToneMapper{exposure, operator}, nil
+}
+
+// A chainedToneMapper applies each of its tone mappers in turn.
+type chainedToneMapper []rt.ToneMapper
+
+func (c chainedToneMapper) Map(color rt.Color) rt.Color {
+	for _, tm := range c {
+		color = tm.Map(color)
+	}
+
+	return color
+}
+
+// Returns a context that is canceled when the process is interrupted, and a function that stops
+// watching for interrupts.
+func interruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(interrupts)
+		cancel()
+	}
+}
+
+// Parses flags that may appear both before and after positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func runRender(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: rt render [flags] scene.yaml|scene.json")
+		flags.PrintDefaults()
+	}
+
+	var rf renderFlags
+	rf.register(flags)
+	rf.registerListen(flags)
+	passList := flags.String("passes", "", "comma-separated auxiliary `passes` to save beside the image, named after it (e.g. out.depth.png): "+
+		strings.Join(rt.PassNames, ", "))
+	denoise := flags.Bool("denoise", false, "filter sampling noise out of the image, guided by its normal, albedo, and depth passes")
+	checkpointFile := flags.String("checkpoint", "", "periodically save the render's progress to `file`, which is removed once the render is finished")
+	checkpointInterval := flags.Duration("checkpoint-interval", time.Minute, "how often to save the checkpoint")
+	resume := flags.Bool("resume", false, "resume the render saved in the checkpoint file")
+	crop := flags.String("crop", "", "render only a window of the image, with edges `MINX,MINY,MAXX,MAXY` given as fractions of its width and height from the top left")
+	heatmap := flags.String("heatmap", "", "save a heatmap of what each pixel cost to render instead of the image, measured by `metric`: intersection-tests or time")
+	printStatistics := flags.Bool("stats", false, "print the rays, intersection tests, and time per tile the render took")
+	into := flags.String("into", "", "paste the -crop window into a prior render of the whole image read from `file`, instead of saving just the window")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+
+	if len(positional) != 1 || rf.output == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rt render: %v\n", err)
+		return exitError
+	}
+
+	if err := rt.CheckSaveFormat(rf.output); err != nil {
+		return fail(err)
+	}
+
+	if *resume && *checkpointFile == "" {
+		return fail(fmt.Errorf("-resume requires -checkpoint"))
+	}
+
+	if *checkpointFile != "" && (*passList != "" || *denoise) {
+		return fail(fmt.Errorf("-checkpoint can't be combined with -passes or -denoise"))
+	}
+
+	if rf.listen != "" && (*passList != "" || *denoise || *checkpointFile != "") {
+		return fail(fmt.Errorf("-listen can't be combined with -passes, -denoise, or -checkpoint"))
+	}
+
+	var window [4]float64
+	if *crop != "" {
+		if _, err := fmt.Sscanf(*crop, "%g,%g,%g,%g", &window[0], &window[1], &window[2], &window[3]); err != nil {
+			return fail(fmt.Errorf("invalid crop window %q; expected MINX,MINY,MAXX,MAXY", *crop))
+		}
+
+		if *passList != "" || *denoise || *checkpointFile != "" || rf.listen != "" {
+			return fail(fmt.Errorf("-crop can't be combined with -passes, -denoise, -checkpoint, or -listen"))
+		}
+	} else if *into != "" {
+		return fail(fmt.Errorf("-into requires -crop"))
+	}
+
+	if *heatmap != "" && (*passList != "" || *denoise || *checkpointFile != "" || *crop != "" || rf.listen != "") {
+		return fail(fmt.Errorf("-heatmap can't be combined with -passes, -denoise, -checkpoint, -crop, or -listen"))
+	}
+
+	toneMapper, err := rf.toneMapper()
+	if err != nil {
+		return fail(err)
+	}
+
+	scene, err := rt.LoadScene(positional[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	camera, err := rf.configure(scene.Camera)
+	if err != nil {
+		return fail(err)
+	}
+
+	co, err := rf.coordinator(positional[0], stderr)
+	if err != nil {
+		return fail(err)
+	}
+
+	if co != nil {
+		defer co.Close()
+	}
+
+	// stop rendering on interrupt, but still save what has been rendered so far
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var bar *progressBar
+	var stats *rt.RenderStats
+	var progress func(rt.RenderProgress)
+	if !rf.quiet {
+		bar = newProgressBar(stderr)
+	}
+
+	if bar != nil || *printStatistics {
+		progress = func(p rt.RenderProgress) {
+			stats = p.Stats
+			if bar != nil {
+				bar.update(p)
+			}
+		}
+	}
+
+	var passNames []string
+	if *passList != "" {
+		passNames = strings.Split(*passList, ",")
+	}
+
+	rendered := passNames
+	if *denoise {
+		rendered = append([]string{"normal", "albedo", "depth"}, passNames...)
+	}
+
+	start := time.Now()
+	var canvas *rt.Canvas
+	var passes map[string]*rt.Canvas
+	var renderErr error
+	if *heatmap != "" {
+		canvas, renderErr = camera.RenderHeatmap(ctx, scene.World, *heatmap, progress)
+		if canvas == nil {
+			return fail(renderErr)
+		}
+
+		// the heatmap's colors are already meant for display
+		toneMapper = nil
+	} else if rendered != nil {
+		canvas, passes, renderErr = camera.RenderPasses(ctx, scene.World, rendered, progress)
+		if canvas == nil {
+			return fail(renderErr)
+		}
+	} else if *checkpointFile != "" {
+		canvas, renderErr = renderCheckpointed(ctx, camera, scene.World, *checkpointFile, *checkpointInterval, *resume, progress)
+		if canvas == nil {
+			return fail(renderErr)
+		}
+	} else if *crop != "" {
+		region := camera.CropWindow(window[0], window[1], window[2], window[3])
+		canvas, renderErr = camera.RenderRegion(ctx, scene.World, region, nil, progress)
+		if canvas == nil {
+			return fail(renderErr)
+		}
+
+		canvas, err = pasteRegion(canvas, region, toneMapper, *into)
+		if err != nil {
+			return fail(err)
+		}
+
+		// the window is already tone mapped
+		toneMapper = nil
+	} else if co != nil {
+		canvas, renderErr = co.Render(ctx, camera, scene.World, progress)
+	} else {
+		canvas, renderErr = camera.RenderContext(ctx, scene.World, progress)
+	}
+
+	elapsed := time.Since(start)
+
+	if bar != nil {
+		bar.finish()
+		rays := camera.HSize * camera.VSize * camera.Samples * camera.Samples
+		fmt.Fprintf(stderr, "rendered %dx%d at %d samples/pixel in %v (%.0f primary rays/s)\n",
+			camera.HSize, camera.VSize, camera.Samples*camera.Samples,
+			elapsed.Round(time.Millisecond), float64(rays)/elapsed.Seconds())
+	}
+
+	if *printStatistics && stats != nil {
+		printStats(stderr, stats)
+	}
+
+	if *denoise {
+		canvas, err = canvas.Denoise(rt.NewDenoiser(), rt.DenoiseGuides{
+			Normal: passes["normal"],
+			Albedo: passes["albedo"],
+			Depth:  passes["depth"],
+		})
+
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if toneMapper != nil {
+		canvas = canvas.ToneMap(toneMapper)
+	}
+
+	if err := canvas.Save(rf.output); err != nil {
+		return fail(err)
+	}
+
+	for _, name := range passNames {
+		pass := passes[name]
+		if name == "direct" || name == "indirect" {
+			if toneMapper != nil {
+				pass = pass.ToneMap(toneMapper)
+			}
+		} else {
+			pass = rt.DisplayPass(name, pass)
+		}
+
+		if err := pass.Save(passFilename(rf.output, name)); err != nil {
+			return fail(err)
+		}
+	}
+
+	if renderErr != nil {
+		if *checkpointFile != "" {
+			return fail(fmt.Errorf("render stopped early (%v); the partial image was saved to %s, and can be finished with -resume", renderErr, rf.output))
+		}
+
+		return fail(fmt.Errorf("render stopped early (%v); the partial image was saved to %s", renderErr, rf.output))
+	}
+
+	return exitOK
+}
+
+// Tone maps a rendered region of an image, and pastes it into the prior render in a file, or
+// crops the image to it if there's no file.
+func pasteRegion(canvas *rt.Canvas, region image.Rectangle, toneMapper rt.ToneMapper, into string) (*rt.Canvas, error) {
+	window := canvas.Crop(region)
+	if toneMapper != nil {
+		window = window.ToneMap(toneMapper)
+	}
+
+	if into == "" {
+		return window, nil
+	}
+
+	prior, err := rt.LoadCanvas(into)
+	if err != nil {
+		return nil, err
+	}
+
+	if prior.Width() != canvas.Width() || prior.Height() != canvas.Height() {
+		return nil, fmt.Errorf("%s is %dx%d, not %dx%d like the image", into, prior.Width(), prior.Height(), canvas.Width(), canvas.Height())
+	}
+
+	prior.Paste(window, region.Min)
+	return prior, nil
+}
+
+// Renders a world, saving its progress to a checkpoint file, or resuming from the file if resume
+// is true. The file is removed once the render is finished.
+func renderCheckpointed(ctx context.Context, camera *rt.Camera, world *rt.World, filename string, interval time.Duration, resume bool, progress func(rt.RenderProgress)) (*rt.Canvas, error) {
+	var checkpoint *rt.Checkpoint
+	var err error
+	if resume {
+		checkpoint, err = rt.LoadCheckpoint(filename)
+	} else {
+		checkpoint, err = rt.NewCheckpoint(camera, world)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	canvas, err := camera.RenderCheckpointed(ctx, world, checkpoint, interval, func(checkpoint *rt.Checkpoint) error {
+		return rt.SaveCheckpoint(filename, checkpoint)
+	}, progress)
+	if canvas == nil && resume {
+		return nil, fmt.Errorf("can't resume from %s: %v", filename, err)
+	} else if canvas == nil {
+		return nil, err
+	}
+
+	if err == nil {
+		err = os.Remove(filename)
+	}
+
+	return canvas, err
+}
+
+// Returns the name of the file a pass is saved to, which is the output file's name with the pass's
+// name before its extension.
+func passFilename(output string, pass string) string {
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + "." + pass + ext
+}
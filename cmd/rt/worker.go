@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/jefflinse/go-ray-tracer"
+)
+
+func runWorker(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("worker", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: rt worker [flags] address")
+		fmt.Fprintln(stderr, "Renders tiles for the rt render or rt animate command listening on address with -listen.")
+		flags.PrintDefaults()
+	}
+
+	threads := flags.Int("threads", 0, "number of render threads (default one per CPU)")
+	quiet := flags.Bool("q", false, "don't print when connected")
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+
+	if len(positional) != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	fail := func(err error) int {
+		fmt.Fprintf(stderr, "rt worker: %v\n", err)
+		return exitError
+	}
+
+	if *threads < 0 {
+		return fail(fmt.Errorf("threads must be positive"))
+	}
+
+	conn, err := net.Dial("tcp", positional[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	if !*quiet {
+		fmt.Fprintf(stderr, "rendering for %s\n", conn.RemoteAddr())
+	}
+
+	if err := rt.RunWorker(conn, *threads); err != nil {
+		return fail(err)
+	}
+
+	return exitOK
+}
@@ -58,6 +58,11 @@ func (c Color) HadamardBlend(other Color) Color {
 	return NewColor(c[0]*other[0], c[1]*other[1], c[2]*other[2])
 }
 
+// Luminance returns the relative luminance of this color using the Rec. 709 coefficients.
+func (c Color) Luminance() float64 {
+	return .2126*c[0] + .7152*c[1] + .0722*c[2]
+}
+
 // ToPPM retuns the PPM-formatted string representation of a Color.
 func (c Color) ToPPM() string {
 	if c == nil {
@@ -50,6 +50,12 @@ func TestColor_HadamardBlend(t *testing.T) {
 	assert.True(t, c1.HadamardBlend(c2).Equals(NewColor(.9, .2, .04)))
 }
 
+func TestColor_Luminance(t *testing.T) {
+	assert.True(t, eq(1, NewColor(1, 1, 1).Luminance()))
+	assert.True(t, eq(.7152, NewColor(0, 1, 0).Luminance()))
+	assert.True(t, eq(0, NewColor(0, 0, 0).Luminance()))
+}
+
 func TestColor_ToPPM(t *testing.T) {
 	var c Color
 	assert.Equal(t, "0 0 0", c.ToPPM())
@@ -0,0 +1,125 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+)
+
+// DenoiseGuides are the auxiliary passes (see RenderPasses) that guide a Denoiser around the
+// edges in an image. Any of them may be nil, in which case it isn't used.
+type DenoiseGuides struct {
+	Normal *Canvas
+	Albedo *Canvas
+	Depth  *Canvas
+}
+
+// A Denoiser smooths out sampling noise with an edge-avoiding à-trous wavelet filter, which
+// repeatedly blurs the image with a widening 5x5 kernel while giving less weight to neighbors
+// whose color, normal, albedo, or depth differ from the pixel's. The sigmas set how large a
+// difference must be to stop the blur: smaller sigmas keep more edges, and more noise.
+type Denoiser struct {
+	Iterations  int
+	ColorSigma  float64
+	NormalSigma float64
+	AlbedoSigma float64
+
+	// DepthSigma is relative to the pixel's depth.
+	DepthSigma float64
+}
+
+// NewDenoiser creates a new Denoiser with settings that suit most renders.
+func NewDenoiser() *Denoiser {
+	return &Denoiser{
+		Iterations:  5,
+		ColorSigma:  4,
+		NormalSigma: .2,
+		AlbedoSigma: .1,
+		DepthSigma:  .05,
+	}
+}
+
+// The weights of the B3 spline kernel the filter is built from.
+var denoiseKernel = [5]float64{1.0 / 16, 1.0 / 4, 3.0 / 8, 1.0 / 4, 1.0 / 16}
+
+// Denoise creates a new Canvas by filtering the noise out of this one, guided by the passes
+// rendered along with it, which must be the same size as the canvas.
+func (c *Canvas) Denoise(denoiser *Denoiser, guides DenoiseGuides) (*Canvas, error) {
+	names := []string{"normal", "albedo", "depth"}
+	for i, guide := range []*Canvas{guides.Normal, guides.Albedo, guides.Depth} {
+		if guide != nil && (guide.width != c.width || guide.height != c.height) {
+			return nil, fmt.Errorf("%s guide is %dx%d, but the image is %dx%d", names[i], guide.width, guide.height, c.width, c.height)
+		}
+	}
+
+	pixel := func(canvas *Canvas, x int, y int) Color {
+		if canvas == nil || canvas.pixels[y][x] == nil {
+			return NewColor(0, 0, 0)
+		}
+
+		return canvas.pixels[y][x]
+	}
+
+	filtered := c.clone()
+
+	// the color sigma halves with each iteration, as the noise it's tolerating is smoothed away
+	colorSigma := denoiser.ColorSigma
+	for i, step := 0, 1; i < denoiser.Iterations; i, step = i+1, step*2 {
+		next := NewCanvas(c.width, c.height)
+		for y := 0; y < c.height; y++ {
+			for x := 0; x < c.width; x++ {
+				color := pixel(filtered, x, y)
+				normal, albedo, depth := pixel(guides.Normal, x, y), pixel(guides.Albedo, x, y), pixel(guides.Depth, x, y).Red()
+				sum, totalWeight := NewColor(0, 0, 0), 0.0
+				for ky, wy := range denoiseKernel {
+					qy := y + (ky-2)*step
+					if qy < 0 || qy >= c.height {
+						continue
+					}
+
+					for kx, wx := range denoiseKernel {
+						qx := x + (kx-2)*step
+						if qx < 0 || qx >= c.width {
+							continue
+						}
+
+						neighbor := pixel(filtered, qx, qy)
+						weight := wx * wy * edgeWeight(color, neighbor, colorSigma)
+						if guides.Normal != nil {
+							weight *= edgeWeight(normal, pixel(guides.Normal, qx, qy), denoiser.NormalSigma)
+						}
+
+						if guides.Albedo != nil {
+							weight *= edgeWeight(albedo, pixel(guides.Albedo, qx, qy), denoiser.AlbedoSigma)
+						}
+
+						if guides.Depth != nil {
+							neighborDepth := pixel(guides.Depth, qx, qy).Red()
+							if scale := math.Max(depth, neighborDepth); scale > 0 {
+								weight *= math.Exp(-math.Abs(depth-neighborDepth) / (denoiser.DepthSigma * scale))
+							}
+						}
+
+						sum = sum.Add(neighbor.Multiply(weight))
+						totalWeight += weight
+					}
+				}
+
+				// the pixel itself always has weight, so the total is never 0
+				next.pixels[y][x] = sum.Multiply(1 / totalWeight)
+			}
+		}
+
+		filtered = next
+		colorSigma /= 2
+	}
+
+	return filtered, nil
+}
+
+// Returns the weight given to a neighbor whose value differs from a pixel's, which falls off
+// with the square of the distance between their colors.
+func edgeWeight(a Color, b Color, sigma float64) float64 {
+	diff := a.Subtract(b)
+	distance := diff.Red()*diff.Red() + diff.Green()*diff.Green() + diff.Blue()*diff.Blue()
+	return math.Exp(-distance / (sigma * sigma))
+}
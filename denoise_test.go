@@ -0,0 +1,75 @@
+package rt
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanvas_Denoise(t *testing.T) {
+	// a noisy flat region, split down the middle by an edge in the normals
+	rng := rand.New(rand.NewSource(1))
+	noisy, normals := NewCanvas(16, 16), NewCanvas(16, 16)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			base, normal := .2, NewColor(0, 0, -1)
+			if x >= 8 {
+				base, normal = .8, NewColor(-1, 0, 0)
+			}
+
+			value := base + (rng.Float64()-.5)*.2
+			noisy.WritePixel(x, y, NewColor(value, value, value))
+			normals.WritePixel(x, y, normal)
+		}
+	}
+
+	denoised, err := noisy.Denoise(NewDenoiser(), DenoiseGuides{Normal: normals})
+	require.NoError(t, err)
+	variance := func(canvas *Canvas, minX int, maxX int, mean float64) float64 {
+		sum := 0.0
+		for y := 0; y < 16; y++ {
+			for x := minX; x < maxX; x++ {
+				diff := canvas.PixelAt(x, y).Red() - mean
+				sum += diff * diff
+			}
+		}
+
+		return sum / float64(16*(maxX-minX))
+	}
+
+	// the noise goes down on both sides, without the sides bleeding into each other
+	assert.Less(t, variance(denoised, 0, 8, .2), variance(noisy, 0, 8, .2)/10)
+	assert.Less(t, variance(denoised, 8, 16, .8), variance(noisy, 8, 16, .8)/10)
+
+	// the original is untouched
+	assert.NotEqual(t, noisy, denoised)
+
+	// guides must be the same size as the image
+	_, err = noisy.Denoise(NewDenoiser(), DenoiseGuides{Normal: normals, Depth: NewCanvas(16, 32)})
+	assert.EqualError(t, err, "depth guide is 16x32, but the image is 16x16")
+}
+
+func TestCanvas_Denoise_render(t *testing.T) {
+	world, c := newCornellBox(24)
+	c.Samples = 8
+	reference := c.Render(world)
+
+	c.Samples = 1
+	noisy, passes, err := c.RenderPasses(context.Background(), world, []string{"normal", "albedo", "depth"}, nil)
+	require.NoError(t, err)
+	denoised, err := noisy.Denoise(NewDenoiser(), DenoiseGuides{
+		Normal: passes["normal"],
+		Albedo: passes["albedo"],
+		Depth:  passes["depth"],
+	})
+
+	require.NoError(t, err)
+
+	// denoising brings a single sample per pixel much closer to the reference
+	before, after := meanSquaredError(noisy, reference), meanSquaredError(denoised, reference)
+	t.Logf("mean squared error before %f, after %f", before, after)
+	assert.Less(t, after, before/2)
+}
@@ -0,0 +1,494 @@
+package rt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// A Coordinator renders images by handing out their tiles to worker processes (see RunWorker),
+// which connect to it over TCP. Workers may come and go at any time: the tiles a worker was
+// rendering when its connection is lost, or that it doesn't finish in time, are handed out
+// again. A Coordinator renders one image at a time, and its workers stay connected from one
+// render to the next.
+type Coordinator struct {
+	// Dir is the directory workers resolve relative image file names against. Workers load images
+	// themselves, so they must be able to reach the scene's files by the same names.
+	Dir string
+
+	// TileTimeout is how long a worker has to render a tile before it's handed out to another
+	// worker, and the first worker's result is ignored. Zero means five minutes.
+	TileTimeout time.Duration
+
+	listener net.Listener
+	mutex    sync.Mutex
+	sessions map[*coordinatorSession]bool
+
+	// changed is signaled when tiles are queued, the render changes, or the coordinator closes
+	changed *sync.Cond
+	render  *distributedRender
+	renders int
+	closed  bool
+}
+
+// A distributedRender is the image a Coordinator is rendering.
+type distributedRender struct {
+	id       int
+	scene    []byte
+	tiles    []image.Rectangle
+	queue    []int
+	done     []bool
+	canvas   *Canvas
+	finished chan struct{}
+
+	tilesDone       int
+	samplesPerPixel int
+	rays            int
+	stats           *RenderStats
+	start           time.Time
+	progress        func(RenderProgress)
+}
+
+var errCoordinatorClosed = errors.New("coordinator is closed")
+
+// A TileJob is a tile handed out to a worker by a Coordinator.
+type TileJob struct {
+	Render int
+	Index  int
+	Tile   image.Rectangle
+}
+
+// A TileResult is a tile rendered by a worker, with the red, green, and blue of each of its
+// pixels, a row at a time, and the work it took.
+type TileResult struct {
+	Render int
+	Index  int
+	Pixels []float64
+	Stats  *RenderStats
+}
+
+// NewCoordinator creates a new Coordinator that accepts workers from a listener until it's closed.
+func NewCoordinator(listener net.Listener) *Coordinator {
+	co := &Coordinator{listener: listener, sessions: map[*coordinatorSession]bool{}}
+	co.changed = sync.NewCond(&co.mutex)
+	go co.accept()
+	return co
+}
+
+// Addr returns the address workers connect to.
+func (co *Coordinator) Addr() net.Addr {
+	return co.listener.Addr()
+}
+
+// Close stops accepting workers, and tells the ones that are connected to stop.
+func (co *Coordinator) Close() error {
+	co.mutex.Lock()
+	co.closed = true
+	co.changed.Broadcast()
+	co.mutex.Unlock()
+	return co.listener.Close()
+}
+
+// Accepts workers until the listener is closed.
+func (co *Coordinator) accept() {
+	for {
+		conn, err := co.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go co.serve(conn)
+	}
+}
+
+// Serves the requests of a worker until its connection is lost, then hands out the tiles it
+// was rendering again.
+func (co *Coordinator) serve(conn net.Conn) {
+	session := &coordinatorSession{co: co, assigned: map[TileJob]time.Time{}, overdue: map[TileJob]int{}}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Coordinator", session); err != nil {
+		conn.Close()
+		return
+	}
+
+	co.mutex.Lock()
+	co.sessions[session] = true
+	co.mutex.Unlock()
+
+	server.ServeConn(conn)
+
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	delete(co.sessions, session)
+	if r := co.render; r != nil {
+		session.prune(r)
+		for job := range session.assigned {
+			if !r.done[job.Index] {
+				r.queue = append(r.queue, job.Index)
+			}
+		}
+
+		co.changed.Broadcast()
+	}
+}
+
+// Render renders a world with a camera as RenderContext does, but on the coordinator's workers.
+// It waits for workers to connect if there are none.
+func (co *Coordinator) Render(ctx context.Context, c *Camera, world *World, progress func(RenderProgress)) (*Canvas, error) {
+	scene, err := json.Marshal(NewScene(c, world))
+	if err != nil {
+		return nil, err
+	}
+
+	co.mutex.Lock()
+	if co.closed {
+		co.mutex.Unlock()
+		return nil, errCoordinatorClosed
+	}
+
+	co.renders++
+	tiles := c.tiles()
+	r := &distributedRender{
+		id:       co.renders,
+		scene:    scene,
+		tiles:    tiles,
+		queue:    make([]int, len(tiles)),
+		done:     make([]bool, len(tiles)),
+		canvas:   NewCanvas(c.HSize, c.VSize),
+		finished: make(chan struct{}),
+		stats:    NewRenderStats(),
+		start:    time.Now(),
+		progress: progress,
+	}
+
+	r.samplesPerPixel = c.Samples * c.Samples
+	if r.samplesPerPixel < 1 {
+		r.samplesPerPixel = 1
+	}
+
+	for i := range tiles {
+		r.queue[i] = i
+	}
+
+	co.render = r
+	co.changed.Broadcast()
+	co.mutex.Unlock()
+
+	err = co.wait(ctx, r)
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	co.render = nil
+	return r.canvas, err
+}
+
+// Waits for a render to finish or be canceled, handing out its overdue tiles again. Overdue
+// tiles are looked for several times per timeout, so they're handed out soon after they're due.
+func (co *Coordinator) wait(ctx context.Context, r *distributedRender) error {
+	ticker := time.NewTicker(co.tileTimeout() / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.finished:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			co.mutex.Lock()
+			co.requeueOverdue(r)
+			co.mutex.Unlock()
+		}
+	}
+}
+
+// Returns how long a worker has to render a tile.
+func (co *Coordinator) tileTimeout() time.Duration {
+	if co.TileTimeout <= 0 {
+		return 5 * time.Minute
+	}
+
+	return co.TileTimeout
+}
+
+// Hands out the tiles of a render that are overdue again.
+func (co *Coordinator) requeueOverdue(r *distributedRender) {
+	now := time.Now()
+	for session := range co.sessions {
+		for job, due := range session.assigned {
+			if job.Render != r.id || now.Before(due) {
+				continue
+			}
+
+			delete(session.assigned, job)
+			session.overdue[job]++
+			if !r.done[job.Index] {
+				r.queue = append(r.queue, job.Index)
+				co.changed.Broadcast()
+			}
+		}
+	}
+}
+
+// A coordinatorSession answers the requests of a worker, and keeps track of the tiles it's
+// rendering.
+type coordinatorSession struct {
+	co *Coordinator
+
+	// the tiles handed out to the worker, and when they're due
+	assigned map[TileJob]time.Time
+
+	// how many times each tile was handed out again once it was overdue, which is how many of
+	// the worker's results for it are ignored
+	overdue map[TileJob]int
+}
+
+// Forgets the tiles of renders other than the current one.
+func (s *coordinatorSession) prune(r *distributedRender) {
+	for job := range s.assigned {
+		if r == nil || job.Render != r.id {
+			delete(s.assigned, job)
+		}
+	}
+
+	for job := range s.overdue {
+		if r == nil || job.Render != r.id {
+			delete(s.overdue, job)
+		}
+	}
+}
+
+// NextTile waits for a tile to render and hands it out.
+func (s *coordinatorSession) NextTile(_ int, job *TileJob) error {
+	co := s.co
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	for !co.closed && (co.render == nil || len(co.render.queue) == 0) {
+		co.changed.Wait()
+	}
+
+	if co.closed {
+		return errCoordinatorClosed
+	}
+
+	r := co.render
+	s.prune(r)
+	index := r.queue[0]
+	r.queue = r.queue[1:]
+	*job = TileJob{Render: r.id, Index: index, Tile: r.tiles[index]}
+	s.assigned[*job] = time.Now().Add(co.tileTimeout())
+	return nil
+}
+
+// Scene returns the scene of a render, as JSON.
+func (s *coordinatorSession) Scene(render int, scene *[]byte) error {
+	co := s.co
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	if co.render == nil || co.render.id != render {
+		return fmt.Errorf("render %d is over", render)
+	}
+
+	*scene = co.render.scene
+	return nil
+}
+
+// Dir returns the directory relative image file names are resolved against.
+func (s *coordinatorSession) Dir(_ int, dir *string) error {
+	*dir = s.co.Dir
+	return nil
+}
+
+// SubmitTile adds a rendered tile to its image.
+func (s *coordinatorSession) SubmitTile(result TileResult, _ *int) error {
+	co := s.co
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	r := co.render
+	s.prune(r)
+	if r == nil || r.id != result.Render {
+		// the render was canceled while the tile was rendering
+		return nil
+	}
+
+	if result.Index < 0 || result.Index >= len(r.tiles) {
+		return fmt.Errorf("render %d has no tile %d", r.id, result.Index)
+	}
+
+	tile := r.tiles[result.Index]
+	job := TileJob{Render: result.Render, Index: result.Index, Tile: tile}
+	if _, ok := s.assigned[job]; !ok {
+		if s.overdue[job] == 0 {
+			return fmt.Errorf("tile %d wasn't handed out to this worker", result.Index)
+		}
+
+		// the tile took too long, and was handed out again
+		s.overdue[job]--
+		return nil
+	}
+
+	delete(s.assigned, job)
+	if r.done[result.Index] {
+		// a tile that was handed out again can be finished twice
+		return nil
+	}
+
+	if len(result.Pixels) != tile.Dx()*tile.Dy()*3 {
+		r.queue = append(r.queue, result.Index)
+		co.changed.Broadcast()
+		return fmt.Errorf("tile %d has %d pixels, not %d", result.Index, len(result.Pixels)/3, tile.Dx()*tile.Dy())
+	}
+
+	pixels := result.Pixels
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			r.canvas.WritePixel(x, y, NewColor(pixels[0], pixels[1], pixels[2]))
+			pixels = pixels[3:]
+		}
+	}
+
+	r.done[result.Index] = true
+	r.tilesDone++
+	r.rays += tile.Dx() * tile.Dy() * r.samplesPerPixel
+	if result.Stats != nil {
+		r.stats.Merge(result.Stats)
+	}
+
+	if r.progress != nil {
+		elapsed := time.Since(r.start)
+		r.progress(RenderProgress{
+			TilesDone:     r.tilesDone,
+			TilesTotal:    len(r.tiles),
+			Elapsed:       elapsed,
+			ETA:           elapsed * time.Duration(len(r.tiles)-r.tilesDone) / time.Duration(r.tilesDone),
+			RaysPerSecond: float64(r.rays) / elapsed.Seconds(),
+			Stats:         r.stats,
+		})
+	}
+
+	if r.tilesDone == len(r.tiles) {
+		close(r.finished)
+	}
+
+	return nil
+}
+
+// RunWorker renders tiles handed out by the coordinator at the other end of a connection, on the
+// specified number of threads (zero means one per CPU), until the coordinator closes. The tiles
+// are rendered just as Camera.Render renders them, so the image is the same.
+func RunWorker(conn io.ReadWriteCloser, threads int) error {
+	client := rpc.NewClient(conn)
+	defer client.Close()
+
+	var dir string
+	if err := client.Call("Coordinator.Dir", 0, &dir); err != nil {
+		return err
+	}
+
+	if threads <= 0 {
+		threads = (&Camera{}).threads()
+	}
+
+	w := &worker{client: client, dir: dir}
+	errs := make(chan error, threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			errs <- w.run()
+		}()
+	}
+
+	// the first thread to stop closes the connection, which stops the others
+	err := <-errs
+	client.Close()
+	for i := 1; i < threads; i++ {
+		<-errs
+	}
+
+	// errors returned by the coordinator arrive as ServerErrors holding their messages
+	if err == rpc.ErrShutdown || err == io.ErrUnexpectedEOF || err.Error() == errCoordinatorClosed.Error() {
+		return nil
+	}
+
+	return err
+}
+
+// A worker renders the tiles handed out by a coordinator.
+type worker struct {
+	client *rpc.Client
+	dir    string
+
+	mutex  sync.Mutex
+	render int
+	scene  *Scene
+	canvas *Canvas
+}
+
+// Renders tiles until the connection is closed.
+func (w *worker) run() error {
+	for {
+		var job TileJob
+		if err := w.client.Call("Coordinator.NextTile", 0, &job); err != nil {
+			return err
+		}
+
+		scene, canvas, err := w.sceneFor(job.Render)
+		if err != nil {
+			return err
+		}
+
+		if scene == nil {
+			// the render is over
+			continue
+		}
+
+		// each tile writes only its own pixels, so threads can share the canvas
+		stats := scene.Camera.renderTile(scene.World, canvas, nil, job.Tile)
+		result := TileResult{Render: job.Render, Index: job.Index, Stats: stats}
+		for y := job.Tile.Min.Y; y < job.Tile.Max.Y; y++ {
+			for x := job.Tile.Min.X; x < job.Tile.Max.X; x++ {
+				result.Pixels = append(result.Pixels, canvas.PixelAt(x, y)[:3]...)
+			}
+		}
+
+		if err := w.client.Call("Coordinator.SubmitTile", result, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the scene of a render, and a canvas to render it on, fetching the scene from the
+// coordinator the first time it's needed. Returns a nil scene if the render is over.
+func (w *worker) sceneFor(render int) (*Scene, *Canvas, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.render == render {
+		return w.scene, w.canvas, nil
+	}
+
+	var data []byte
+	if err := w.client.Call("Coordinator.Scene", render, &data); err != nil {
+		if _, ok := err.(rpc.ServerError); ok {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	var scene Scene
+	if err := json.Unmarshal(data, &scene); err != nil {
+		return nil, nil, err
+	}
+
+	if err := scene.LoadImages(w.dir); err != nil {
+		return nil, nil, err
+	}
+
+	w.render, w.scene = render, &scene
+	w.canvas = NewCanvas(scene.Camera.HSize, scene.Camera.VSize)
+	return w.scene, w.canvas, nil
+}
@@ -0,0 +1,210 @@
+package rt
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Starts a worker connected to a coordinator, returning a channel that receives its result.
+func startWorker(t *testing.T, co *Coordinator, threads int) <-chan error {
+	conn, err := net.Dial("tcp", co.Addr().String())
+	require.NoError(t, err)
+	result := make(chan error, 1)
+	go func() {
+		result <- RunWorker(conn, threads)
+	}()
+
+	return result
+}
+
+func TestCoordinator_Render(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	co := NewCoordinator(listener)
+
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.Samples = 2
+	c.TileSize = 4
+
+	workers := []<-chan error{startWorker(t, co, 1), startWorker(t, co, 2), startWorker(t, co, 3)}
+	calls := 0
+	var stats *RenderStats
+	image, err := co.Render(context.Background(), c, w, func(p RenderProgress) {
+		calls++
+		assert.Equal(t, calls, p.TilesDone)
+		assert.Equal(t, 9, p.TilesTotal)
+		stats = p.Stats
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 9, calls)
+	assert.Equal(t, c.Render(w), image)
+
+	// the workers' statistics are sent with their tiles
+	_, local, err := c.RenderWithStats(context.Background(), w, nil)
+	require.NoError(t, err)
+	assert.Equal(t, local.Rays(), stats.Rays())
+	assert.Equal(t, local.IntersectionTests, stats.IntersectionTests)
+	assert.Len(t, stats.Tiles, 9)
+
+	// the workers stay for the next render
+	c.Integrator = NewPathTracer()
+	image, err = co.Render(context.Background(), c, w, nil)
+	require.NoError(t, err)
+	assert.Equal(t, c.Render(w), image)
+
+	// and stop when the coordinator closes
+	require.NoError(t, co.Close())
+	for _, result := range workers {
+		assert.NoError(t, <-result)
+	}
+
+	_, err = co.Render(context.Background(), c, w, nil)
+	assert.EqualError(t, err, "coordinator is closed")
+}
+
+func TestCoordinator_Render_workerLost(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	co := NewCoordinator(listener)
+	defer co.Close()
+
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.TileSize = 4
+
+	type rendered struct {
+		image *Canvas
+		err   error
+	}
+
+	result := make(chan rendered, 1)
+	go func() {
+		image, err := co.Render(context.Background(), c, w, nil)
+		result <- rendered{image, err}
+	}()
+
+	// a worker takes a tile and dies without finishing it
+	conn, err := net.Dial("tcp", co.Addr().String())
+	require.NoError(t, err)
+	client := rpc.NewClient(conn)
+	var job TileJob
+	require.NoError(t, client.Call("Coordinator.NextTile", 0, &job))
+	client.Close()
+
+	// so the tile is rendered by another worker
+	startWorker(t, co, 2)
+	r := <-result
+	require.NoError(t, r.err)
+	assert.Equal(t, c.Render(w), r.image)
+}
+
+func TestCoordinator_Render_workerHung(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	co := NewCoordinator(listener)
+	co.TileTimeout = 20 * time.Millisecond
+	defer co.Close()
+
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.TileSize = 4
+
+	type rendered struct {
+		image *Canvas
+		err   error
+	}
+
+	result := make(chan rendered, 1)
+	go func() {
+		image, err := co.Render(context.Background(), c, w, nil)
+		result <- rendered{image, err}
+	}()
+
+	// a worker takes a tile and stops responding while staying connected
+	conn, err := net.Dial("tcp", co.Addr().String())
+	require.NoError(t, err)
+	client := rpc.NewClient(conn)
+	defer client.Close()
+	var job TileJob
+	require.NoError(t, client.Call("Coordinator.NextTile", 0, &job))
+	time.Sleep(100 * time.Millisecond)
+
+	// so its result is ignored once it's overdue
+	pixels := make([]float64, job.Tile.Dx()*job.Tile.Dy()*3)
+	require.NoError(t, client.Call("Coordinator.SubmitTile", TileResult{Render: job.Render, Index: job.Index, Pixels: pixels}, nil))
+
+	// and the tile is rendered by another worker
+	startWorker(t, co, 2)
+	r := <-result
+	require.NoError(t, r.err)
+	assert.Equal(t, c.Render(w), r.image)
+}
+
+func TestCoordinator_Render_pruned(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	co := NewCoordinator(listener)
+	defer co.Close()
+
+	conn, err := net.Dial("tcp", co.Addr().String())
+	require.NoError(t, err)
+	client := rpc.NewClient(conn)
+	defer client.Close()
+
+	// a worker takes a tile of each of two renders, the first of which is canceled
+	c := NewCamera(8, 8, math.Pi/2)
+	c.TileSize = 4
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		rendered := make(chan error, 1)
+		go func() {
+			_, err := co.Render(ctx, c, NewDefaultWorld(), nil)
+			rendered <- err
+		}()
+
+		var job TileJob
+		require.NoError(t, client.Call("Coordinator.NextTile", 0, &job))
+		assert.Equal(t, i+1, job.Render)
+		if i == 0 {
+			cancel()
+			assert.Equal(t, context.Canceled, <-rendered)
+		}
+	}
+
+	// only the tile of the current render is remembered
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+	require.Len(t, co.sessions, 1)
+	for session := range co.sessions {
+		assert.Len(t, session.assigned, 1)
+		for job := range session.assigned {
+			assert.Equal(t, 2, job.Render)
+		}
+	}
+}
+
+func TestCoordinator_Render_canceled(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	co := NewCoordinator(listener)
+	defer co.Close()
+
+	// with no workers, nothing is rendered
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	image, err := co.Render(ctx, NewCamera(4, 4, math.Pi/2), NewDefaultWorld(), nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Nil(t, image.PixelAt(0, 0))
+}
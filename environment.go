@@ -0,0 +1,287 @@
+package rt
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// An Environment is the light arriving from infinitely far away, which is seen by rays that
+// escape the scene.
+type Environment interface {
+	// Radiance returns the light arriving from a direction.
+	Radiance(direction Tuple) Color
+}
+
+// An EnvironmentSampler is an environment that can choose directions at random in proportion
+// to the light arriving from them, so the path tracer can light surfaces with it efficiently.
+type EnvironmentSampler interface {
+	Environment
+
+	// SampleDirection returns a random unit direction and the probability density of choosing
+	// it per unit of solid angle.
+	SampleDirection(rng *rand.Rand) (direction Tuple, pdf float64)
+
+	// PDF returns the probability density of SampleDirection choosing a direction.
+	PDF(direction Tuple) float64
+}
+
+// A SolidEnvironment is the same color in every direction.
+type SolidEnvironment struct {
+	Color Color
+}
+
+// NewSolidEnvironment creates a new SolidEnvironment.
+func NewSolidEnvironment(color Color) *SolidEnvironment {
+	return &SolidEnvironment{color}
+}
+
+// Radiance returns the light arriving from a direction.
+func (e *SolidEnvironment) Radiance(direction Tuple) Color {
+	return e.Color
+}
+
+// A SkyEnvironment blends from the horizon color straight ahead to the zenith color overhead,
+// above a ground of a single color.
+type SkyEnvironment struct {
+	Zenith  Color
+	Horizon Color
+	Ground  Color
+}
+
+// NewSkyEnvironment creates a new SkyEnvironment for a clear blue sky over gray ground.
+func NewSkyEnvironment() *SkyEnvironment {
+	return &SkyEnvironment{
+		Zenith:  NewColor(.5, .7, 1),
+		Horizon: NewColor(1, 1, 1),
+		Ground:  NewColor(.4, .4, .4),
+	}
+}
+
+// Radiance returns the light arriving from a direction.
+func (e *SkyEnvironment) Radiance(direction Tuple) Color {
+	y := direction.Normalize().Y()
+	if y < 0 {
+		return e.Ground
+	}
+
+	return mixColors(e.Horizon, e.Zenith, y)
+}
+
+// An ImageEnvironment surrounds the scene with an equirectangular image, usually a high dynamic
+// range photograph of real surroundings. The image's center looks along +Z, with +X to its
+// right and +Y at its top edge.
+type ImageEnvironment struct {
+	Canvas *Canvas
+
+	// File is the name of the image file the canvas was loaded from, if any. Scene files refer
+	// to the image by this name.
+	File string
+
+	// Intensity scales the brightness of the image.
+	Intensity float64
+
+	// Transform orients the image around the scene.
+	Transform Transformation
+
+	once         sync.Once
+	distribution *environmentDistribution
+}
+
+// NewImageEnvironment creates a new ImageEnvironment.
+func NewImageEnvironment(canvas *Canvas) *ImageEnvironment {
+	return &ImageEnvironment{Canvas: canvas, Intensity: 1, Transform: NewTransform()}
+}
+
+// LoadImageEnvironment creates a new ImageEnvironment from an image file.
+func LoadImageEnvironment(filename string) (*ImageEnvironment, error) {
+	canvas, err := LoadCanvas(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	environment := NewImageEnvironment(canvas)
+	environment.File = filename
+	return environment, nil
+}
+
+// Radiance returns the light arriving from a direction.
+func (e *ImageEnvironment) Radiance(direction Tuple) Color {
+	u, polar := equirectangularMap(e.Transform.Inverse().ApplyTo(direction))
+
+	// blend the four pixels whose centers surround the point, repeating the image around the
+	// vertical axis
+	width, height := e.Canvas.Width(), e.Canvas.Height()
+	x := u*float64(width) - .5
+	y := polar/math.Pi*float64(height) - .5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := x-x0, y-y0
+	pixel := func(x int, y int) Color {
+		color := e.Canvas.PixelAt(RepeatWrap.apply(x, width), ClampWrap.apply(y, height))
+		if color == nil {
+			return black
+		}
+
+		return color
+	}
+
+	ix, iy := int(x0), int(y0)
+	top := mixColors(pixel(ix, iy), pixel(ix+1, iy), tx)
+	bottom := mixColors(pixel(ix, iy+1), pixel(ix+1, iy+1), tx)
+	return mixColors(top, bottom, ty).Multiply(e.Intensity)
+}
+
+// SampleDirection returns a random direction, chosen in proportion to the brightness of the
+// image in that direction.
+func (e *ImageEnvironment) SampleDirection(rng *rand.Rand) (Tuple, float64) {
+	d := e.sampling()
+	if d == nil {
+		return NewVector(0, 0, 1), 0
+	}
+
+	x, y := d.sample(rng)
+	u := (float64(x) + rng.Float64()) / float64(d.width)
+	polar := (float64(y) + rng.Float64()) / float64(d.height) * math.Pi
+	direction := e.Transform.ApplyTo(equirectangularDirection(u, polar))
+	return direction, d.pdf(x, y, polar)
+}
+
+// PDF returns the probability density of SampleDirection choosing a direction.
+func (e *ImageEnvironment) PDF(direction Tuple) float64 {
+	d := e.sampling()
+	if d == nil {
+		return 0
+	}
+
+	u, polar := equirectangularMap(e.Transform.Inverse().ApplyTo(direction))
+	x := ClampWrap.apply(int(u*float64(d.width)), d.width)
+	y := ClampWrap.apply(int(polar/math.Pi*float64(d.height)), d.height)
+	return d.pdf(x, y, polar)
+}
+
+// Returns the distribution used to sample the image, building it the first time it's needed,
+// or nil if the image is black.
+func (e *ImageEnvironment) sampling() *environmentDistribution {
+	e.once.Do(func() {
+		e.distribution = newEnvironmentDistribution(e.Canvas)
+	})
+
+	return e.distribution
+}
+
+// An environmentDistribution chooses pixels of an equirectangular image with probabilities
+// proportional to their share of the light arriving from the environment.
+type environmentDistribution struct {
+	width, height int
+
+	// rows is the cumulative distribution of rows, and columns holds the cumulative
+	// distribution of each row's pixels.
+	rows    []float64
+	columns [][]float64
+
+	// probabilities holds the probability of choosing each pixel.
+	probabilities [][]float64
+}
+
+// Returns the distribution for an image, or nil if the image has no light to sample.
+func newEnvironmentDistribution(canvas *Canvas) *environmentDistribution {
+	width, height := canvas.Width(), canvas.Height()
+	d := &environmentDistribution{
+		width:         width,
+		height:        height,
+		rows:          make([]float64, height+1),
+		columns:       make([][]float64, height),
+		probabilities: make([][]float64, height),
+	}
+
+	// pixels near the poles cover less of the sphere than those at the equator
+	for y := 0; y < height; y++ {
+		d.columns[y] = make([]float64, width+1)
+		d.probabilities[y] = make([]float64, width)
+		sinPolar := math.Sin((float64(y) + .5) / float64(height) * math.Pi)
+		for x := 0; x < width; x++ {
+			weight := 0.0
+			if color := canvas.PixelAt(x, y); color != nil {
+				weight = math.Max(color.Luminance(), 0) * sinPolar
+			}
+
+			d.probabilities[y][x] = weight
+			d.columns[y][x+1] = d.columns[y][x] + weight
+		}
+
+		d.rows[y+1] = d.rows[y] + d.columns[y][width]
+	}
+
+	total := d.rows[height]
+	if total <= 0 {
+		return nil
+	}
+
+	for y := 0; y < height; y++ {
+		rowTotal := d.columns[y][width]
+		for x := 0; x < width; x++ {
+			d.probabilities[y][x] /= total
+			if rowTotal > 0 {
+				d.columns[y][x+1] /= rowTotal
+			}
+		}
+
+		d.rows[y+1] /= total
+	}
+
+	return d
+}
+
+// Returns a pixel chosen at random.
+func (d *environmentDistribution) sample(rng *rand.Rand) (x int, y int) {
+	y = sampleCDF(d.rows, rng.Float64())
+	x = sampleCDF(d.columns[y], rng.Float64())
+	return x, y
+}
+
+// Returns the probability density per unit solid angle of choosing a direction within pixel x, y,
+// at the given angle from the top of the image.
+func (d *environmentDistribution) pdf(x int, y int, polar float64) float64 {
+	sinPolar := math.Sin(polar)
+	if sinPolar <= 0 {
+		return 0
+	}
+
+	// each pixel covers 2π / width by π / height of the image, shrunk by sin(polar) on the sphere
+	return d.probabilities[y][x] * float64(d.width*d.height) / (2 * math.Pi * math.Pi * sinPolar)
+}
+
+// Returns the index of the interval of a cumulative distribution that contains value.
+func sampleCDF(cdf []float64, value float64) int {
+	i := sort.Search(len(cdf)-1, func(i int) bool {
+		return cdf[i+1] > value
+	})
+
+	if i >= len(cdf)-1 {
+		i = len(cdf) - 2
+	}
+
+	// skip empty intervals, which can't be chosen
+	for i > 0 && cdf[i+1] == cdf[i] {
+		i--
+	}
+
+	return i
+}
+
+// Returns the equirectangular coordinates of a direction: u from 0 to 1 around the vertical axis,
+// with +Z at .5, and the angle down from +Y.
+func equirectangularMap(direction Tuple) (u float64, polar float64) {
+	d := direction.Normalize()
+	u = .5 + math.Atan2(d.X(), d.Z())/(2*math.Pi)
+	polar = math.Acos(clamp(d.Y(), -1, 1))
+	return u, polar
+}
+
+// Returns the unit direction with the given equirectangular coordinates.
+func equirectangularDirection(u float64, polar float64) Tuple {
+	azimuth := (u - .5) * 2 * math.Pi
+	sinPolar := math.Sin(polar)
+	return NewVector(sinPolar*math.Sin(azimuth), math.Cos(polar), sinPolar*math.Cos(azimuth))
+}
@@ -0,0 +1,128 @@
+package rt
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns an equirectangular image that is dark except for a bright patch straight ahead.
+func newTestEnvironmentImage() *Canvas {
+	c := NewCanvas(16, 8)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			c.WritePixel(x, y, NewColor(.1, .1, .1))
+		}
+	}
+
+	c.WritePixel(8, 4, NewColor(50, 40, 30))
+	return c
+}
+
+func TestSolidEnvironment_Radiance(t *testing.T) {
+	e := NewSolidEnvironment(NewColor(.2, .3, .4))
+	assert.Equal(t, NewColor(.2, .3, .4), e.Radiance(NewVector(0, 1, 0)))
+	assert.Equal(t, NewColor(.2, .3, .4), e.Radiance(NewVector(1, -1, 0)))
+}
+
+func TestSkyEnvironment_Radiance(t *testing.T) {
+	e := NewSkyEnvironment()
+	assert.True(t, e.Zenith.Equals(e.Radiance(NewVector(0, 2, 0))))
+	assert.True(t, e.Horizon.Equals(e.Radiance(NewVector(1, 0, 0))))
+	assert.True(t, mixColors(e.Horizon, e.Zenith, .6).Equals(e.Radiance(NewVector(.8, .6, 0))))
+	assert.True(t, e.Ground.Equals(e.Radiance(NewVector(0, -1, 1))))
+}
+
+func TestEquirectangularMap(t *testing.T) {
+	tests := []struct {
+		direction Tuple
+		u, polar  float64
+	}{
+		{NewVector(0, 0, 1), .5, math.Pi / 2},
+		{NewVector(1, 0, 0), .75, math.Pi / 2},
+		{NewVector(-1, 0, 0), .25, math.Pi / 2},
+		{NewVector(0, 0, -1), 1, math.Pi / 2},
+		{NewVector(0, 3, 0), .5, 0},
+		{NewVector(0, -1, 0), .5, math.Pi},
+		{NewVector(0, 1, 1), .5, math.Pi / 4},
+	}
+
+	for _, test := range tests {
+		u, polar := equirectangularMap(test.direction)
+		assert.InDelta(t, test.u, u, EPSILON, test.direction.String())
+		assert.InDelta(t, test.polar, polar, EPSILON, test.direction.String())
+		assert.True(t, test.direction.Normalize().Equals(equirectangularDirection(u, polar)), test.direction.String())
+	}
+}
+
+func TestImageEnvironment_Radiance(t *testing.T) {
+	c := NewCanvas(4, 2)
+	c.WritePixel(2, 0, NewColor(1, 0, 0))
+	c.WritePixel(3, 0, NewColor(0, 1, 0))
+	c.WritePixel(0, 1, NewColor(0, 0, 1))
+	e := NewImageEnvironment(c)
+
+	// looking forward and up sees the center of the image's top row, which blends the pixels
+	// on either side
+	assert.True(t, NewColor(.5, 0, 0).Equals(e.Radiance(NewVector(0, 1, 1))))
+
+	// further to the right is the next pixel's center
+	assert.True(t, NewColor(0, 1, 0).Equals(e.Radiance(equirectangularDirection(.875, math.Pi/4))))
+
+	// the image wraps around horizontally, and its edges extend to the poles
+	assert.True(t, NewColor(0, .25, .25).Equals(e.Radiance(NewVector(0, 0, -1))))
+	assert.True(t, NewColor(.5, 0, 0).Equals(e.Radiance(NewVector(0, 1, 0))))
+
+	// the environment can be turned and brightened
+	e.Transform = NewRotationY(math.Pi / 2)
+	e.Intensity = 2
+	assert.True(t, NewColor(1, 0, 0).Equals(e.Radiance(NewVector(1, 1, 0))))
+}
+
+func TestImageEnvironment_SampleDirection(t *testing.T) {
+	e := NewImageEnvironment(newTestEnvironmentImage())
+	e.Transform = NewRotationX(.3)
+	rng := rand.New(rand.NewSource(1))
+	const samples = 20000
+	bright, inverseSum := 0, 0.0
+	for i := 0; i < samples; i++ {
+		direction, pdf := e.SampleDirection(rng)
+		assert.True(t, eq(1, direction.Magnitude()))
+		assert.InDelta(t, pdf, e.PDF(direction), pdf*1e-6)
+		if e.Radiance(direction).Red() > 1 {
+			bright++
+		}
+
+		inverseSum += 1 / pdf
+	}
+
+	// most samples come from the bright patch, but the densities still cover the whole sphere
+	assert.Greater(t, bright, samples*3/4)
+	assert.InDelta(t, 4*math.Pi, inverseSum/samples, .4)
+
+	// black images can't be sampled
+	e = NewImageEnvironment(NewCanvas(4, 2))
+	_, pdf := e.SampleDirection(rng)
+	assert.Equal(t, 0.0, pdf)
+	assert.Equal(t, 0.0, e.PDF(NewVector(0, 1, 0)))
+}
+
+func TestSampleCDF(t *testing.T) {
+	cdf := []float64{0, .25, .25, 1}
+	assert.Equal(t, 0, sampleCDF(cdf, 0))
+	assert.Equal(t, 0, sampleCDF(cdf, .2))
+	assert.Equal(t, 2, sampleCDF(cdf, .25))
+	assert.Equal(t, 2, sampleCDF(cdf, .99))
+
+	// rounding can leave the last value short of 1
+	assert.Equal(t, 0, sampleCDF([]float64{0, .999, .999}, .9995))
+}
+
+func TestPowerHeuristic(t *testing.T) {
+	assert.Equal(t, .5, powerHeuristic(2, 2))
+	assert.Equal(t, .8, powerHeuristic(2, 1))
+	assert.Equal(t, 1.0, powerHeuristic(2, 0))
+	assert.Equal(t, 0.0, powerHeuristic(0, 2))
+}
@@ -0,0 +1,190 @@
+package rt
+
+import (
+	"flag"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run "go test -run TestGolden -update" to regenerate the reference images after a change that's
+// meant to alter the renders.
+var updateGolden = flag.Bool("update", false, "regenerate the reference images in testdata/golden")
+
+const (
+	// goldenTolerance is how far a pixel's channels may stray from the reference before it counts
+	// as different.
+	goldenTolerance = 2.0 / 255
+
+	// goldenMaxDifferent is the fraction of pixels that may differ from the reference.
+	goldenMaxDifferent = .005
+
+	// goldenMinSSIM is the least structural similarity a render may have with the reference.
+	goldenMinSSIM = .98
+)
+
+// TestGolden renders each scene in testdata/golden and compares it to the PNG of the same name.
+// When a render doesn't match, a heatmap of the differences is saved next to the reference as
+// name.diff.png, along with the render as name.actual.png.
+func TestGolden(t *testing.T) {
+	scenes, err := filepath.Glob(filepath.Join("testdata", "golden", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, scenes)
+
+	for _, filename := range scenes {
+		filename := filename
+		name := strings.TrimSuffix(filename, filepath.Ext(filename))
+		t.Run(filepath.Base(name), func(t *testing.T) {
+			scene, err := LoadScene(filename)
+			require.NoError(t, err)
+
+			// compare the image as it's saved, not the colors it was rendered with
+			actual := NewCanvasFromImage(scene.Camera.Render(scene.World).ToImage())
+			if *updateGolden {
+				require.NoError(t, actual.Save(name+".png"))
+				return
+			}
+
+			expected, err := LoadCanvas(name + ".png")
+			require.NoError(t, err, "run go test -run TestGolden -update to create the reference image")
+			require.Equal(t, expected.Width(), actual.Width())
+			require.Equal(t, expected.Height(), actual.Height())
+
+			different := float64(differentPixels(expected, actual, goldenTolerance)) / float64(actual.Width()*actual.Height())
+			ssim := structuralSimilarity(expected, actual)
+			if different <= goldenMaxDifferent && ssim >= goldenMinSSIM {
+				os.Remove(name + ".diff.png")
+				os.Remove(name + ".actual.png")
+				return
+			}
+
+			require.NoError(t, diffHeatmap(expected, actual).Save(name+".diff.png"))
+			require.NoError(t, actual.Save(name+".actual.png"))
+			t.Errorf("%.2f%% of pixels differ and SSIM is %.4f; see %s.diff.png and %s.actual.png",
+				different*100, ssim, name, name)
+		})
+	}
+}
+
+// Returns the number of pixels whose channels differ by more than a tolerance.
+func differentPixels(a *Canvas, b *Canvas, tolerance float64) int {
+	different := 0
+	for y := 0; y < a.Height(); y++ {
+		for x := 0; x < a.Width(); x++ {
+			if pixelDifference(a.PixelAt(x, y), b.PixelAt(x, y)) > tolerance {
+				different++
+			}
+		}
+	}
+
+	return different
+}
+
+// Returns the largest difference between the channels of two colors.
+func pixelDifference(a Color, b Color) float64 {
+	return math.Max(math.Abs(a.Red()-b.Red()), math.Max(math.Abs(a.Green()-b.Green()), math.Abs(a.Blue()-b.Blue())))
+}
+
+// Returns the mean structural similarity (SSIM) of the luminance of two images of the same size,
+// over 8x8 windows. Identical images have an SSIM of 1.
+func structuralSimilarity(a *Canvas, b *Canvas) float64 {
+	const window = 8
+	const c1, c2 = .01 * .01, .03 * .03
+	bounds := image.Rect(0, 0, a.Width(), a.Height())
+	total, windows := 0.0, 0
+	for y0 := 0; y0 < a.Height(); y0 += window {
+		for x0 := 0; x0 < a.Width(); x0 += window {
+			r := image.Rect(x0, y0, x0+window, y0+window).Intersect(bounds)
+			var sumA, sumB, sumAA, sumBB, sumAB, n float64
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				for x := r.Min.X; x < r.Max.X; x++ {
+					la, lb := a.PixelAt(x, y).Luminance(), b.PixelAt(x, y).Luminance()
+					sumA += la
+					sumB += lb
+					sumAA += la * la
+					sumBB += lb * lb
+					sumAB += la * lb
+					n++
+				}
+			}
+
+			meanA, meanB := sumA/n, sumB/n
+			varA, varB := sumAA/n-meanA*meanA, sumBB/n-meanB*meanB
+			covariance := sumAB/n - meanA*meanB
+			total += (2*meanA*meanB + c1) * (2*covariance + c2) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+
+	return total / float64(windows)
+}
+
+// Returns a heatmap of the differences between two images of the same size, running from black
+// where they match through red and yellow to white where a channel differs by a quarter or more.
+func diffHeatmap(a *Canvas, b *Canvas) *Canvas {
+	heatmap := NewCanvas(a.Width(), a.Height())
+	for y := 0; y < a.Height(); y++ {
+		for x := 0; x < a.Width(); x++ {
+			heat := math.Min(pixelDifference(a.PixelAt(x, y), b.PixelAt(x, y))*4, 1) * 3
+			heatmap.WritePixel(x, y, NewColor(clamp(heat, 0, 1), clamp(heat-1, 0, 1), clamp(heat-2, 0, 1)))
+		}
+	}
+
+	return heatmap
+}
+
+func TestStructuralSimilarity(t *testing.T) {
+	a := NewCanvas(16, 16)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			a.WritePixel(x, y, NewColor(float64(x)/16, float64(y)/16, .5))
+		}
+	}
+
+	assert.InDelta(t, 1, structuralSimilarity(a, a), 1e-9)
+
+	// a slight shift in brightness is hardly a change in structure
+	b := a.clone()
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			b.WritePixel(x, y, a.PixelAt(x, y).Add(NewColor(.01, .01, .01)))
+		}
+	}
+
+	assert.Greater(t, structuralSimilarity(a, b), .99)
+
+	// but losing the gradient is
+	flat := NewCanvas(16, 16)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			flat.WritePixel(x, y, NewColor(.5, .5, .5))
+		}
+	}
+
+	assert.Less(t, structuralSimilarity(a, flat), .5)
+}
+
+func TestDiffHeatmap(t *testing.T) {
+	a := NewCanvas(3, 1)
+	b := NewCanvas(3, 1)
+	for x := 0; x < 3; x++ {
+		a.WritePixel(x, 0, NewColor(0, 0, 0))
+	}
+
+	b.WritePixel(0, 0, NewColor(0, 0, 0))
+	b.WritePixel(1, 0, NewColor(0, 1.0/12, 0))
+	b.WritePixel(2, 0, NewColor(0, 0, 1))
+
+	heatmap := diffHeatmap(a, b)
+	assert.True(t, NewColor(0, 0, 0).Equals(heatmap.PixelAt(0, 0)))
+	assert.True(t, NewColor(1, 0, 0).Equals(heatmap.PixelAt(1, 0)))
+	assert.True(t, NewColor(1, 1, 1).Equals(heatmap.PixelAt(2, 0)))
+	assert.Equal(t, 2, differentPixels(a, b, goldenTolerance))
+}
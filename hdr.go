@@ -0,0 +1,155 @@
+package rt
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ParseHDR decodes a Radiance RGBE (.hdr) image, whose colors aren't limited to [0, 1].
+// Scanlines may be stored flat or run-length encoded.
+func ParseHDR(data []byte) (*Canvas, error) {
+	if !bytes.HasPrefix(data, []byte("#?")) {
+		return nil, fmt.Errorf("not a Radiance HDR image")
+	}
+
+	// the header is a list of variables ending with a blank line, followed by the resolution
+	pos := 0
+	line := func() (string, bool) {
+		end := bytes.IndexByte(data[pos:], '\n')
+		if end < 0 {
+			return "", false
+		}
+
+		l := string(data[pos : pos+end])
+		pos += end + 1
+		return strings.TrimSpace(l), true
+	}
+
+	line()
+	for {
+		l, ok := line()
+		if !ok {
+			return nil, fmt.Errorf("invalid HDR header")
+		}
+
+		if l == "" {
+			break
+		}
+
+		if strings.HasPrefix(l, "FORMAT=") && l != "FORMAT=32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported HDR pixel format %q", strings.TrimPrefix(l, "FORMAT="))
+		}
+	}
+
+	resolution, ok := line()
+	if !ok {
+		return nil, fmt.Errorf("invalid HDR header")
+	}
+
+	var width, height int
+	if _, err := fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); err != nil {
+		return nil, fmt.Errorf("unsupported HDR image orientation %q", resolution)
+	}
+
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid HDR header")
+	}
+
+	r := &hdrReader{data: data, pos: pos}
+	canvas := NewCanvas(width, height)
+	scanline := make([]byte, 4*width)
+	for y := 0; y < height; y++ {
+		if err := r.scanline(scanline, width); err != nil {
+			return nil, err
+		}
+
+		for x := 0; x < width; x++ {
+			canvas.pixels[y][x] = rgbeColor(scanline[4*x : 4*x+4])
+		}
+	}
+
+	return canvas, nil
+}
+
+type hdrReader struct {
+	data []byte
+	pos  int
+}
+
+// Reads a scanline of RGBE pixels into line, which holds width pixels.
+func (r *hdrReader) scanline(line []byte, width int) error {
+	if len(r.data)-r.pos < 4 {
+		return fmt.Errorf("HDR image is missing pixel data")
+	}
+
+	// run-length encoded scanlines start with two 2s and the width, and store each
+	// component of the scanline's pixels in turn
+	header := r.data[r.pos : r.pos+4]
+	if width < 8 || width > 0x7fff || header[0] != 2 || header[1] != 2 || header[2]&0x80 != 0 {
+		if len(r.data)-r.pos < len(line) {
+			return fmt.Errorf("HDR image is missing pixel data")
+		}
+
+		r.pos += copy(line, r.data[r.pos:])
+		return nil
+	}
+
+	if int(header[2])<<8|int(header[3]) != width {
+		return fmt.Errorf("invalid HDR scanline width")
+	}
+
+	r.pos += 4
+	for c := 0; c < 4; c++ {
+		for x := 0; x < width; {
+			if r.pos >= len(r.data) {
+				return fmt.Errorf("HDR image is missing pixel data")
+			}
+
+			// counts above 128 repeat the next byte; others are followed by that many bytes
+			count := int(r.data[r.pos])
+			r.pos++
+			repeat := count > 128
+			if repeat {
+				count -= 128
+			}
+
+			if count == 0 || x+count > width {
+				return fmt.Errorf("invalid HDR run length")
+			}
+
+			n := count
+			if repeat {
+				n = 1
+			}
+
+			if len(r.data)-r.pos < n {
+				return fmt.Errorf("HDR image is missing pixel data")
+			}
+
+			for i := 0; i < count; i++ {
+				if repeat {
+					line[4*(x+i)+c] = r.data[r.pos]
+				} else {
+					line[4*(x+i)+c] = r.data[r.pos+i]
+				}
+			}
+
+			r.pos += n
+			x += count
+		}
+	}
+
+	return nil
+}
+
+// Returns the color of an RGBE pixel, whose red, green, and blue mantissas share an exponent.
+func rgbeColor(rgbe []byte) Color {
+	if rgbe[3] == 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	scale := math.Ldexp(1, int(rgbe[3])-(128+8))
+	return NewColor(float64(rgbe[0])*scale, float64(rgbe[1])*scale, float64(rgbe[2])*scale)
+}
@@ -0,0 +1,144 @@
+package rt
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Encodes a canvas as a Radiance HDR image, run-length encoding its scanlines if rle is true.
+func encodeHDR(c *Canvas, rle bool) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "#?RADIANCE\n# a comment\nFORMAT=32-bit_rle_rgbe\n\n-Y %d +X %d\n", c.Height(), c.Width())
+	for y := 0; y < c.Height(); y++ {
+		line := make([]byte, 4*c.Width())
+		for x := 0; x < c.Width(); x++ {
+			color := c.PixelAt(x, y)
+			if color == nil {
+				continue
+			}
+
+			largest := math.Max(color.Red(), math.Max(color.Green(), color.Blue()))
+			if largest < 1e-32 {
+				continue
+			}
+
+			mantissa, exponent := math.Frexp(largest)
+			scale := mantissa * 256 / largest
+			copy(line[4*x:], []byte{byte(color.Red() * scale), byte(color.Green() * scale), byte(color.Blue() * scale), byte(exponent + 128)})
+		}
+
+		if !rle {
+			b.Write(line)
+			continue
+		}
+
+		// store each component as a run if it's the same across the scanline, and otherwise as
+		// literal bytes
+		b.Write([]byte{2, 2, byte(c.Width() >> 8), byte(c.Width())})
+		for i := 0; i < 4; i++ {
+			component := make([]byte, c.Width())
+			same := true
+			for x := range component {
+				component[x] = line[4*x+i]
+				same = same && component[x] == component[0]
+			}
+
+			for x := 0; x < len(component); x += 127 {
+				end := x + 127
+				if end > len(component) {
+					end = len(component)
+				}
+
+				if same {
+					b.Write([]byte{byte(128 + end - x), component[x]})
+				} else {
+					b.WriteByte(byte(end - x))
+					b.Write(component[x:end])
+				}
+			}
+		}
+	}
+
+	return b.Bytes()
+}
+
+func TestParseHDR(t *testing.T) {
+	c := NewCanvas(10, 3)
+	c.WritePixel(0, 0, NewColor(1, 0, 0))
+	c.WritePixel(9, 1, NewColor(12, 3, .75))
+	c.WritePixel(4, 2, NewColor(.25, .5, .125))
+	for _, rle := range []bool{false, true} {
+		parsed, err := ParseHDR(encodeHDR(c, rle))
+		require.NoError(t, err, "rle %v", rle)
+		assert.Equal(t, 10, parsed.Width())
+		assert.Equal(t, 3, parsed.Height())
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 10; x++ {
+				expected := c.PixelAt(x, y)
+				if expected == nil {
+					expected = black
+				}
+
+				assert.True(t, expected.Equals(parsed.PixelAt(x, y)), "rle %v, %d, %d", rle, x, y)
+			}
+		}
+	}
+
+	// narrow images are never run-length encoded
+	narrow := NewCanvas(2, 1)
+	narrow.WritePixel(1, 0, NewColor(2, 4, 8))
+	parsed, err := ParseHDR(encodeHDR(narrow, false))
+	require.NoError(t, err)
+	assert.True(t, NewColor(2, 4, 8).Equals(parsed.PixelAt(1, 0)))
+}
+
+func TestParseHDR_errors(t *testing.T) {
+	valid := string(encodeHDR(NewCanvas(10, 1), true))
+	header := "#?RADIANCE\n\n-Y 1 +X 10\n"
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"P3\n1 1\n255\n", "not a Radiance HDR image"},
+		{"#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n", "invalid HDR header"},
+		{"#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n", `unsupported HDR pixel format "32-bit_rle_xyze"`},
+		{"#?RADIANCE\n\n+Y 1 +X 1\n", `unsupported HDR image orientation "+Y 1 +X 1"`},
+		{"#?RADIANCE\n\n-Y 0 +X 1\n", "invalid HDR header"},
+		{valid[:len(valid)-1], "HDR image is missing pixel data"},
+		{header + "\x02\x02\x00\x09", "invalid HDR scanline width"},
+		{header + "\x02\x02\x00\x0a\x8b\x00", "invalid HDR run length"},
+		{header + "\x02\x02\x00\x0a\x00", "invalid HDR run length"},
+		{"#?RADIANCE\n\n-Y 1 +X 2\n\x01\x02\x03", "HDR image is missing pixel data"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseHDR([]byte(test.data))
+		assert.EqualError(t, err, test.expected, "%q", test.data)
+	}
+}
+
+func TestLoadCanvas_hdr(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hdr")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewCanvas(2, 1)
+	c.WritePixel(0, 0, NewColor(16, 8, 4))
+	filename := filepath.Join(dir, "sky.hdr")
+	require.NoError(t, ioutil.WriteFile(filename, encodeHDR(c, false), 0644))
+	loaded, err := LoadCanvas(filename)
+	require.NoError(t, err)
+	assert.True(t, NewColor(16, 8, 4).Equals(loaded.PixelAt(0, 0)))
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("#?RADIANCE\n"), 0644))
+	_, err = LoadCanvas(filename)
+	assert.EqualError(t, err, filename+": invalid HDR header")
+}
@@ -0,0 +1,145 @@
+package rt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// RenderHeatmap renders a debug image that shows what each pixel of the image costs to render,
+// measured by a metric: "intersection-tests" counts the tests of rays against shapes, and "time"
+// the nanoseconds spent. The costs are rendered as passes alongside the image (see PassNames),
+// and drawn with Heatmap.
+func (c *Camera) RenderHeatmap(ctx context.Context, world *World, metric string, progress func(RenderProgress)) (*Canvas, error) {
+	if metric != "intersection-tests" && metric != "time" {
+		return nil, fmt.Errorf("unknown heatmap metric '%s'", metric)
+	}
+
+	_, passes, err := c.RenderPasses(ctx, world, []string{metric}, progress)
+	if passes == nil {
+		return nil, err
+	}
+
+	return Heatmap(passes[metric]), err
+}
+
+// Records the cost of rendering a pixel in the passes that measure it.
+func (p *renderPasses) writeCost(x int, y int, tests int64, nanoseconds int64) {
+	p.write("intersection-tests", x, y, NewColor(float64(tests), float64(tests), float64(tests)))
+	p.write("time", x, y, NewColor(float64(nanoseconds), float64(nanoseconds), float64(nanoseconds)))
+}
+
+// The stops of the false-color ramp heatmaps are drawn with, from the lowest cost to the highest.
+var heatmapRamp = []Color{
+	NewColor(0, 0, 0),
+	NewColor(.1, 0, .5),
+	NewColor(.7, 0, .6),
+	NewColor(1, .3, 0),
+	NewColor(1, .9, 0),
+	NewColor(1, 1, 1),
+}
+
+// Heatmap returns a false-color image of a pass of costs, such as intersection-tests or time,
+// running from black for no cost through purple, red, and yellow to white for the highest. A
+// legend is added below the image: the ramp, labeled with the costs at its ends and middle.
+func Heatmap(pass *Canvas) *Canvas {
+	maxCost := 0.0
+	for y := 0; y < pass.Height(); y++ {
+		for x := 0; x < pass.Width(); x++ {
+			if color := pass.PixelAt(x, y); color != nil {
+				maxCost = math.Max(maxCost, color.Red())
+			}
+		}
+	}
+
+	// the legend is sized to stay legible as the image grows
+	scale := pass.Width()/200 + 1
+	legendHeight := 19 * scale
+	heatmap := NewCanvas(pass.Width(), pass.Height()+legendHeight)
+	black := NewColor(0, 0, 0)
+	for y := 0; y < heatmap.Height(); y++ {
+		for x := 0; x < heatmap.Width(); x++ {
+			heatmap.WritePixel(x, y, black)
+		}
+	}
+
+	for y := 0; y < pass.Height(); y++ {
+		for x := 0; x < pass.Width(); x++ {
+			if color := pass.PixelAt(x, y); color != nil && maxCost > 0 {
+				heatmap.WritePixel(x, y, heatColor(color.Red()/maxCost))
+			}
+		}
+	}
+
+	// the ramp, with a margin on each side
+	margin := 2 * scale
+	top := pass.Height() + margin
+	span := math.Max(float64(pass.Width()-2*margin-1), 1)
+	for x := margin; x < pass.Width()-margin; x++ {
+		color := heatColor(float64(x-margin) / span)
+		for y := top; y < top+8*scale; y++ {
+			heatmap.WritePixel(x, y, color)
+		}
+	}
+
+	// and its labels, with the middle one only if there's room for it
+	labelY := top + 10*scale
+	low, middle, high := "0", strconv.FormatInt(int64(maxCost/2), 10), strconv.FormatInt(int64(maxCost), 10)
+	drawLabel(heatmap, low, margin, labelY, scale)
+	highX := pass.Width() - margin - labelWidth(high, scale)
+	drawLabel(heatmap, high, highX, labelY, scale)
+	middleX := (pass.Width() - labelWidth(middle, scale)) / 2
+	if middleX > margin+labelWidth(low, scale)+2*scale && middleX+labelWidth(middle, scale)+2*scale < highX {
+		drawLabel(heatmap, middle, middleX, labelY, scale)
+	}
+
+	return heatmap
+}
+
+// Returns the color of the heatmap ramp at a fraction of the way along it.
+func heatColor(fraction float64) Color {
+	f := clamp(fraction, 0, 1) * float64(len(heatmapRamp)-1)
+	i := int(f)
+	if i == len(heatmapRamp)-1 {
+		return heatmapRamp[i]
+	}
+
+	return heatmapRamp[i].Multiply(1 - (f - float64(i))).Add(heatmapRamp[i+1].Multiply(f - float64(i)))
+}
+
+// The digits of the legend's labels, 3 pixels wide by 5 tall, with each row's pixels as bits
+// from left to right.
+var digitGlyphs = [10][5]uint8{
+	{7, 5, 5, 5, 7}, // 0
+	{2, 6, 2, 2, 7}, // 1
+	{7, 1, 7, 4, 7}, // 2
+	{7, 1, 7, 1, 7}, // 3
+	{5, 5, 7, 1, 1}, // 4
+	{7, 4, 7, 1, 7}, // 5
+	{7, 4, 7, 5, 7}, // 6
+	{7, 1, 2, 2, 2}, // 7
+	{7, 5, 7, 5, 7}, // 8
+	{7, 5, 7, 1, 7}, // 9
+}
+
+// Returns the width in pixels of a label of digits drawn at a scale.
+func labelWidth(label string, scale int) int {
+	return (4*len(label) - 1) * scale
+}
+
+// Draws a label of digits in white, with its top left corner at x, y, clipped to the canvas.
+func drawLabel(canvas *Canvas, label string, x int, y int, scale int) {
+	white := NewColor(1, 1, 1)
+	for i, digit := range label {
+		glyph := digitGlyphs[digit-'0']
+		for row := 0; row < 5*scale; row++ {
+			for column := 0; column < 3*scale; column++ {
+				px, py := x+(4*i)*scale+column, y+row
+				if glyph[row/scale]&(4>>uint(column/scale)) != 0 && px >= 0 && px < canvas.Width() && py < canvas.Height() {
+					canvas.WritePixel(px, py, white)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package rt
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeatmap(t *testing.T) {
+	pass := NewCanvas(40, 10)
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 40; x++ {
+			cost := float64(x * 10)
+			pass.WritePixel(x, y, NewColor(cost, cost, cost))
+		}
+	}
+
+	heatmap := Heatmap(pass)
+	assert.Equal(t, 40, heatmap.Width())
+	assert.Equal(t, 10+19, heatmap.Height())
+
+	// costs run from black to white
+	assert.Equal(t, NewColor(0, 0, 0), heatmap.PixelAt(0, 5))
+	assert.Equal(t, NewColor(1, 1, 1), heatmap.PixelAt(39, 5))
+	assert.True(t, heatColor(200.0/390).Equals(heatmap.PixelAt(20, 5)))
+
+	// the legend below shows the ramp, starting and ending at the margins
+	assert.Equal(t, NewColor(0, 0, 0), heatmap.PixelAt(2, 15))
+	assert.Equal(t, NewColor(1, 1, 1), heatmap.PixelAt(37, 15))
+	assert.Equal(t, NewColor(0, 0, 0), heatmap.PixelAt(38, 15))
+
+	// labeled with the lowest and highest costs: the 0 at the left, and the 390 at the right,
+	// whose 0 ends at the margin
+	label := 10 + 12
+	assert.Equal(t, NewColor(1, 1, 1), heatmap.PixelAt(2, label))
+	assert.Equal(t, NewColor(0, 0, 0), heatmap.PixelAt(3, label+1))
+	assert.Equal(t, NewColor(1, 1, 1), heatmap.PixelAt(37, label))
+	assert.Equal(t, NewColor(0, 0, 0), heatmap.PixelAt(36, label+1))
+	assert.Equal(t, NewColor(0, 0, 0), heatmap.PixelAt(38, label))
+}
+
+func TestHeatColor(t *testing.T) {
+	assert.Equal(t, NewColor(0, 0, 0), heatColor(-1))
+	assert.Equal(t, NewColor(1, 1, 1), heatColor(2))
+	assert.True(t, NewColor(.05, 0, .25).Equals(heatColor(.1)))
+}
+
+func TestCamera_RenderHeatmap(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+
+	heatmap, err := c.RenderHeatmap(context.Background(), w, "intersection-tests", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 11+19, heatmap.Height())
+
+	// pixels that hit the sphere also trace a shadow ray, so they're hotter than the background
+	_, passes, err := c.RenderPasses(context.Background(), w, []string{"intersection-tests"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, NewColor(4, 4, 4), passes["intersection-tests"].PixelAt(5, 5))
+	assert.Equal(t, NewColor(2, 2, 2), passes["intersection-tests"].PixelAt(0, 0))
+	assert.True(t, heatmap.PixelAt(5, 5).Equals(NewColor(1, 1, 1)))
+	assert.True(t, heatmap.PixelAt(0, 0).Equals(heatColor(.5)))
+
+	_, err = c.RenderHeatmap(context.Background(), w, "memory", nil)
+	assert.EqualError(t, err, "unknown heatmap metric 'memory'")
+}
@@ -0,0 +1,262 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// An Integrator computes the light arriving at the camera along a ray.
+type Integrator interface {
+	// Radiance returns the light arriving along a ray. Integrators that take random samples
+	// draw them from rng.
+	Radiance(world *World, ray *Ray, rng *rand.Rand) Color
+}
+
+// A SplitIntegrator can separate the light arriving along a ray into direct and indirect light.
+type SplitIntegrator interface {
+	Integrator
+
+	// RadianceSplit returns the light arriving along a ray in two parts that sum to what Radiance
+	// returns: the light the first surface hit emits or reflects straight from light sources,
+	// including the light seen where the ray escapes, and the light reflected after bouncing.
+	RadianceSplit(world *World, ray *Ray, rng *rand.Rand) (direct Color, indirect Color)
+}
+
+// The WhittedIntegrator is the classic ray tracer, which lights each surface directly from
+// the world's light and approximates indirect light with each material's ambient term.
+type WhittedIntegrator struct{}
+
+// NewWhittedIntegrator creates a new WhittedIntegrator.
+func NewWhittedIntegrator() *WhittedIntegrator {
+	return &WhittedIntegrator{}
+}
+
+// Radiance returns the light arriving along a ray.
+func (i *WhittedIntegrator) Radiance(world *World, ray *Ray, rng *rand.Rand) Color {
+	return world.ColorAt(ray)
+}
+
+// RadianceSplit returns the light arriving along a ray, split into the light from the world's
+// light and emission, and the ambient light that stands in for indirect light.
+func (i *WhittedIntegrator) RadianceSplit(world *World, ray *Ray, rng *rand.Rand) (Color, Color) {
+	hit := world.Intersect(ray).Hit()
+	if hit == nil {
+		return world.background(ray), NewColor(0, 0, 0)
+	}
+
+	info := hit.PrepareComputations(ray)
+	color := world.ShadeHit(info)
+	if world.Light == nil {
+		return color, NewColor(0, 0, 0)
+	}
+
+	// in shadow, only the ambient light and emission remain
+	material := info.Object.GetMaterial()
+	ambient := material.Lighting(info.Object, world.Light, info.Point, info.EyeV, info.NormalV, true)
+	ambient = ambient.Subtract(material.EmissionAt(info.Object, info.Point))
+	return color.Subtract(ambient), ambient
+}
+
+// A PathTracer is a Monte Carlo integrator for global illumination. It follows each camera ray
+// as it bounces from surface to surface, choosing each bounce by cosine-weighted sampling of the
+// hemisphere and lighting every surface it hits directly from the world's light, a random
+// point on an emissive object, and a direction chosen from the environment when it can be
+// sampled (next-event estimation). Materials' ambient terms are ignored, since indirect light
+// replaces them.
+//
+// A light's intensity is treated as the brightness of a white, matte surface facing it, as with
+// the Phong model, so scenes look alike with either integrator apart from indirect light.
+type PathTracer struct {
+	// MaxDepth is the largest number of bounces a path may take. Zero gives direct lighting only.
+	MaxDepth int
+
+	// RouletteDepth is the number of bounces after which paths are terminated at random, with a
+	// probability that increases as less light can be carried along the path (Russian roulette).
+	RouletteDepth int
+}
+
+// NewPathTracer creates a new PathTracer.
+func NewPathTracer() *PathTracer {
+	return &PathTracer{MaxDepth: 8, RouletteDepth: 3}
+}
+
+// Radiance returns an estimate of the light arriving along a ray.
+func (pt *PathTracer) Radiance(world *World, ray *Ray, rng *rand.Rand) Color {
+	direct, indirect := pt.RadianceSplit(world, ray, rng)
+	return direct.Add(indirect)
+}
+
+// RadianceSplit returns an estimate of the light arriving along a ray, split into the light
+// gathered where the ray first hits and the light gathered by the bounces after it.
+func (pt *PathTracer) RadianceSplit(world *World, ray *Ray, rng *rand.Rand) (Color, Color) {
+	direct, indirect := NewColor(0, 0, 0), NewColor(0, 0, 0)
+	throughput := NewColor(1, 1, 1)
+	emitters := world.emitters()
+	bouncePDF := 0.0
+	for depth := 0; ; depth++ {
+		radiance := &indirect
+		if depth == 0 {
+			radiance = &direct
+		}
+
+		hit := world.Intersect(ray).Hit()
+		if hit == nil {
+			*radiance = radiance.Add(throughput.HadamardBlend(pt.escapedLight(world, ray, depth, bouncePDF)))
+			break
+		}
+
+		// light from objects that can be sampled was already counted by the previous bounce's
+		// next-event estimation
+		info := hit.PrepareComputations(ray)
+		material := info.Object.GetMaterial()
+		if _, sampled := unfrozenShape(info.Object).(SurfaceSampler); depth == 0 || !sampled {
+			*radiance = radiance.Add(throughput.HadamardBlend(material.EmissionAt(info.Object, info.Point)))
+		}
+
+		light := pt.directLight(world, info).Add(pt.emittedLight(world, info, emitters, rng))
+		light = light.Add(pt.environmentLight(world, info, depth < pt.MaxDepth, rng))
+		*radiance = radiance.Add(throughput.HadamardBlend(light))
+		if depth >= pt.MaxDepth {
+			break
+		}
+
+		// with cosine-weighted sampling, the BRDF times the cosine over the probability of the
+		// direction is just the BRDF times π
+		direction := cosineSampleHemisphere(info.NormalV, rng)
+		bouncePDF = info.NormalV.Dot(direction) / math.Pi
+		brdf := material.BRDF(info.Object, info.Point, info.EyeV, info.NormalV, direction)
+		throughput = throughput.HadamardBlend(brdf.Multiply(math.Pi))
+
+		survival := math.Max(throughput.Red(), math.Max(throughput.Green(), throughput.Blue()))
+		if survival <= 0 {
+			break
+		}
+
+		if depth >= pt.RouletteDepth {
+			survival = math.Min(survival, .95)
+			if rng.Float64() >= survival {
+				break
+			}
+
+			throughput = throughput.Multiply(1 / survival)
+		}
+
+		ray = info.rayFrom(direction)
+		info.stats.addReflectionRay()
+	}
+
+	return direct, indirect
+}
+
+// Returns the light reflected toward the eye from the world's light.
+func (pt *PathTracer) directLight(world *World, info *IntersectionInfo) Color {
+	if world.Light == nil {
+		return NewColor(0, 0, 0)
+	}
+
+	lightV := world.Light.Position.Subtract(info.Point).Normalize()
+	cosine := info.NormalV.Dot(lightV)
+	if cosine <= 0 || world.isOccluded(info.OverPoint, world.Light.Position, info.Time, info.stats) {
+		return NewColor(0, 0, 0)
+	}
+
+	brdf := info.Object.GetMaterial().BRDF(info.Object, info.Point, info.EyeV, info.NormalV, lightV)
+	return brdf.HadamardBlend(world.Light.Intensity).Multiply(math.Pi * cosine)
+}
+
+// Returns the light reflected toward the eye from a point chosen at random on one of the emitters.
+func (pt *PathTracer) emittedLight(world *World, info *IntersectionInfo, emitters []SurfaceSampler, rng *rand.Rand) Color {
+	if len(emitters) == 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	emitter := emitters[rng.Intn(len(emitters))]
+	point, normal, pdf := emitter.SampleSurface(info.Time, rng)
+	toLight := point.Subtract(info.OverPoint)
+	distance := toLight.Magnitude()
+	lightV := toLight.Multiply(1 / distance)
+
+	// emitters glow from both sides of their surfaces
+	cosine := info.NormalV.Dot(lightV)
+	lightCosine := math.Abs(normal.Dot(lightV))
+	if cosine <= 0 || lightCosine <= 0 || pdf <= 0 || world.isOccluded(info.OverPoint, point, info.Time, info.stats) {
+		return NewColor(0, 0, 0)
+	}
+
+	// convert the probability per unit area to a probability per unit solid angle, and account
+	// for choosing one emitter among many
+	emission := emitter.GetMaterial().EmissionAt(emitter, point)
+	brdf := info.Object.GetMaterial().BRDF(info.Object, info.Point, info.EyeV, info.NormalV, lightV)
+	weight := cosine * lightCosine * float64(len(emitters)) / (distance * distance * pdf)
+	return brdf.HadamardBlend(emission).Multiply(weight)
+}
+
+// Returns the light arriving from the environment along a ray that escapes the world. When the
+// environment is also sampled directly, the light is weighted by multiple importance sampling,
+// given the probability density with which the last bounce chose the ray's direction.
+func (pt *PathTracer) escapedLight(world *World, ray *Ray, depth int, bouncePDF float64) Color {
+	light := world.background(ray)
+	if sampler, ok := world.Environment.(EnvironmentSampler); ok && depth > 0 {
+		light = light.Multiply(powerHeuristic(bouncePDF, sampler.PDF(ray.Direction)))
+	}
+
+	return light
+}
+
+// Returns the light reflected toward the eye from a direction chosen from the environment, if
+// it can be sampled. The light is weighted by multiple importance sampling when the path goes on
+// to bounce, since a ray that escapes after the bounce counts the rest of it; otherwise it is
+// given full weight.
+func (pt *PathTracer) environmentLight(world *World, info *IntersectionInfo, bounces bool, rng *rand.Rand) Color {
+	sampler, ok := world.Environment.(EnvironmentSampler)
+	if !ok {
+		return NewColor(0, 0, 0)
+	}
+
+	lightV, pdf := sampler.SampleDirection(rng)
+	cosine := info.NormalV.Dot(lightV)
+	if pdf <= 0 || cosine <= 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	info.stats.addShadowRay()
+	if world.Intersect(info.rayFrom(lightV)).Hit() != nil {
+		return NewColor(0, 0, 0)
+	}
+
+	brdf := info.Object.GetMaterial().BRDF(info.Object, info.Point, info.EyeV, info.NormalV, lightV)
+	weight := cosine / pdf
+	if bounces {
+		weight *= powerHeuristic(pdf, cosine/math.Pi)
+	}
+
+	return brdf.HadamardBlend(sampler.Radiance(lightV)).Multiply(weight)
+}
+
+// IntegratorNames are the names of the integrators available to scene files and the rt command.
+var IntegratorNames = []string{"whitted", "path"}
+
+// NewIntegrator creates an integrator with default settings from its name.
+func NewIntegrator(name string) (Integrator, error) {
+	switch name {
+	case "whitted":
+		return NewWhittedIntegrator(), nil
+	case "path":
+		return NewPathTracer(), nil
+	}
+
+	return nil, fmt.Errorf("unknown integrator '%s'", name)
+}
+
+// Returns the name of an integrator.
+func integratorName(integrator Integrator) (string, error) {
+	switch integrator.(type) {
+	case *WhittedIntegrator:
+		return "whitted", nil
+	case *PathTracer:
+		return "path", nil
+	}
+
+	return "", fmt.Errorf("unsupported integrator type %T", integrator)
+}
@@ -0,0 +1,276 @@
+package rt
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a closed Cornell box with a red left wall, a green right wall, a sphere on the floor,
+// and a light near the ceiling, along with a camera looking into it.
+func newCornellBox(size int) (*World, *Camera) {
+	wall := func(color Color, transform Transformation) Shape {
+		plane := NewPlane()
+		plane.Material.Color = color
+		plane.Material.Ambient = 0
+		plane.Material.Specular = 0
+		plane.Material.Diffuse = .75
+		plane.SetTransform(transform)
+		return plane
+	}
+
+	world := NewWorld()
+	world.Light = NewPointLight(NewPoint(0, 1.9, 0), NewColor(1, 1, 1))
+	world.AddObjects(
+		wall(white, NewTransform()),
+		wall(white, NewTranslation(0, 2, 0)),
+		wall(NewColor(1, 0, 0), NewTranslation(-1, 0, 0).CombineWith(NewRotationZ(math.Pi/2))),
+		wall(NewColor(0, 1, 0), NewTranslation(1, 0, 0).CombineWith(NewRotationZ(math.Pi/2))),
+		wall(white, NewTranslation(0, 0, 1).CombineWith(NewRotationX(math.Pi/2))),
+		wall(white, NewTranslation(0, 0, -4).CombineWith(NewRotationX(math.Pi/2))),
+	)
+
+	sphere := NewSphere()
+	sphere.SetTransform(NewTranslation(0, .4, .2).CombineWith(NewScaling(.4, .4, .4)))
+	sphere.Material.Ambient = 0
+	sphere.Material.Specular = 0
+	world.AddObjects(sphere)
+
+	camera := NewCamera(size, size, math.Pi/2.5)
+	camera.Transform = NewViewTransform(NewPoint(0, 1, -2.5), NewPoint(0, 1, 0), NewVector(0, 1, 0))
+	camera.Integrator = NewPathTracer()
+	return world, camera
+}
+
+// Returns the mean squared difference between the pixels of two canvases.
+func meanSquaredError(a *Canvas, b *Canvas) float64 {
+	sum := 0.0
+	for y := 0; y < a.Height(); y++ {
+		for x := 0; x < a.Width(); x++ {
+			diff := a.PixelAt(x, y).Subtract(b.PixelAt(x, y))
+			sum += diff.Red()*diff.Red() + diff.Green()*diff.Green() + diff.Blue()*diff.Blue()
+		}
+	}
+
+	return sum / float64(a.Width()*a.Height())
+}
+
+func TestNewIntegrator(t *testing.T) {
+	for _, name := range IntegratorNames {
+		integrator, err := NewIntegrator(name)
+		require.NoError(t, err)
+		n, err := integratorName(integrator)
+		require.NoError(t, err)
+		assert.Equal(t, name, n)
+	}
+
+	_, err := NewIntegrator("photon-mapping")
+	assert.EqualError(t, err, "unknown integrator 'photon-mapping'")
+}
+
+func TestWhittedIntegrator_Radiance(t *testing.T) {
+	w := NewDefaultWorld()
+	r := NewRay(NewPoint(0, 0, -5), NewVector(0, 0, 1))
+	assert.Equal(t, w.ColorAt(r), NewWhittedIntegrator().Radiance(w, r, nil))
+
+	// cameras use the Whitted integrator by default
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	image := c.Render(w)
+	c.Integrator = NewWhittedIntegrator()
+	assert.Equal(t, image, c.Render(w))
+}
+
+func TestWhittedIntegrator_RadianceSplit(t *testing.T) {
+	w := NewDefaultWorld()
+	r := NewRay(NewPoint(0, 0, -5), NewVector(0, 0, 1))
+	direct, indirect := NewWhittedIntegrator().RadianceSplit(w, r, nil)
+
+	// ambient light stands in for the light arriving indirectly
+	assert.True(t, NewColor(.08, .1, .06).Equals(indirect), "%v", indirect)
+	assert.True(t, w.ColorAt(r).Equals(direct.Add(indirect)))
+}
+
+func TestPathTracer_Radiance_direct(t *testing.T) {
+	// with no bounces and no ambient light, path tracing a matte surface lights it just like Phong
+	w := NewDefaultWorld()
+	for _, object := range w.Objects {
+		object.GetMaterial().Ambient = 0
+		object.GetMaterial().Specular = 0
+	}
+
+	pt := NewPathTracer()
+	pt.MaxDepth = 0
+	rng := rand.New(rand.NewSource(1))
+	for _, direction := range []Tuple{NewVector(0, 0, 1), NewVector(-.1, .1, 1), NewVector(.1, -.05, 1)} {
+		r := NewRay(NewPoint(0, 0, -5), direction.Normalize())
+		assert.True(t, w.ColorAt(r).Equals(pt.Radiance(w, r, rng)), direction.String())
+	}
+
+	// rays that miss everything are black
+	r := NewRay(NewPoint(0, 0, -5), NewVector(0, 1, 0))
+	assert.Equal(t, NewColor(0, 0, 0), pt.Radiance(w, r, rng))
+}
+
+func TestPathTracer_Radiance_emission(t *testing.T) {
+	w := NewWorld()
+	s := NewSphere()
+	s.Material.PBR = NewPBRMaterial()
+	s.Material.PBR.Emission = NewColor(2, 1, 0)
+	w.AddObjects(s)
+
+	r := NewRay(NewPoint(0, 0, -5), NewVector(0, 0, 1))
+	result := NewPathTracer().Radiance(w, r, rand.New(rand.NewSource(1)))
+	assert.True(t, NewColor(2, 1, 0).Equals(result), "%v", result)
+}
+
+func TestPathTracer_cornellBox(t *testing.T) {
+	world, camera := newCornellBox(12)
+	camera.Samples = 12
+	reference := camera.Render(world)
+
+	// the render converges as samples are added
+	camera.Samples = 1
+	rough := camera.Render(world)
+	camera.Samples = 4
+	better := camera.Render(world)
+	assert.Less(t, meanSquaredError(better, reference), meanSquaredError(rough, reference)/4)
+
+	// light bounces off the colored walls onto the floor and the sphere
+	left, right := reference.PixelAt(2, 10), reference.PixelAt(9, 10)
+	assert.Greater(t, left.Red(), left.Green())
+	assert.Greater(t, right.Green(), right.Red())
+	sphere := reference.PixelAt(5, 5)
+	assert.Greater(t, sphere.Red(), 0.0)
+	assert.Greater(t, sphere.Green(), 0.0)
+
+	// surfaces in shadow are lit indirectly, unlike with the Whitted integrator
+	shadow := reference.PixelAt(5, 9)
+	assert.Greater(t, shadow.Luminance(), .01)
+	camera.Integrator = NewWhittedIntegrator()
+	camera.Samples = 1
+	assert.Equal(t, 0.0, camera.Render(world).PixelAt(5, 9).Luminance())
+}
+
+func TestPathTracer_Radiance_emitter(t *testing.T) {
+	// a white, matte floor lit only by a glowing sphere above it
+	floor := NewPlane()
+	floor.Material.Diffuse = 1
+	floor.Material.Specular = 0
+	bulb := NewSphere()
+	bulb.Transform = NewTranslation(0, 2, 0).CombineWith(NewScaling(.5, .5, .5))
+	bulb.Material = &Material{Color: black, Emission: NewColor(4, 2, 1)}
+	world := NewWorld()
+	world.AddObjects(floor, bulb)
+
+	// the floor beneath the sphere reflects the emission scaled by the solid angle the sphere
+	// covers, (r/d)² for a diffuse white surface
+	expected := NewColor(4, 2, 1).Multiply(.5 * .5 / (2 * 2))
+	ray := NewRay(NewPoint(0, 1, -1), NewVector(0, -1, 1).Normalize())
+	for _, depth := range []int{0, 3} {
+		// bounces that hit the sphere don't count its light a second time
+		pt := &PathTracer{MaxDepth: depth, RouletteDepth: 1}
+		rng := rand.New(rand.NewSource(1))
+		const samples = 4000
+		sum := NewColor(0, 0, 0)
+		for i := 0; i < samples; i++ {
+			sum = sum.Add(pt.Radiance(world, ray, rng))
+		}
+
+		mean := sum.Multiply(1.0 / samples)
+		assert.InDelta(t, expected.Red(), mean.Red(), .02, "depth %d", depth)
+		assert.InDelta(t, expected.Green(), mean.Green(), .01, "depth %d", depth)
+		assert.InDelta(t, expected.Blue(), mean.Blue(), .005, "depth %d", depth)
+	}
+
+	// the sphere itself appears with its emission
+	ray = NewRay(NewPoint(0, 2, -5), NewVector(0, 0, 1))
+	assert.True(t, NewColor(4, 2, 1).Equals(NewPathTracer().Radiance(world, ray, rand.New(rand.NewSource(1)))))
+}
+
+// An unsampledEnvironment hides whether an environment can be sampled.
+func TestPathTracer_Radiance_furnace(t *testing.T) {
+	// a matte sphere in a uniformly white environment reflects its albedo, however few
+	// bounces paths may take, since the light it reflects arrives straight from the environment
+	sphere := NewSphere()
+	sphere.Material.Diffuse = .5
+	sphere.Material.Specular = 0
+	world := NewWorld()
+	world.AddObjects(sphere)
+	ray := NewRay(NewPoint(0, 0, -5), NewVector(0, 0, 1))
+
+	uniform := NewCanvas(8, 4)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			uniform.WritePixel(x, y, white)
+		}
+	}
+
+	world.Environment = NewImageEnvironment(uniform)
+	for _, depth := range []int{0, 1, 8} {
+		pt := NewPathTracer()
+		pt.MaxDepth = depth
+		rng := rand.New(rand.NewSource(1))
+		sum := NewColor(0, 0, 0)
+		samples := 20000
+		for i := 0; i < samples; i++ {
+			sum = sum.Add(pt.Radiance(world, ray, rng))
+		}
+
+		assert.InDelta(t, .5, sum.Red()/float64(samples), .01, "max depth %d", depth)
+	}
+}
+
+type unsampledEnvironment struct {
+	Environment
+}
+
+func TestPathTracer_Radiance_environment(t *testing.T) {
+	// a matte sphere in a uniformly white environment reflects its albedo, with only the
+	// light that escapes after a single bounce
+	sphere := NewSphere()
+	sphere.Material.Diffuse = .5
+	sphere.Material.Specular = 0
+	world := NewWorld()
+	world.AddObjects(sphere)
+	ray := NewRay(NewPoint(0, 0, -5), NewVector(0, 0, 1))
+
+	uniform := NewCanvas(8, 4)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			uniform.WritePixel(x, y, white)
+		}
+	}
+
+	mean := func(environment Environment, samples int) Color {
+		world.Environment = environment
+		rng := rand.New(rand.NewSource(1))
+		sum := NewColor(0, 0, 0)
+		for i := 0; i < samples; i++ {
+			sum = sum.Add(NewPathTracer().Radiance(world, ray, rng))
+		}
+
+		return sum.Multiply(1 / float64(samples))
+	}
+
+	for _, environment := range []Environment{NewSolidEnvironment(white), NewImageEnvironment(uniform)} {
+		assert.InDelta(t, .5, mean(environment, 2000).Red(), .01, "%T", environment)
+	}
+
+	// importance sampling a bright spot in the environment gives the same answer as finding it
+	// by chance, with much less noise
+	spot := NewImageEnvironment(newTestEnvironmentImage())
+	spot.Transform = NewRotationY(math.Pi).CombineWith(NewRotationX(-.5))
+	sampled := mean(spot, 500)
+	unsampled := mean(unsampledEnvironment{spot}, 20000)
+	assert.InDelta(t, unsampled.Red(), sampled.Red(), unsampled.Red()*.05)
+	assert.Greater(t, sampled.Red(), .2)
+
+	// rays that miss everything see the environment
+	world.Environment = NewSkyEnvironment()
+	up := NewRay(NewPoint(0, 0, -5), NewVector(0, 1, 0))
+	assert.Equal(t, world.Environment.Radiance(up.Direction), NewPathTracer().Radiance(world, up, nil))
+}
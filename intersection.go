@@ -20,18 +20,32 @@ func (i *Intersection) PrepareComputations(ray *Ray) *IntersectionInfo {
 	info := &IntersectionInfo{
 		Object: i.Object,
 		T:      i.T,
+		Time:   ray.Time,
+		stats:  ray.stats,
+	}
+
+	// moving objects are shaded where they are at the ray's time
+	if motion := i.Object.GetMotion(); motion != nil {
+		info.Object = &movingShape{i.Object, motion.TransformAt(ray.Time)}
 	}
 
 	info.Point = ray.Position(info.T)
 	info.EyeV = ray.Direction.Negate()
-	info.NormalV = i.Object.NormalAt(info.Point)
+	normalV := info.Object.NormalAt(info.Point)
+	info.NormalV = normalV
+	if material := info.Object.GetMaterial(); material != nil && material.Perturber != nil {
+		info.NormalV = material.Perturber.PerturbNormal(info.Object, info.Point, normalV)
+	}
 
-	if info.NormalV.Dot(info.EyeV) < 0 {
+	// whether the hit is inside the object depends only on its geometry
+	if normalV.Dot(info.EyeV) < 0 {
 		info.Inside = true
+		normalV = normalV.Negate()
 		info.NormalV = info.NormalV.Negate()
 	}
 
-	info.OverPoint = info.Point.Add(info.NormalV.Multiply(EPSILON))
+	info.ReflectV = ray.Direction.Reflect(info.NormalV)
+	info.OverPoint = info.Point.Add(normalV.Multiply(EPSILON))
 
 	return info
 }
@@ -40,11 +54,21 @@ func (i *Intersection) PrepareComputations(ray *Ray) *IntersectionInfo {
 type IntersectionInfo struct {
 	Object    Shape
 	T         float64
+	Time      float64
 	Point     Tuple
 	OverPoint Tuple
 	EyeV      Tuple
 	NormalV   Tuple
+	ReflectV  Tuple
 	Inside    bool
+
+	// stats, if not nil, counts the work done lighting the hit
+	stats *RenderStats
+}
+
+// Returns a ray leaving the hit, from just above the surface at the moment it was hit.
+func (info *IntersectionInfo) rayFrom(direction Tuple) *Ray {
+	return &Ray{Origin: info.OverPoint, Direction: direction, Time: info.Time, stats: info.stats}
 }
 
 // An IntersectionSet is a collection of Intersections.
@@ -1,6 +1,7 @@
 package rt
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -40,6 +41,12 @@ func TestIntersection_PrepareComputatations(t *testing.T) {
 	assert.Equal(t, NewVector(0, 0, -1), info.EyeV)
 	assert.Equal(t, NewVector(0, 0, -1), info.NormalV)
 	assert.Equal(t, true, info.Inside)
+
+	// the reflection vector
+	r = NewRay(NewPoint(0, 1, -1), NewVector(0, -math.Sqrt2/2, math.Sqrt2/2))
+	i = NewIntersection(math.Sqrt2, NewPlane())
+	info = i.PrepareComputations(r)
+	assert.True(t, NewVector(0, math.Sqrt2/2, math.Sqrt2/2).Equals(info.ReflectV))
 }
 
 func TestNewIntersectionSet(t *testing.T) {
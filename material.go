@@ -23,6 +23,17 @@ type Material struct {
 	Pattern   Pattern
 	Specular  float64
 	Shininess float64
+
+	// Emission is light given off by the surface itself, which is unaffected by shadows. Under
+	// global illumination, emissive shapes also light their surroundings.
+	Emission Color
+
+	// Perturber, if set, perturbs the surface normal to add small-scale relief.
+	Perturber NormalPerturber
+
+	// PBR, if set, lights the material with its physically based model instead of the Phong
+	// model, which ignores every property but Ambient.
+	PBR *PBRMaterial
 }
 
 // NewMaterial creates a new Material.
@@ -33,17 +44,17 @@ func NewMaterial() *Material {
 		Diffuse:   .9,
 		Specular:  .9,
 		Shininess: 200.0,
+		Emission:  NewColor(0, 0, 0),
 	}
 }
 
 // Lighting returns the computed color of the lighting for the given parameters.
 func (m Material) Lighting(object Shape, light *PointLight, position Tuple, eyeV Tuple, normalV Tuple, inShadow bool) Color {
-	color := m.Color
-	if m.Pattern != nil {
-		color = m.Pattern.AtObject(object, position)
+	if m.PBR != nil {
+		return m.PBR.Lighting(object, light, position, eyeV, normalV, inShadow, m.Ambient).Add(m.emission())
 	}
 
-	effectiveColor := color.HadamardBlend(light.Intensity)
+	effectiveColor := m.AlbedoAt(object, position).HadamardBlend(light.Intensity)
 	lightV := light.Position.Subtract(position).Normalize()
 	ambient := effectiveColor.Multiply(m.Ambient)
 	lightDotNormal := lightV.Dot(normalV)
@@ -59,9 +70,87 @@ func (m Material) Lighting(object Shape, light *PointLight, position Tuple, eyeV
 		}
 	}
 
+	ambient = ambient.Add(m.emission())
 	if inShadow {
 		return ambient
 	}
 
 	return ambient.Add(diffuse).Add(specular)
 }
+
+// BRDF returns the fraction of light arriving from direction lightV that the material reflects
+// toward direction eyeV, at a point on an object. All vectors point away from the surface.
+//
+// The Phong model is treated as a Lambertian diffuse lobe plus a normalized Phong specular lobe.
+// Their reflectances are scaled down if together they would reflect more light than they receive.
+func (m Material) BRDF(object Shape, point Tuple, eyeV Tuple, normalV Tuple, lightV Tuple) Color {
+	if m.PBR != nil {
+		return m.PBR.BRDF(object, point, eyeV, normalV, lightV)
+	}
+
+	if normalV.Dot(lightV) <= 0 || normalV.Dot(eyeV) <= 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	color := m.AlbedoAt(object, point)
+	diffuse, specular := m.Diffuse, m.Specular
+	albedo := math.Max(color.Red(), math.Max(color.Green(), color.Blue()))
+	if total := diffuse*albedo + specular; total > 1 {
+		diffuse, specular = diffuse/total, specular/total
+	}
+
+	brdf := color.Multiply(diffuse / math.Pi)
+	reflectDotEye := lightV.Negate().Reflect(normalV).Dot(eyeV)
+	if reflectDotEye > 0 && specular > 0 {
+		factor := specular * (m.Shininess + 2) / (2 * math.Pi) * math.Pow(reflectDotEye, m.Shininess)
+		brdf = brdf.Add(NewColor(factor, factor, factor))
+	}
+
+	return brdf
+}
+
+// AlbedoAt returns the color of the material at a point on an object, before it's lit.
+func (m Material) AlbedoAt(object Shape, point Tuple) Color {
+	if m.PBR != nil {
+		baseColor, _, _ := m.PBR.surfaceAt(object, point)
+		return baseColor
+	}
+
+	if m.Pattern != nil {
+		return m.Pattern.AtObject(object, point)
+	}
+
+	return m.Color
+}
+
+// EmissionAt returns the light the material emits at a point on an object.
+func (m Material) EmissionAt(object Shape, point Tuple) Color {
+	if m.PBR != nil {
+		return m.PBR.EmissionAt(object, point).Add(m.emission())
+	}
+
+	return m.emission()
+}
+
+// IsEmissive returns true if the material emits light anywhere.
+func (m Material) IsEmissive() bool {
+	if m.PBR != nil && (m.PBR.EmissionPattern != nil || !isBlack(m.PBR.Emission)) {
+		return true
+	}
+
+	return !isBlack(m.emission())
+}
+
+// Returns the material's emission, treating an unset emission as black.
+func (m Material) emission() Color {
+	if m.Emission == nil {
+		return black
+	}
+
+	return m.Emission
+}
+
+// Returns true if a color is black or unset.
+func isBlack(c Color) bool {
+	return c == nil || (c.Red() <= 0 && c.Green() <= 0 && c.Blue() <= 0)
+}
@@ -2,6 +2,7 @@ package rt
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -22,6 +23,7 @@ func TestNewMaterial(t *testing.T) {
 	assert.Equal(t, .9, m.Diffuse)
 	assert.Equal(t, .9, m.Specular)
 	assert.Equal(t, 200.0, m.Shininess)
+	assert.Equal(t, NewColor(0, 0, 0), m.Emission)
 }
 
 func TestMaterial_Lighting(t *testing.T) {
@@ -96,3 +98,72 @@ func TestMaterial_Lighting(t *testing.T) {
 	assert.Equal(t, NewColor(1, 1, 1), c1)
 	assert.Equal(t, NewColor(0, 0, 0), c2)
 }
+
+// Returns the fraction of light arriving head-on that a material reflects, by integrating its
+// BRDF times the cosine over the hemisphere.
+func albedo(m Material, object Shape, rng *rand.Rand) float64 {
+	normal := NewVector(0, 0, -1)
+	const samples = 20000
+	sum := 0.0
+	for i := 0; i < samples; i++ {
+		direction := cosineSampleHemisphere(normal, rng)
+		sum += m.BRDF(object, Origin(), direction, normal, normal).Red() * math.Pi
+	}
+
+	return sum / samples
+}
+
+func TestMaterial_BRDF(t *testing.T) {
+	s := NewSphere()
+	rng := rand.New(rand.NewSource(1))
+
+	// a matte material reflects its diffuse reflectance
+	m := NewMaterial()
+	m.Specular = 0
+	m.Diffuse = .5
+	assert.True(t, eq(.5/math.Pi, m.BRDF(s, Origin(), NewVector(0, 0, -1), NewVector(0, 0, -1), NewVector(0, 1, -1).Normalize()).Red()))
+	assert.InDelta(t, .5, albedo(*m, s, rng), .01)
+
+	// diffuse and specular reflectance are scaled down so that no more light is reflected than received
+	m = NewMaterial()
+	assert.InDelta(t, 1, albedo(*m, s, rng), .05)
+
+	// no light is reflected through the surface
+	assert.Equal(t, NewColor(0, 0, 0), m.BRDF(s, Origin(), NewVector(0, 0, -1), NewVector(0, 0, -1), NewVector(0, 0, 1)))
+
+	// physically based materials use their own BRDF
+	m.PBR = NewPBRMaterial()
+	lightV := NewVector(0, 1, -1).Normalize()
+	expected := m.PBR.BRDF(s, Origin(), NewVector(0, 0, -1), NewVector(0, 0, -1), lightV)
+	assert.Equal(t, expected, m.BRDF(s, Origin(), NewVector(0, 0, -1), NewVector(0, 0, -1), lightV))
+}
+
+func TestMaterial_emission(t *testing.T) {
+	s := NewSphere()
+	m := NewMaterial()
+	assert.False(t, m.IsEmissive())
+	assert.False(t, Material{}.IsEmissive())
+	assert.Equal(t, black, Material{}.EmissionAt(s, Origin()))
+
+	// emission is added to the lighting, even in shadow
+	m.Emission = NewColor(.5, .25, 0)
+	assert.True(t, m.IsEmissive())
+	eyeV, normalV := NewVector(0, 0, -1), NewVector(0, 0, -1)
+	light := NewPointLight(NewPoint(0, 0, -10), NewColor(1, 1, 1))
+	assert.True(t, NewColor(2.4, 2.15, 1.9).Equals(m.Lighting(s, light, Origin(), eyeV, normalV, false)))
+	assert.True(t, NewColor(.6, .35, .1).Equals(m.Lighting(s, light, Origin(), eyeV, normalV, true)))
+	assert.True(t, m.Emission.Equals(m.EmissionAt(s, Origin())))
+
+	// and to a physically based material's own emission
+	m.PBR = NewPBRMaterial()
+	m.PBR.Emission = NewColor(0, 0, 1)
+	assert.True(t, NewColor(.5, .25, 1).Equals(m.EmissionAt(s, Origin())))
+	assert.True(t, NewColor(.6, .35, 1.1).Equals(m.Lighting(s, light, Origin(), eyeV, normalV, true)))
+
+	m.Emission = nil
+	assert.True(t, m.IsEmissive())
+	m.PBR.Emission = NewColor(0, 0, 0)
+	assert.False(t, m.IsEmissive())
+	m.PBR.EmissionPattern = NewSolidPattern(white)
+	assert.True(t, m.IsEmissive())
+}
@@ -83,6 +83,13 @@ func (m Matrix) Determinant() float64 {
 		return m[0][0]*m[1][1] - m[0][1]*m[1][0]
 	}
 
+	// 4x4 determinants are computed as inverse4 computes them, so that whether a matrix is
+	// invertible doesn't depend on how it's inverted
+	if len(m) == 4 {
+		s, c := m.subdeterminants4()
+		return determinant4(s, c)
+	}
+
 	var d float64
 	for c := 0; c < len(m); c++ {
 		d += m[0][c] * m.Cofactor(0, c)
@@ -134,7 +141,17 @@ func (m Matrix) IsInvertable() bool {
 
 // Inverse creates a new matrix representing the inverse of this one.
 func (m Matrix) Inverse() Matrix {
-	if !m.IsInvertable() {
+	if len(m) == 4 {
+		return m.inverse4()
+	}
+
+	return m.cofactorInverse()
+}
+
+// Returns the inverse of a matrix of any size, built from its cofactors.
+func (m Matrix) cofactorInverse() Matrix {
+	determinant := m.Determinant()
+	if determinant == 0 {
 		panic("attempted to invert non-invertable matrix")
 	}
 
@@ -143,9 +160,74 @@ func (m Matrix) Inverse() Matrix {
 	for r := 0; r < size; r++ {
 		for c := 0; c < size; c++ {
 			cofactor := m.Cofactor(r, c)
-			new[c][r] = cofactor / m.Determinant()
+			new[c][r] = cofactor / determinant
 		}
 	}
 
 	return new
 }
+
+// Returns the determinants of the 2x2 submatrices of the top and bottom pairs of rows of a 4x4
+// matrix, which its determinant and inverse are built from.
+func (m Matrix) subdeterminants4() (s [6]float64, c [6]float64) {
+	s[0] = m[0][0]*m[1][1] - m[1][0]*m[0][1]
+	s[1] = m[0][0]*m[1][2] - m[1][0]*m[0][2]
+	s[2] = m[0][0]*m[1][3] - m[1][0]*m[0][3]
+	s[3] = m[0][1]*m[1][2] - m[1][1]*m[0][2]
+	s[4] = m[0][1]*m[1][3] - m[1][1]*m[0][3]
+	s[5] = m[0][2]*m[1][3] - m[1][2]*m[0][3]
+
+	c[5] = m[2][2]*m[3][3] - m[3][2]*m[2][3]
+	c[4] = m[2][1]*m[3][3] - m[3][1]*m[2][3]
+	c[3] = m[2][1]*m[3][2] - m[3][1]*m[2][2]
+	c[2] = m[2][0]*m[3][3] - m[3][0]*m[2][3]
+	c[1] = m[2][0]*m[3][2] - m[3][0]*m[2][2]
+	c[0] = m[2][0]*m[3][1] - m[3][0]*m[2][1]
+	return s, c
+}
+
+// Returns the determinant of a 4x4 matrix from its subdeterminants.
+func determinant4(s [6]float64, c [6]float64) float64 {
+	return s[0]*c[5] - s[1]*c[4] + s[2]*c[3] + s[3]*c[2] - s[4]*c[1] + s[5]*c[0]
+}
+
+// Returns the inverse of a 4x4 matrix. Every transformation is inverted at least once per ray,
+// so this avoids the allocations of computing cofactors with submatrices, instead building them
+// from the determinants of the 2x2 submatrices of the top and bottom pairs of rows.
+func (m Matrix) inverse4() Matrix {
+	s, c := m.subdeterminants4()
+	s0, s1, s2, s3, s4, s5 := s[0], s[1], s[2], s[3], s[4], s[5]
+	c0, c1, c2, c3, c4, c5 := c[0], c[1], c[2], c[3], c[4], c[5]
+	determinant := determinant4(s, c)
+	if determinant == 0 {
+		panic("attempted to invert non-invertable matrix")
+	}
+
+	d := 1 / determinant
+	return Matrix{
+		{
+			(m[1][1]*c5 - m[1][2]*c4 + m[1][3]*c3) * d,
+			(-m[0][1]*c5 + m[0][2]*c4 - m[0][3]*c3) * d,
+			(m[3][1]*s5 - m[3][2]*s4 + m[3][3]*s3) * d,
+			(-m[2][1]*s5 + m[2][2]*s4 - m[2][3]*s3) * d,
+		},
+		{
+			(-m[1][0]*c5 + m[1][2]*c2 - m[1][3]*c1) * d,
+			(m[0][0]*c5 - m[0][2]*c2 + m[0][3]*c1) * d,
+			(-m[3][0]*s5 + m[3][2]*s2 - m[3][3]*s1) * d,
+			(m[2][0]*s5 - m[2][2]*s2 + m[2][3]*s1) * d,
+		},
+		{
+			(m[1][0]*c4 - m[1][1]*c2 + m[1][3]*c0) * d,
+			(-m[0][0]*c4 + m[0][1]*c2 - m[0][3]*c0) * d,
+			(m[3][0]*s4 - m[3][1]*s2 + m[3][3]*s0) * d,
+			(-m[2][0]*s4 + m[2][1]*s2 - m[2][3]*s0) * d,
+		},
+		{
+			(-m[1][0]*c3 + m[1][1]*c1 - m[1][2]*c0) * d,
+			(m[0][0]*c3 - m[0][1]*c1 + m[0][2]*c0) * d,
+			(-m[3][0]*s3 + m[3][1]*s1 - m[3][2]*s0) * d,
+			(m[2][0]*s3 - m[2][1]*s1 + m[2][2]*s0) * d,
+		},
+	}
+}
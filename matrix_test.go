@@ -1,6 +1,8 @@
 package rt
 
 import (
+	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -329,3 +331,55 @@ func TestMatrix_Inverse(t *testing.T) {
 	m3 = m1.Multiply(m2)
 	assert.True(t, m3.Multiply(m2.Inverse()).Equals(m1))
 }
+
+func TestMatrix_inverse4(t *testing.T) {
+	// the closed form inverse matches the one built from cofactors
+	matches := func(m Matrix) {
+		expected, actual := m.cofactorInverse(), m.inverse4()
+		for r := 0; r < 4; r++ {
+			for c := 0; c < 4; c++ {
+				tolerance := 1e-6 * math.Max(1, math.Abs(expected[r][c]))
+				assert.InDelta(t, expected[r][c], actual[r][c], tolerance, "%v at %d, %d", m, r, c)
+			}
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		m := NewMatrix(4)
+		for r := 0; r < 4; r++ {
+			for c := 0; c < 4; c++ {
+				m[r][c] = rng.Float64()*20 - 10
+			}
+		}
+
+		matches(m)
+	}
+
+	// including nearly singular ones
+	matches(Matrix(NewTransform().Scale(1e-6, 1, 1).RotateY(1).Translate(1, 2, 3)))
+	matches(Matrix{
+		{1, 1, 0, 0},
+		{1, 1 + 1e-6, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1},
+	})
+	matches(Matrix{
+		{2, 4, 6, 1},
+		{1, 2, 3 + 1e-7, 5},
+		{0, 1, 1, 1},
+		{3, 0, 1, 2},
+	})
+
+	assert.Panics(t, func() { NewScaling(1, 0, 1).Inverse() })
+
+	// a matrix that's only invertible by rounding is invertible however it's inverted
+	m := Matrix{
+		{1, 1, .1, 3},
+		{1, 1, .1, -1},
+		{.1, .7, 0, -1},
+		{0, 0, 0, 1},
+	}
+	assert.False(t, m.IsInvertable())
+	assert.Panics(t, func() { m.Inverse() })
+}
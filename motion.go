@@ -0,0 +1,286 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// A Keyframe is a shape's transform at a moment in time.
+type Keyframe struct {
+	Time      float64
+	Transform Transformation
+}
+
+// A Motion moves a shape by interpolating between transforms at increasing times, which are
+// usually within the camera's shutter interval. Before the first keyframe and after the last,
+// the shape holds still.
+//
+// Transforms are interpolated by splitting them into a translation, a rotation, and a scale,
+// so rotating shapes turn rather than shrink between keyframes. Each rotation takes the shortest
+// way around, so spinning shapes need a keyframe at least every half turn.
+type Motion struct {
+	Keyframes []Keyframe
+
+	once       sync.Once
+	decomposed []decomposedTransform
+}
+
+// NewMotion creates a new Motion from the start transform at time 0 to the end transform at time 1.
+func NewMotion(start Transformation, end Transformation) (*Motion, error) {
+	return NewKeyframeMotion(Keyframe{0, start}, Keyframe{1, end})
+}
+
+// NewKeyframeMotion creates a new Motion through the given keyframes, which are sorted by time.
+// Each keyframe's transform must be invertible, and a shape can't be mirrored at one keyframe and
+// not the next, since it would be flattened on its way between them.
+func NewKeyframeMotion(keyframes ...Keyframe) (*Motion, error) {
+	sorted := append([]Keyframe(nil), keyframes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time < sorted[j].Time
+	})
+
+	err := checkTransformKeys(len(sorted), func(i int) (float64, Transformation) {
+		return sorted[i].Time, sorted[i].Transform
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Motion{Keyframes: sorted}, nil
+}
+
+// Checks that transforms at times sorted in order can be interpolated: each must be invertible,
+// and a shape can't be mirrored at one time and not the next.
+func checkTransformKeys(n int, key func(i int) (float64, Transformation)) error {
+	var previousTime, previous float64
+	for i := 0; i < n; i++ {
+		time, transform := key(i)
+		determinant := linearPart(transform).Determinant()
+		if determinant == 0 || !transform.IsInvertable() {
+			return fmt.Errorf("transform at time %g is not invertible", time)
+		}
+
+		if i > 0 && time > previousTime && (determinant < 0) != (previous < 0) {
+			return fmt.Errorf("transforms at times %g and %g can't differ in whether they mirror the shape", previousTime, time)
+		}
+
+		previousTime, previous = time, determinant
+	}
+
+	return nil
+}
+
+// TransformAt returns the transform at a moment in time.
+func (m *Motion) TransformAt(time float64) Transformation {
+	keyframes := m.Keyframes
+	n := len(keyframes)
+	if n == 0 {
+		return NewTransform()
+	}
+
+	if time <= keyframes[0].Time {
+		return keyframes[0].Transform
+	}
+
+	if time >= keyframes[n-1].Time {
+		return keyframes[n-1].Transform
+	}
+
+	m.once.Do(func() {
+		m.decomposed = make([]decomposedTransform, n)
+		for i, keyframe := range keyframes {
+			m.decomposed[i] = decomposeTransform(keyframe.Transform)
+		}
+	})
+
+	i := sort.Search(n, func(i int) bool {
+		return keyframes[i].Time > time
+	}) - 1
+
+	span := keyframes[i+1].Time - keyframes[i].Time
+	if span <= 0 {
+		return keyframes[i+1].Transform
+	}
+
+	return m.decomposed[i].interpolate(m.decomposed[i+1], (time-keyframes[i].Time)/span)
+}
+
+// A decomposedTransform is a transform split into a translation, a rotation, and a stretch,
+// which are applied in reverse order.
+type decomposedTransform struct {
+	translation Tuple
+	rotation    quaternion
+	stretch     Matrix
+}
+
+// Returns a transform split into its parts, using polar decomposition to separate the rotation
+// from the stretch.
+func decomposeTransform(t Transformation) decomposedTransform {
+	translation := NewVector(t[0][3], t[1][3], t[2][3])
+	m := linearPart(t)
+
+	// averaging a matrix with its inverse transpose converges to the nearest rotation
+	rotation := m
+	for i := 0; i < 100; i++ {
+		inverseTranspose := rotation.Inverse().Transpose()
+		next := NewMatrix(3)
+		change := 0.0
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				next[r][c] = (rotation[r][c] + inverseTranspose[r][c]) / 2
+				change = math.Max(change, math.Abs(next[r][c]-rotation[r][c]))
+			}
+		}
+
+		rotation = next
+		if change < 1e-10 {
+			break
+		}
+	}
+
+	// mirroring belongs to the stretch, so the rotation is a proper one
+	stretch := rotation.Transpose().Multiply(m)
+	if rotation.Determinant() < 0 {
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				rotation[r][c] = -rotation[r][c]
+				stretch[r][c] = -stretch[r][c]
+			}
+		}
+	}
+
+	return decomposedTransform{translation, newQuaternionFromMatrix(rotation), stretch}
+}
+
+// Returns the 3x3 part of a transform that turns and stretches, without its translation.
+func linearPart(t Transformation) Matrix {
+	return Matrix{
+		{t[0][0], t[0][1], t[0][2]},
+		{t[1][0], t[1][1], t[1][2]},
+		{t[2][0], t[2][1], t[2][2]},
+	}
+}
+
+// Returns the transform a fraction t of the way from this one to another.
+func (d decomposedTransform) interpolate(other decomposedTransform, t float64) Transformation {
+	translation := d.translation.Add(other.translation.Subtract(d.translation).Multiply(t))
+	rotation := d.rotation.slerp(other.rotation, t).matrix()
+	stretch := NewMatrix(3)
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			stretch[r][c] = d.stretch[r][c] + (other.stretch[r][c]-d.stretch[r][c])*t
+		}
+	}
+
+	m := rotation.Multiply(stretch)
+	return Transformation{
+		{m[0][0], m[0][1], m[0][2], translation.X()},
+		{m[1][0], m[1][1], m[1][2], translation.Y()},
+		{m[2][0], m[2][1], m[2][2], translation.Z()},
+		{0, 0, 0, 1},
+	}
+}
+
+// A quaternion represents a rotation, as w + xi + yj + zk.
+type quaternion struct {
+	w, x, y, z float64
+}
+
+// Returns the quaternion for a 3x3 rotation matrix.
+func newQuaternionFromMatrix(m Matrix) quaternion {
+	trace := m[0][0] + m[1][1] + m[2][2]
+	var q quaternion
+	switch {
+	case trace > 0:
+		s := 2 * math.Sqrt(trace+1)
+		q = quaternion{s / 4, (m[2][1] - m[1][2]) / s, (m[0][2] - m[2][0]) / s, (m[1][0] - m[0][1]) / s}
+	case m[0][0] > m[1][1] && m[0][0] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[0][0]-m[1][1]-m[2][2])
+		q = quaternion{(m[2][1] - m[1][2]) / s, s / 4, (m[0][1] + m[1][0]) / s, (m[0][2] + m[2][0]) / s}
+	case m[1][1] > m[2][2]:
+		s := 2 * math.Sqrt(1+m[1][1]-m[0][0]-m[2][2])
+		q = quaternion{(m[0][2] - m[2][0]) / s, (m[0][1] + m[1][0]) / s, s / 4, (m[1][2] + m[2][1]) / s}
+	default:
+		s := 2 * math.Sqrt(1+m[2][2]-m[0][0]-m[1][1])
+		q = quaternion{(m[1][0] - m[0][1]) / s, (m[0][2] + m[2][0]) / s, (m[1][2] + m[2][1]) / s, s / 4}
+	}
+
+	return q.normalize()
+}
+
+func (q quaternion) dot(other quaternion) float64 {
+	return q.w*other.w + q.x*other.x + q.y*other.y + q.z*other.z
+}
+
+func (q quaternion) normalize() quaternion {
+	length := math.Sqrt(q.dot(q))
+	return quaternion{q.w / length, q.x / length, q.y / length, q.z / length}
+}
+
+// Returns the rotation a fraction t of the way from this one to another, turning at a constant
+// rate the shortest way around.
+func (q quaternion) slerp(other quaternion, t float64) quaternion {
+	cosTheta := q.dot(other)
+	if cosTheta < 0 {
+		other = quaternion{-other.w, -other.x, -other.y, -other.z}
+		cosTheta = -cosTheta
+	}
+
+	// nearly identical rotations are interpolated linearly, avoiding division by zero
+	a, b := 1-t, t
+	if cosTheta < .9995 {
+		theta := math.Acos(cosTheta)
+		a = math.Sin((1-t)*theta) / math.Sin(theta)
+		b = math.Sin(t*theta) / math.Sin(theta)
+	}
+
+	return quaternion{
+		a*q.w + b*other.w,
+		a*q.x + b*other.x,
+		a*q.y + b*other.y,
+		a*q.z + b*other.z,
+	}.normalize()
+}
+
+// Returns the 3x3 rotation matrix for a unit quaternion.
+func (q quaternion) matrix() Matrix {
+	w, x, y, z := q.w, q.x, q.y, q.z
+	return Matrix{
+		{1 - 2*(y*y+z*z), 2 * (x*y - w*z), 2 * (x*z + w*y)},
+		{2 * (x*y + w*z), 1 - 2*(x*x+z*z), 2 * (y*z - w*x)},
+		{2 * (x*z - w*y), 2 * (y*z + w*x), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// A movingShape is a moving shape frozen at a moment in time, for shading a hit on it.
+type movingShape struct {
+	Shape
+	transform Transformation
+}
+
+// GetTransform gets the shape's transformation at the moment it's frozen at.
+func (s *movingShape) GetTransform() Transformation {
+	return s.transform
+}
+
+// NormalAt returns the normal vector at a point on the frozen shape.
+func (s *movingShape) NormalAt(point Tuple) Tuple {
+	// find the normal where the point would be under the shape's own transform, then carry
+	// it back to where the shape has moved
+	toRest := s.Shape.GetTransform().CombineWith(s.transform.Inverse())
+	normal := toRest.Transpose().ApplyTo(s.Shape.NormalAt(toRest.ApplyTo(point)))
+	normal[3] = 0
+	return normal.Normalize()
+}
+
+// Returns the shape a moving shape was frozen from, or the shape itself.
+func unfrozenShape(shape Shape) Shape {
+	if moving, ok := shape.(*movingShape); ok {
+		return moving.Shape
+	}
+
+	return shape
+}
@@ -0,0 +1,100 @@
+package rt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMotion_TransformAt(t *testing.T) {
+	start := NewTranslation(0, 0, 0)
+	end := NewTranslation(2, 4, 0)
+	m, err := NewMotion(start, end)
+	require.NoError(t, err)
+	assert.Equal(t, start, m.TransformAt(0))
+	assert.Equal(t, end, m.TransformAt(1))
+	assert.True(t, m.TransformAt(.5).Equals(NewTranslation(1, 2, 0)))
+
+	// the shape holds still outside its keyframes
+	assert.Equal(t, start, m.TransformAt(-1))
+	assert.Equal(t, end, m.TransformAt(2))
+
+	// rotations turn rather than shrink
+	m, err = NewMotion(NewRotationY(0), NewRotationY(math.Pi/2))
+	require.NoError(t, err)
+	assert.True(t, m.TransformAt(.5).Equals(NewRotationY(math.Pi/4)))
+
+	// scaling is interpolated along with the rotation and translation
+	m, err = NewMotion(
+		NewTransform().Scale(1, 2, 1).RotateZ(0).Translate(0, 0, 0),
+		NewTransform().Scale(3, 2, 1).RotateZ(math.Pi/2).Translate(4, 0, 0),
+	)
+	require.NoError(t, err)
+	expected := NewTransform().Scale(2, 2, 1).RotateZ(math.Pi/4).Translate(2, 0, 0)
+	assert.True(t, m.TransformAt(.5).Equals(expected))
+
+	// mirrored transforms stay mirrored
+	m, err = NewMotion(NewScaling(-1, 1, 1), NewScaling(-3, 1, 1))
+	require.NoError(t, err)
+	assert.True(t, m.TransformAt(.5).Equals(NewScaling(-2, 1, 1)))
+
+	// shapes can't be flattened, or mirrored on the way between transforms, which flattens them
+	_, err = NewMotion(NewTransform(), NewScaling(0, 0, 0))
+	assert.EqualError(t, err, "transform at time 1 is not invertible")
+	_, err = NewMotion(NewScaling(1, 1, 1), NewScaling(-1, 1, 1))
+	assert.EqualError(t, err, "transforms at times 0 and 1 can't differ in whether they mirror the shape")
+}
+
+func TestNewKeyframeMotion(t *testing.T) {
+	m, err := NewKeyframeMotion(
+		Keyframe{2, NewTranslation(0, 2, 0)},
+		Keyframe{0, NewTranslation(0, 0, 0)},
+		Keyframe{1, NewTranslation(1, 0, 0)},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 1, 2}, []float64{m.Keyframes[0].Time, m.Keyframes[1].Time, m.Keyframes[2].Time})
+	assert.True(t, m.TransformAt(.5).Equals(NewTranslation(.5, 0, 0)))
+	assert.True(t, m.TransformAt(1.5).Equals(NewTranslation(.5, 1, 0)))
+
+	m, err = NewKeyframeMotion()
+	require.NoError(t, err)
+	assert.Equal(t, NewTransform(), m.TransformAt(0))
+
+	// a shape can be mirrored in an instant, though
+	_, err = NewKeyframeMotion(Keyframe{0, NewTransform()}, Keyframe{.5, NewTransform()}, Keyframe{.5, NewScaling(-1, 1, 1)})
+	assert.NoError(t, err)
+	_, err = NewKeyframeMotion(Keyframe{0, NewTransform()}, Keyframe{.5, NewScaling(2, 0, 2)})
+	assert.EqualError(t, err, "transform at time 0.5 is not invertible")
+}
+
+func TestMovingShape(t *testing.T) {
+	s := NewSphere()
+	s.Material.Pattern = NewStripePattern(NewSolidPattern(white), NewSolidPattern(black))
+	m, err := NewMotion(NewScaling(1, 2, 1), NewTransform().Scale(1, 2, 1).Translate(10, 0, 0))
+	require.NoError(t, err)
+	s.SetMotion(m)
+
+	// rays hit the shape where it is at their time
+	r := NewRayAt(NewPoint(5, 0, -5), NewVector(0, 0, 1), .5)
+	xs := s.Intersect(r)
+	if assert.Len(t, xs, 2) {
+		assert.InDelta(t, 4, xs[0].T, EPSILON)
+		assert.InDelta(t, 6, xs[1].T, EPSILON)
+	}
+
+	assert.Empty(t, s.Intersect(NewRayAt(NewPoint(5, 0, -5), NewVector(0, 0, 1), 0)))
+
+	// and hits are shaded on the moved shape
+	info := xs[0].PrepareComputations(r)
+	assert.True(t, info.Object.GetTransform().Equals(NewTransform().Scale(1, 2, 1).Translate(5, 0, 0)))
+	assert.Equal(t, s, unfrozenShape(info.Object))
+	assert.True(t, info.NormalV.Equals(NewVector(0, 0, -1)))
+
+	// the point is in the first stripe of the moved sphere, though far from the sphere's rest
+	r = NewRayAt(NewPoint(5.5, 10, 0), NewVector(0, -1, 0), .5)
+	info = NewIntersection(10-2*math.Sqrt(.75), s).PrepareComputations(r)
+	assert.True(t, info.NormalV.Equals(NewVector(.5, math.Sqrt(.75)/2, 0).Normalize()))
+	assert.Equal(t, white, s.Material.Pattern.AtObject(info.Object, info.Point))
+}
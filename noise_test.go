@@ -0,0 +1,58 @@
+package rt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoise(t *testing.T) {
+	// zero at lattice points
+	assert.Equal(t, 0.0, Noise(NewPoint(0, 0, 0)))
+	assert.Equal(t, 0.0, Noise(NewPoint(3, -7, 12)))
+
+	// deterministic
+	p := NewPoint(1.3, 2.7, -.4)
+	assert.Equal(t, Noise(p), Noise(p))
+
+	// bounded and not constant
+	min, max := math.Inf(1), math.Inf(-1)
+	for i := 0; i < 1000; i++ {
+		n := Noise(NewPoint(float64(i)*.137, float64(i)*.071, float64(i)*.053))
+		min = math.Min(min, n)
+		max = math.Max(max, n)
+	}
+
+	assert.True(t, min >= -1 && max <= 1)
+	assert.True(t, max-min > .5)
+
+	// continuous
+	assert.InDelta(t, Noise(p), Noise(p.Add(NewVector(.0001, 0, 0))), .001)
+}
+
+func TestFBM(t *testing.T) {
+	p := NewPoint(1.3, 2.7, -.4)
+
+	// one octave is plain noise
+	assert.Equal(t, Noise(p), FBM(p, 1))
+
+	// more octaves add detail at higher frequencies
+	expected := (Noise(p) + .5*Noise(p.Multiply(2))) / 1.5
+	assert.True(t, eq(expected, FBM(p, 2)))
+
+	assert.Equal(t, 0.0, FBM(p, 0))
+}
+
+func TestTurbulence(t *testing.T) {
+	p := NewPoint(1.3, 2.7, -.4)
+	assert.Equal(t, math.Abs(Noise(p)), Turbulence(p, 1))
+
+	expected := (math.Abs(Noise(p)) + .5*math.Abs(Noise(p.Multiply(2)))) / 1.5
+	assert.True(t, eq(expected, Turbulence(p, 2)))
+
+	for i := 0; i < 100; i++ {
+		v := Turbulence(NewPoint(float64(i)*.37, 1.1, float64(i)*.19), 4)
+		assert.True(t, v >= 0 && v <= 1)
+	}
+}
@@ -16,6 +16,7 @@ type Pattern interface {
 	SetTransform(transform Transformation)
 	atA(parentPatternPoint Tuple) Color
 	atB(parentPatternPoint Tuple) Color
+	subpatterns() []Pattern
 }
 
 // PatternProps contains properties common to all patterns.
@@ -62,6 +63,18 @@ func (props *PatternProps) atB(parentPatternPoint Tuple) Color {
 	return props.B.At(props.B.GetTransform().Inverse().ApplyTo(parentPatternPoint))
 }
 
+// Returns the pattern's subpatterns.
+func (props *PatternProps) subpatterns() []Pattern {
+	var patterns []Pattern
+	for _, pattern := range []Pattern{props.A, props.B} {
+		if pattern != nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
 // A SolidPattern is just a single color.
 type SolidPattern struct {
 	PatternProps
@@ -184,3 +197,121 @@ func (p *CheckerPattern) At(point Tuple) Color {
 	subpatternPoint := p.B.GetTransform().Inverse().ApplyTo(point)
 	return p.B.At(subpatternPoint)
 }
+
+// A PerturbedPattern moves each point it is sampled at by a random-looking but smooth
+// amount before sampling its subpattern, which makes regular patterns look organic.
+type PerturbedPattern struct {
+	PatternProps
+
+	// Scale is the largest distance a point is moved along each axis.
+	Scale float64
+
+	// Octaves is the number of octaves of noise used to move points.
+	Octaves int
+}
+
+// NewPerturbedPattern creates a new PerturbedPattern.
+func NewPerturbedPattern(pattern Pattern, scale float64) *PerturbedPattern {
+	perturbed := &PerturbedPattern{NewPatternProps(pattern, nil), scale, 1}
+	perturbed.p = perturbed
+	return perturbed
+}
+
+// At returns the pattern color at the given point.
+func (p *PerturbedPattern) At(point Tuple) Color {
+	// sample the noise field at three distant offsets to get independent values for each axis
+	jitter := NewVector(
+		FBM(point, p.Octaves),
+		FBM(point.Add(NewVector(31.7, 17.3, 5.9)), p.Octaves),
+		FBM(point.Add(NewVector(-11.3, 47.1, 23.7)), p.Octaves),
+	)
+
+	return p.atA(point.Add(jitter.Multiply(p.Scale)))
+}
+
+// A TurbulencePattern blends between two patterns using turbulent noise.
+type TurbulencePattern struct {
+	PatternProps
+	Octaves int
+}
+
+// NewTurbulencePattern creates a new TurbulencePattern.
+func NewTurbulencePattern(a Pattern, b Pattern) *TurbulencePattern {
+	pattern := &TurbulencePattern{NewPatternProps(a, b), 4}
+	pattern.p = pattern
+	return pattern
+}
+
+// At returns the pattern color at the given point.
+func (p *TurbulencePattern) At(point Tuple) Color {
+	return mixColors(p.atA(point), p.atB(point), Turbulence(point, p.Octaves))
+}
+
+// An FBMPattern blends between two patterns using fractional Brownian motion, which looks like clouds.
+type FBMPattern struct {
+	PatternProps
+	Octaves int
+}
+
+// NewFBMPattern creates a new FBMPattern.
+func NewFBMPattern(a Pattern, b Pattern) *FBMPattern {
+	pattern := &FBMPattern{NewPatternProps(a, b), 4}
+	pattern.p = pattern
+	return pattern
+}
+
+// At returns the pattern color at the given point.
+func (p *FBMPattern) At(point Tuple) Color {
+	return mixColors(p.atA(point), p.atB(point), (FBM(point, p.Octaves)+1)/2)
+}
+
+// A MarblePattern is a pattern of veins that alternate between two patterns along the X axis,
+// distorted by turbulence.
+type MarblePattern struct {
+	PatternProps
+	Octaves int
+
+	// Strength is how much the turbulence distorts the veins.
+	Strength float64
+}
+
+// NewMarblePattern creates a new MarblePattern.
+func NewMarblePattern(a Pattern, b Pattern) *MarblePattern {
+	pattern := &MarblePattern{NewPatternProps(a, b), 4, 5}
+	pattern.p = pattern
+	return pattern
+}
+
+// At returns the pattern color at the given point.
+func (p *MarblePattern) At(point Tuple) Color {
+	x := point.X() + p.Strength*Turbulence(point, p.Octaves)
+	return mixColors(p.atA(point), p.atB(point), (1+math.Sin(x*math.Pi))/2)
+}
+
+// A WoodPattern is a pattern of growth rings around the Y axis that fade from one
+// pattern to another, distorted by turbulence.
+type WoodPattern struct {
+	PatternProps
+	Octaves int
+
+	// Strength is how much the turbulence distorts the rings.
+	Strength float64
+}
+
+// NewWoodPattern creates a new WoodPattern.
+func NewWoodPattern(a Pattern, b Pattern) *WoodPattern {
+	pattern := &WoodPattern{NewPatternProps(a, b), 2, .5}
+	pattern.p = pattern
+	return pattern
+}
+
+// At returns the pattern color at the given point.
+func (p *WoodPattern) At(point Tuple) Color {
+	r := math.Sqrt(point.X()*point.X()+point.Z()*point.Z()) + p.Strength*Turbulence(point, p.Octaves)
+	return mixColors(p.atA(point), p.atB(point), r-math.Floor(r))
+}
+
+// Returns the color a fraction t of the way from a to b.
+func mixColors(a Color, b Color, t float64) Color {
+	return a.Add(b.Subtract(a).Multiply(t))
+}
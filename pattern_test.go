@@ -146,3 +146,71 @@ func TestCheckerPattern_At(t *testing.T) {
 	assert.True(t, p.At(NewPoint(0, 0, .99)).Equals(white))
 	assert.True(t, p.At(NewPoint(0, 0, 1.01)).Equals(black))
 }
+
+func TestPerturbedPattern_At(t *testing.T) {
+	p := NewPerturbedPattern(newTestPattern(solidWhite, solidBlack), .5)
+
+	// the test pattern returns the point it was sampled at, which is moved by at most the scale
+	point := NewPoint(1.2, 3.4, 5.6)
+	c := p.At(point)
+	assert.False(t, c.Equals(NewColor(1.2, 3.4, 5.6)))
+	assert.InDelta(t, 1.2, c.Red(), .5)
+	assert.InDelta(t, 3.4, c.Green(), .5)
+	assert.InDelta(t, 5.6, c.Blue(), .5)
+
+	// with no scale, points aren't moved
+	p.Scale = 0
+	assert.Equal(t, NewColor(1.2, 3.4, 5.6), p.At(point))
+
+	// the subpattern's transformation is applied
+	sub := newTestPattern(solidWhite, solidBlack)
+	sub.SetTransform(NewScaling(2, 2, 2))
+	p = NewPerturbedPattern(sub, 0)
+	assert.True(t, p.At(point).Equals(NewColor(.6, 1.7, 2.8)))
+}
+
+func TestTurbulencePattern_At(t *testing.T) {
+	p := NewTurbulencePattern(solidWhite, solidBlack)
+	point := NewPoint(1.3, 2.7, -.4)
+	v := 1 - Turbulence(point, 4)
+	assert.True(t, p.At(point).Equals(NewColor(v, v, v)))
+
+	// lattice points have no turbulence
+	assert.Equal(t, white, p.At(NewPoint(1, 2, 3)))
+}
+
+func TestFBMPattern_At(t *testing.T) {
+	p := NewFBMPattern(solidBlack, solidWhite)
+	point := NewPoint(1.3, 2.7, -.4)
+	v := (FBM(point, 4) + 1) / 2
+	assert.True(t, p.At(point).Equals(NewColor(v, v, v)))
+	assert.True(t, p.At(NewPoint(1, 2, 3)).Equals(NewColor(.5, .5, .5)))
+}
+
+func TestMarblePattern_At(t *testing.T) {
+	p := NewMarblePattern(solidBlack, solidWhite)
+
+	// without turbulence, the veins follow a sine wave along X
+	p.Strength = 0
+	assert.True(t, p.At(NewPoint(0, 0, 0)).Equals(NewColor(.5, .5, .5)))
+	assert.True(t, p.At(NewPoint(.5, 0, 0)).Equals(white))
+	assert.True(t, p.At(NewPoint(1.5, 0, 0)).Equals(black))
+
+	// turbulence distorts the veins
+	p.Strength = 5
+	assert.False(t, p.At(NewPoint(.5, .3, .2)).Equals(white))
+}
+
+func TestWoodPattern_At(t *testing.T) {
+	p := NewWoodPattern(solidBlack, solidWhite)
+
+	// without turbulence, rings fade outward from the Y axis
+	p.Strength = 0
+	assert.True(t, p.At(NewPoint(0, 0, 0)).Equals(black))
+	assert.True(t, p.At(NewPoint(.25, 5, 0)).Equals(NewColor(.25, .25, .25)))
+	assert.True(t, p.At(NewPoint(0, 0, 1.75)).Equals(NewColor(.75, .75, .75)))
+
+	// turbulence distorts the rings
+	p.Strength = .5
+	assert.False(t, p.At(NewPoint(.25, 5.3, .1)).Equals(NewColor(.25, .25, .25)))
+}
@@ -0,0 +1,145 @@
+package rt
+
+import (
+	"math"
+)
+
+// MinRoughness is the smallest roughness used for lighting, which keeps the highlights of
+// perfectly smooth surfaces from becoming infinitely small and bright.
+const MinRoughness = .03
+
+// A PBRMaterial is a physically based metallic-roughness material, lit with a Cook-Torrance
+// BRDF: a GGX microfacet distribution, Smith geometry term, and Schlick Fresnel term.
+//
+// Each property can be driven by a pattern, which replaces the property's value where it's set.
+// Numeric properties use the luminance of their pattern's color.
+type PBRMaterial struct {
+	BaseColor        Color
+	BaseColorPattern Pattern
+
+	// Metallic is 0 for dielectrics like plastic and stone and 1 for bare metal.
+	Metallic        float64
+	MetallicPattern Pattern
+
+	// Roughness is 0 for a mirror-like surface and 1 for a completely matte one.
+	Roughness        float64
+	RoughnessPattern Pattern
+
+	// Emission is light given off by the surface itself, which is unaffected by shadows.
+	Emission        Color
+	EmissionPattern Pattern
+}
+
+// NewPBRMaterial creates a new PBRMaterial for a white, fairly rough dielectric.
+func NewPBRMaterial() *PBRMaterial {
+	return &PBRMaterial{
+		BaseColor: NewColor(1, 1, 1),
+		Roughness: .5,
+		Emission:  NewColor(0, 0, 0),
+	}
+}
+
+// Returns the base color, metallic, and roughness at a point on an object.
+func (m *PBRMaterial) surfaceAt(object Shape, point Tuple) (baseColor Color, metallic float64, roughness float64) {
+	baseColor, metallic, roughness = m.BaseColor, m.Metallic, m.Roughness
+	if m.BaseColorPattern != nil {
+		baseColor = m.BaseColorPattern.AtObject(object, point)
+	}
+
+	if m.MetallicPattern != nil {
+		metallic = m.MetallicPattern.AtObject(object, point).Luminance()
+	}
+
+	if m.RoughnessPattern != nil {
+		roughness = m.RoughnessPattern.AtObject(object, point).Luminance()
+	}
+
+	return baseColor, clamp(metallic, 0, 1), clamp(roughness, MinRoughness, 1)
+}
+
+// Returns the patterns that drive the material's properties.
+func (m *PBRMaterial) patterns() []Pattern {
+	var patterns []Pattern
+	for _, pattern := range []Pattern{m.BaseColorPattern, m.MetallicPattern, m.RoughnessPattern, m.EmissionPattern} {
+		if pattern != nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
+}
+
+// EmissionAt returns the light emitted at a point on an object.
+func (m *PBRMaterial) EmissionAt(object Shape, point Tuple) Color {
+	if m.EmissionPattern != nil {
+		return m.EmissionPattern.AtObject(object, point)
+	}
+
+	return m.Emission
+}
+
+// BRDF returns the fraction of light arriving from direction lightV that the surface at a point
+// reflects toward direction eyeV. All vectors point away from the surface.
+func (m *PBRMaterial) BRDF(object Shape, point Tuple, eyeV Tuple, normalV Tuple, lightV Tuple) Color {
+	nDotL := normalV.Dot(lightV)
+	nDotV := normalV.Dot(eyeV)
+	if nDotL <= 0 || nDotV <= 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	baseColor, metallic, roughness := m.surfaceAt(object, point)
+	halfV := lightV.Add(eyeV).Normalize()
+	nDotH := math.Max(normalV.Dot(halfV), 0)
+	vDotH := math.Max(eyeV.Dot(halfV), 0)
+
+	// dielectrics reflect about 4% of light head-on, while metals reflect their base color
+	f0 := mixColors(NewColor(.04, .04, .04), baseColor, metallic)
+	fresnel := schlickFresnel(f0, vDotH)
+	distribution := ggxDistribution(nDotH, roughness)
+	geometry := smithGeometry(nDotV, roughness) * smithGeometry(nDotL, roughness)
+	specular := fresnel.Multiply(distribution * geometry / (4 * nDotL * nDotV))
+
+	// light that isn't reflected at the surface is scattered diffusely, except by metals
+	diffuse := white.Subtract(fresnel).HadamardBlend(baseColor).Multiply((1 - metallic) / math.Pi)
+	return diffuse.Add(specular)
+}
+
+// Lighting returns the color of a point on an object lit by a light. A light's intensity is the
+// brightness of a white, matte surface facing it, and ambient is the fraction of the light's
+// intensity that reaches the surface indirectly, even when it's in shadow.
+func (m *PBRMaterial) Lighting(object Shape, light *PointLight, position Tuple, eyeV Tuple, normalV Tuple, inShadow bool, ambient float64) Color {
+	baseColor, _, _ := m.surfaceAt(object, position)
+	color := m.EmissionAt(object, position).Add(baseColor.HadamardBlend(light.Intensity).Multiply(ambient))
+	if inShadow {
+		return color
+	}
+
+	lightV := light.Position.Subtract(position).Normalize()
+	nDotL := normalV.Dot(lightV)
+	if nDotL <= 0 {
+		return color
+	}
+
+	reflected := m.BRDF(object, position, eyeV, normalV, lightV).HadamardBlend(light.Intensity)
+	return color.Add(reflected.Multiply(math.Pi * nDotL))
+}
+
+// Returns the GGX (Trowbridge-Reitz) normal distribution function, the density of microfacets
+// whose normals point along the half vector.
+func ggxDistribution(nDotH float64, roughness float64) float64 {
+	alpha2 := math.Pow(roughness, 4)
+	d := nDotH*nDotH*(alpha2-1) + 1
+	return alpha2 / (math.Pi * d * d)
+}
+
+// Returns the Smith geometry term for one direction, using the Schlick-GGX approximation, which
+// is the fraction of microfacets that aren't shadowed or masked from that direction.
+func smithGeometry(nDotX float64, roughness float64) float64 {
+	k := (roughness + 1) * (roughness + 1) / 8
+	return nDotX / (nDotX*(1-k) + k)
+}
+
+// Returns Schlick's approximation of the Fresnel reflectance, given the reflectance head-on.
+func schlickFresnel(f0 Color, cosTheta float64) Color {
+	return f0.Add(white.Subtract(f0).Multiply(math.Pow(1-cosTheta, 5)))
+}
@@ -0,0 +1,135 @@
+package rt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPBRMaterial(t *testing.T) {
+	m := NewPBRMaterial()
+	assert.Equal(t, NewColor(1, 1, 1), m.BaseColor)
+	assert.Equal(t, 0.0, m.Metallic)
+	assert.Equal(t, .5, m.Roughness)
+	assert.Equal(t, NewColor(0, 0, 0), m.Emission)
+}
+
+func TestGGXDistribution(t *testing.T) {
+	// the projected area of the microfacets is the area of the surface, so D(h) (n.h)
+	// integrates to one over the hemisphere
+	for _, roughness := range []float64{.2, .5, 1} {
+		const steps = 2000
+		sum := 0.0
+		for i := 0; i < steps; i++ {
+			theta := (float64(i) + .5) / steps * math.Pi / 2
+			cosTheta := math.Cos(theta)
+			sum += ggxDistribution(cosTheta, roughness) * cosTheta * math.Sin(theta) * 2 * math.Pi * (math.Pi / 2 / steps)
+		}
+
+		assert.InDelta(t, 1, sum, .01, "roughness %v", roughness)
+	}
+}
+
+func TestSmithGeometry(t *testing.T) {
+	assert.True(t, eq(1, smithGeometry(1, .5)))
+	assert.Equal(t, 0.0, smithGeometry(0, .5))
+
+	// rougher surfaces shadow more at grazing angles
+	assert.Less(t, smithGeometry(.2, 1), smithGeometry(.2, .1))
+}
+
+func TestSchlickFresnel(t *testing.T) {
+	f0 := NewColor(.04, .5, 1)
+	assert.True(t, f0.Equals(schlickFresnel(f0, 1)))
+	assert.True(t, NewColor(1, 1, 1).Equals(schlickFresnel(f0, 0)))
+}
+
+func TestPBRMaterial_BRDF(t *testing.T) {
+	s := NewSphere()
+	normalV := NewVector(0, 0, -1)
+	m := NewPBRMaterial()
+
+	// no light is reflected from below the surface or toward it
+	assert.Equal(t, NewColor(0, 0, 0), m.BRDF(s, Origin(), normalV, normalV, NewVector(0, 0, 1)))
+	assert.Equal(t, NewColor(0, 0, 0), m.BRDF(s, Origin(), NewVector(0, 1, 0), normalV, normalV))
+
+	// a rough dielectric scatters mostly diffusely, while a smooth one has a sharp highlight
+	lightV := NewVector(0, 1, -1).Normalize()
+	mirrorV := NewVector(0, -1, -1).Normalize()
+	offV := NewVector(0, -.5, -1).Normalize()
+	m.Roughness = 1
+	rough := m.BRDF(s, Origin(), mirrorV, normalV, lightV).Red() / m.BRDF(s, Origin(), offV, normalV, lightV).Red()
+	m.Roughness = .1
+	smooth := m.BRDF(s, Origin(), mirrorV, normalV, lightV).Red() / m.BRDF(s, Origin(), offV, normalV, lightV).Red()
+	assert.Less(t, rough, 1.5)
+	assert.Greater(t, smooth, 10.0)
+
+	// metals have no diffuse reflection, and tint their reflections with their base color
+	m.BaseColor = NewColor(1, .5, 0)
+	m.Metallic = 1
+	m.Roughness = .5
+	brdf := m.BRDF(s, Origin(), normalV, normalV, normalV)
+	assert.True(t, eq(brdf.Red()/2, brdf.Green()))
+	assert.True(t, eq(0, brdf.Blue()))
+}
+
+func TestPBRMaterial_Lighting(t *testing.T) {
+	s := NewSphere()
+	normalV := NewVector(0, 0, -1)
+	light := NewPointLight(NewPoint(0, 0, -10), NewColor(1, 1, 1))
+
+	// a white, matte surface facing the light is about as bright as the light
+	m := NewPBRMaterial()
+	m.Roughness = 1
+	result := m.Lighting(s, light, Origin(), NewVector(0, 1, -1).Normalize(), normalV, false, 0)
+	assert.InDelta(t, 1, result.Red(), .05)
+
+	// the surface is dimmer when lit at an angle, and only ambient and emitted light remain in shadow
+	m.Emission = NewColor(0, 0, .5)
+	angled := NewPointLight(NewPoint(0, 10, -10), NewColor(1, 1, 1))
+	result = m.Lighting(s, angled, Origin(), normalV, normalV, false, .1)
+	assert.Less(t, result.Red(), .9)
+	result = m.Lighting(s, light, Origin(), normalV, normalV, true, .1)
+	assert.True(t, NewColor(.1, .1, .6).Equals(result))
+
+	// the light behind the surface
+	behind := NewPointLight(NewPoint(0, 0, 10), NewColor(1, 1, 1))
+	result = m.Lighting(s, behind, Origin(), normalV, normalV, false, .1)
+	assert.True(t, NewColor(.1, .1, .6).Equals(result))
+}
+
+func TestPBRMaterial_patterns(t *testing.T) {
+	s := NewSphere()
+	m := NewPBRMaterial()
+	m.BaseColorPattern = NewStripePattern(NewSolidPattern(NewColor(1, 0, 0)), NewSolidPattern(NewColor(0, 1, 0)))
+	m.MetallicPattern = NewStripePattern(NewSolidPattern(white), NewSolidPattern(black))
+	m.RoughnessPattern = NewStripePattern(NewSolidPattern(black), NewSolidPattern(NewColor(.8, .8, .8)))
+	m.EmissionPattern = NewStripePattern(NewSolidPattern(black), NewSolidPattern(white))
+
+	baseColor, metallic, roughness := m.surfaceAt(s, NewPoint(.5, 0, 0))
+	assert.Equal(t, NewColor(1, 0, 0), baseColor)
+	assert.Equal(t, 1.0, metallic)
+	assert.Equal(t, MinRoughness, roughness)
+	assert.Equal(t, black, m.EmissionAt(s, NewPoint(.5, 0, 0)))
+
+	baseColor, metallic, roughness = m.surfaceAt(s, NewPoint(1.5, 0, 0))
+	assert.Equal(t, NewColor(0, 1, 0), baseColor)
+	assert.Equal(t, 0.0, metallic)
+	assert.True(t, eq(.8, roughness))
+	assert.Equal(t, white, m.EmissionAt(s, NewPoint(1.5, 0, 0)))
+}
+
+func TestMaterial_Lighting_pbr(t *testing.T) {
+	s := NewSphere()
+	light := NewPointLight(NewPoint(0, 0, -10), NewColor(1, 1, 1))
+	normalV := NewVector(0, 0, -1)
+
+	// the physically based model replaces the Phong model, using the material's ambient
+	m := NewMaterial()
+	m.Ambient = .2
+	m.PBR = NewPBRMaterial()
+	expected := m.PBR.Lighting(s, light, Origin(), normalV, normalV, true, .2)
+	assert.Equal(t, expected, m.Lighting(s, light, Origin(), normalV, normalV, true))
+	assert.True(t, NewColor(.2, .2, .2).Equals(expected))
+}
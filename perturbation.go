@@ -0,0 +1,94 @@
+package rt
+
+// A NormalPerturber changes the normal of a surface to add small-scale relief, such as the
+// roughness of stucco or the grooves between bricks, without extra geometry.
+type NormalPerturber interface {
+	// PerturbNormal returns the perturbed normal at a point on an object, given its
+	// unperturbed world-space normal there.
+	PerturbNormal(object Shape, worldPoint Tuple, normal Tuple) Tuple
+}
+
+// A NormalMap perturbs normals using a tangent-space normal map, such as an image, whose red,
+// green, and blue channels encode a normal's components along the directions in which u and
+// v increase and along the surface normal.
+type NormalMap struct {
+	UVPattern UVPattern
+	Mapping   UVMapping
+	Transform Transformation
+
+	// Strength scales the tilt of the normals. Zero leaves normals unperturbed.
+	Strength float64
+}
+
+// NewNormalMap creates a new NormalMap.
+func NewNormalMap(uvPattern UVPattern, mapping UVMapping) *NormalMap {
+	return &NormalMap{uvPattern, mapping, NewTransform(), 1}
+}
+
+// PerturbNormal returns the perturbed normal at a point on an object.
+func (m *NormalMap) PerturbNormal(object Shape, worldPoint Tuple, normal Tuple) Tuple {
+	toMap := object.GetTransform().CombineWith(m.Transform)
+	mapPoint := toMap.Inverse().ApplyTo(worldPoint)
+	tangent := m.Mapping.tangent(mapPoint)
+	if tangent.Magnitude() == 0 {
+		return normal
+	}
+
+	// tangents are directions along the surface, so they transform like ordinary vectors;
+	// make the tangent frame orthonormal around the normal
+	tangent = toMap.ApplyTo(tangent)
+	tangent = tangent.Subtract(normal.Multiply(normal.Dot(tangent)))
+	if tangent.Magnitude() < EPSILON {
+		return normal
+	}
+
+	tangent = tangent.Normalize()
+	bitangent := tangent.Cross(normal)
+
+	u, v := m.Mapping.Map(mapPoint)
+	color := m.UVPattern.UVAt(u, v)
+	x := (color.Red()*2 - 1) * m.Strength
+	y := (color.Green()*2 - 1) * m.Strength
+	z := color.Blue()*2 - 1
+	perturbed := tangent.Multiply(x).Add(bitangent.Multiply(y)).Add(normal.Multiply(z))
+	if perturbed.Magnitude() < EPSILON {
+		return normal
+	}
+
+	return perturbed.Normalize()
+}
+
+// A BumpMap perturbs normals using a height field, which is the luminance of a pattern.
+// Normals tilt away from higher parts of the pattern.
+type BumpMap struct {
+	Pattern Pattern
+
+	// Scale is the height of the pattern's brightest parts, in world units.
+	Scale float64
+
+	// Delta is the distance between the points sampled to find the slope of the height field.
+	Delta float64
+}
+
+// NewBumpMap creates a new BumpMap.
+func NewBumpMap(pattern Pattern, scale float64) *BumpMap {
+	return &BumpMap{pattern, scale, .001}
+}
+
+// PerturbNormal returns the perturbed normal at a point on an object.
+func (m *BumpMap) PerturbNormal(object Shape, worldPoint Tuple, normal Tuple) Tuple {
+	// estimate the gradient of the height field with central differences
+	var gradient [3]float64
+	for axis := range gradient {
+		offset := NewVector(0, 0, 0)
+		offset[axis] = m.Delta
+		ahead := m.Pattern.AtObject(object, worldPoint.Add(offset)).Luminance()
+		behind := m.Pattern.AtObject(object, worldPoint.Subtract(offset)).Luminance()
+		gradient[axis] = (ahead - behind) / (2 * m.Delta)
+	}
+
+	// only the part of the gradient along the surface tilts the normal
+	slope := NewVector(gradient[0], gradient[1], gradient[2])
+	slope = slope.Subtract(normal.Multiply(normal.Dot(slope)))
+	return normal.Subtract(slope.Multiply(m.Scale)).Normalize()
+}
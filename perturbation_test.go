@@ -0,0 +1,109 @@
+package rt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns a UV pattern that is a single color everywhere.
+func newSolidUVPattern(color Color) UVPattern {
+	return NewUVCheckersPattern(1, 1, color, color)
+}
+
+func TestNormalMap_PerturbNormal(t *testing.T) {
+	// a flat normal map leaves normals unchanged
+	plane := NewPlane()
+	flat := NewNormalMap(newSolidUVPattern(NewColor(.5, .5, 1)), PlanarMapping)
+	normal := flat.PerturbNormal(plane, NewPoint(1, 0, 2), NewVector(0, 1, 0))
+	assert.True(t, NewVector(0, 1, 0).Equals(normal))
+
+	// on a plane, red tilts the normal toward +X and green toward +Z
+	tests := []struct {
+		color    Color
+		expected Tuple
+	}{
+		{NewColor(1, .5, .5), NewVector(1, 0, 0)},
+		{NewColor(.5, 1, .5), NewVector(0, 0, 1)},
+		{NewColor(0, .5, 1), NewVector(-1, 1, 0).Normalize()},
+	}
+
+	for _, test := range tests {
+		m := NewNormalMap(newSolidUVPattern(test.color), PlanarMapping)
+		normal := m.PerturbNormal(plane, NewPoint(1, 0, 2), NewVector(0, 1, 0))
+		assert.True(t, test.expected.Equals(normal), "%v: %v", test.color, normal)
+	}
+
+	// on a sphere, the tangent frame follows the direction of increasing u and v
+	sphere := NewSphere()
+	m := NewNormalMap(newSolidUVPattern(NewColor(1, .5, .5)), SphericalMapping)
+	normal = m.PerturbNormal(sphere, NewPoint(0, 0, -1), NewVector(0, 0, -1))
+	assert.True(t, NewVector(1, 0, 0).Equals(normal), normal.String())
+	m.UVPattern = newSolidUVPattern(NewColor(.5, 1, .5))
+	normal = m.PerturbNormal(sphere, NewPoint(0, 0, -1), NewVector(0, 0, -1))
+	assert.True(t, NewVector(0, 1, 0).Equals(normal), normal.String())
+
+	// the frame follows the object's transformation
+	sphere.SetTransform(NewRotationY(math.Pi / 2))
+	normal = m.PerturbNormal(sphere, NewPoint(-1, 0, 0), NewVector(-1, 0, 0))
+	assert.True(t, NewVector(0, 1, 0).Equals(normal), normal.String())
+
+	// zero strength leaves normals unchanged
+	m.UVPattern = newSolidUVPattern(NewColor(.5, 1, .75))
+	m.Strength = 0
+	normal = m.PerturbNormal(sphere, NewPoint(-1, 0, 0), NewVector(-1, 0, 0))
+	assert.True(t, NewVector(-1, 0, 0).Equals(normal), normal.String())
+
+	// at the poles, where there's no tangent, normals are unchanged
+	m.Strength = 1
+	normal = m.PerturbNormal(sphere, NewPoint(0, 1, 0), NewVector(0, 1, 0))
+	assert.True(t, NewVector(0, 1, 0).Equals(normal), normal.String())
+}
+
+func TestBumpMap_PerturbNormal(t *testing.T) {
+	plane := NewPlane()
+
+	// a constant height field leaves normals unchanged
+	m := NewBumpMap(NewSolidPattern(NewColor(.5, .5, .5)), 1)
+	normal := m.PerturbNormal(plane, NewPoint(.3, 0, .2), NewVector(0, 1, 0))
+	assert.True(t, NewVector(0, 1, 0).Equals(normal))
+
+	// a slope tilts normals away from the higher side
+	slope := NewGradientPattern(NewSolidPattern(black), NewSolidPattern(white))
+	m = NewBumpMap(slope, 1)
+	normal = m.PerturbNormal(plane, NewPoint(.3, 0, .2), NewVector(0, 1, 0))
+	assert.True(t, NewVector(-1, 1, 0).Normalize().Equals(normal), normal.String())
+
+	// scaling the height field scales the slope
+	m.Scale = .5
+	normal = m.PerturbNormal(plane, NewPoint(.3, 0, .2), NewVector(0, 1, 0))
+	assert.True(t, NewVector(-.5, 1, 0).Normalize().Equals(normal), normal.String())
+
+	// only the slope along the surface matters
+	slope.SetTransform(NewRotationZ(math.Pi / 2))
+	normal = m.PerturbNormal(plane, NewPoint(.3, 0, .2), NewVector(0, 1, 0))
+	assert.True(t, NewVector(0, 1, 0).Equals(normal), normal.String())
+}
+
+func TestIntersection_PrepareComputations_perturbed(t *testing.T) {
+	r := NewRay(NewPoint(0, 1, -1), NewVector(0, -1, 1).Normalize())
+	plane := NewPlane()
+	plane.Material.Perturber = NewNormalMap(newSolidUVPattern(NewColor(.5, 0, 1)), PlanarMapping)
+	info := NewIntersection(math.Sqrt2, plane).PrepareComputations(r)
+
+	// the normal tilts toward -Z, back toward the eye, and the reflection follows it
+	assert.True(t, NewVector(0, 1, -1).Normalize().Equals(info.NormalV), info.NormalV.String())
+	assert.True(t, NewVector(0, 1, -1).Normalize().Equals(info.ReflectV), info.ReflectV.String())
+	assert.False(t, info.Inside)
+
+	// the over point is still offset along the geometric normal
+	assert.True(t, NewPoint(0, EPSILON, 0).Equals(info.OverPoint))
+
+	// inside an object, the perturbed normal is flipped along with the geometric one
+	sphere := NewSphere()
+	sphere.Material.Perturber = NewNormalMap(newSolidUVPattern(NewColor(.5, .5, 1)), SphericalMapping)
+	info = NewIntersection(1, sphere).PrepareComputations(NewRay(Origin(), NewVector(0, 0, 1)))
+	assert.True(t, info.Inside)
+	assert.True(t, NewVector(0, 0, -1).Equals(info.NormalV))
+}
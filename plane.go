@@ -19,11 +19,11 @@ func NewPlane() *Plane {
 // Intersect returns a set of points where a ray intersects the plane.
 func (p *Plane) Intersect(ray *Ray) IntersectionSet {
 	return p.intersect(ray, func(localRay *Ray) IntersectionSet {
-		if math.Abs(ray.Direction.Y()) < EPSILON {
+		if math.Abs(localRay.Direction.Y()) < EPSILON {
 			return NewIntersectionSet()
 		}
 
-		t := -ray.Origin.Y() / ray.Direction.Y()
+		t := -localRay.Origin.Y() / localRay.Direction.Y()
 		return NewIntersectionSet(
 			NewIntersection(t, p),
 		)
@@ -1,6 +1,7 @@
 package rt
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -39,6 +40,17 @@ func TestPlane_Intersect(t *testing.T) {
 	assert.Len(t, xs, 1)
 	assert.Equal(t, 1.0, xs[0].T)
 	assert.Equal(t, p, xs[0].Object)
+
+	// ray intersects a transformed plane
+	p = NewPlane()
+	p.SetTransform(NewTranslation(1, 0, 0).CombineWith(NewRotationZ(math.Pi / 2)))
+	r = NewRay(NewPoint(-2, 0, 0), NewVector(1, 0, 0))
+	xs = p.Intersect(r)
+	assert.Len(t, xs, 1)
+	assert.True(t, eq(3, xs[0].T))
+
+	r = NewRay(NewPoint(0, -2, 0), NewVector(0, 1, 0))
+	assert.Empty(t, p.Intersect(r))
 }
 
 func TestPlane_NormalAt(t *testing.T) {
@@ -0,0 +1,121 @@
+package rt
+
+import (
+	"context"
+	"image"
+	"math/rand"
+	"time"
+)
+
+// ProgressiveBlockSize is the size of the blocks a progressive render first fills the image with.
+const ProgressiveBlockSize = 16
+
+// A ProgressiveUpdate describes the image after a pass of a progressive render.
+type ProgressiveUpdate struct {
+	// Pass counts the passes finished so far, starting at 1.
+	Pass int
+
+	// BlockSize is the size of the blocks the pass filled the image with, or 1 once the pass
+	// sampled every pixel.
+	BlockSize int
+
+	// Samples is the number of samples averaged into each pixel so far.
+	Samples int
+
+	// Image is a copy of the image so far.
+	Image   *Canvas
+	Elapsed time.Duration
+}
+
+// RenderProgressive renders the specified world a pass at a time, calling update (if not nil)
+// after each one. The first passes quickly fill the image with blocks, each colored by a single
+// ray, halving their size each pass from ProgressiveBlockSize. Then each pass adds the next of
+// the camera's samples of every pixel to a running average, until there are the specified number
+// of samples per pixel. The samples are the same ones Render takes, so once samples is the square
+// of the camera's Samples, the image is the same as Render's. Calls to update are never made
+// concurrently.
+//
+// If the context is canceled before the render is complete, RenderProgressive stops as soon as
+// the tiles in progress are finished and returns the image so far along with ctx.Err().
+func (c *Camera) RenderProgressive(ctx context.Context, world *World, samples int, update func(ProgressiveUpdate)) (*Canvas, error) {
+	canvas := NewCanvas(c.HSize, c.VSize)
+	sums := NewCanvas(c.HSize, c.VSize)
+	tiles := c.tiles()
+	indices := make([]int, len(tiles))
+	for i := range tiles {
+		indices[i] = i
+	}
+
+	integrator := c.integrator()
+	sampler := c.sampler()
+	start := time.Now()
+	pass := 0
+
+	// renders a pass over every tile
+	renderPass := func(blockSize int, samples int, render func(rng *rand.Rand, block image.Rectangle)) bool {
+		tilesDone := c.forEachTile(ctx, indices, func(i int) {
+			rng := newSampleRand()
+			tile := tiles[i]
+			for y := tile.Min.Y; y < tile.Max.Y; y += blockSize {
+				for x := tile.Min.X; x < tile.Max.X; x += blockSize {
+					render(rng, image.Rect(x, y, x+blockSize, y+blockSize).Intersect(tile))
+				}
+			}
+		}, nil)
+
+		if tilesDone < len(tiles) {
+			return false
+		}
+
+		pass++
+		if update != nil {
+			update(ProgressiveUpdate{
+				Pass:      pass,
+				BlockSize: blockSize,
+				Samples:   samples,
+				Image:     canvas.clone(),
+				Elapsed:   time.Since(start),
+			})
+		}
+
+		return true
+	}
+
+	for size := ProgressiveBlockSize; size > 1; size /= 2 {
+		finished := renderPass(size, 0, func(rng *rand.Rand, block image.Rectangle) {
+			// the first sample of the pixel in the middle of the block colors all of it
+			ray := c.sampleRay(sampler, rng, nil, (block.Min.X+block.Max.X)/2, (block.Min.Y+block.Max.Y)/2, 0, 1)
+			color := integrator.Radiance(world, ray, rng)
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				for x := block.Min.X; x < block.Max.X; x++ {
+					canvas.WritePixel(x, y, color)
+				}
+			}
+		})
+
+		if !finished {
+			return canvas, ctx.Err()
+		}
+	}
+
+	for n := 1; n <= samples; n++ {
+		n := n
+		finished := renderPass(1, n, func(rng *rand.Rand, pixel image.Rectangle) {
+			x, y := pixel.Min.X, pixel.Min.Y
+			ray := c.sampleRay(sampler, rng, nil, x, y, n-1, samples)
+			sum := integrator.Radiance(world, ray, rng)
+			if n > 1 {
+				sum = sum.Add(sums.PixelAt(x, y))
+			}
+
+			sums.WritePixel(x, y, sum)
+			canvas.WritePixel(x, y, sum.Multiply(1/float64(n)))
+		})
+
+		if !finished {
+			return canvas, ctx.Err()
+		}
+	}
+
+	return canvas, nil
+}
@@ -0,0 +1,74 @@
+package rt
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamera_RenderProgressive(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(21, 21, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.Threads = 3
+	c.TileSize = 8
+
+	var updates []ProgressiveUpdate
+	image, err := c.RenderProgressive(context.Background(), w, 16, func(u ProgressiveUpdate) {
+		updates = append(updates, u)
+	})
+	require.NoError(t, err)
+
+	// blocks of 16, 8, 4, and 2 pixels, then a pass for each sample
+	require.Len(t, updates, 20)
+	for i, u := range updates {
+		assert.Equal(t, i+1, u.Pass)
+		if i < 4 {
+			assert.Equal(t, 16>>i, u.BlockSize)
+			assert.Zero(t, u.Samples)
+		} else {
+			assert.Equal(t, 1, u.BlockSize)
+			assert.Equal(t, i-3, u.Samples)
+		}
+	}
+
+	// the first pass colors each block by the ray through its middle
+	assert.Equal(t, updates[0].Image.PixelAt(0, 0), updates[0].Image.PixelAt(7, 7))
+	assert.True(t, updates[0].Image.PixelAt(4, 4).Equals(c.Render(w).PixelAt(4, 4)))
+
+	// the image converges on a supersampled render
+	assert.Equal(t, image, updates[19].Image)
+	c.Samples = 4
+	reference := c.Render(w)
+	assert.Less(t, meanSquaredError(updates[19].Image, reference), meanSquaredError(updates[4].Image, reference)/4)
+
+	// and doesn't depend on how tiles are scheduled
+	c.Threads = 1
+	again, err := c.RenderProgressive(context.Background(), w, 16, nil)
+	require.NoError(t, err)
+	assert.Equal(t, image, again)
+}
+
+func TestCamera_RenderProgressive_canceled(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+
+	// cancel after the first sampling pass
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	passes := 0
+	image, err := c.RenderProgressive(ctx, w, 8, func(u ProgressiveUpdate) {
+		passes++
+		if u.Samples == 1 {
+			cancel()
+		}
+	})
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 5, passes)
+	assert.NotNil(t, image.PixelAt(10, 10))
+}
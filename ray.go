@@ -4,11 +4,22 @@ package rt
 type Ray struct {
 	Origin    Tuple
 	Direction Tuple
+
+	// Time is the moment the ray is traced at, which places moving shapes.
+	Time float64
+
+	// stats, if not nil, counts the work done tracing the ray
+	stats *RenderStats
 }
 
-// NewRay creates a new Ray.
+// NewRay creates a new Ray at time 0.
 func NewRay(origin Tuple, direction Tuple) *Ray {
-	return &Ray{origin, direction}
+	return &Ray{Origin: origin, Direction: direction}
+}
+
+// NewRayAt creates a new Ray at the given time.
+func NewRayAt(origin Tuple, direction Tuple, time float64) *Ray {
+	return &Ray{Origin: origin, Direction: direction, Time: time}
 }
 
 // Position returns the point on the ray at distance t.
@@ -18,5 +29,5 @@ func (r *Ray) Position(t float64) Tuple {
 
 // Transform applies the specified transformation matrix and returns a new ray.
 func (r *Ray) Transform(t Transformation) *Ray {
-	return NewRay(t.ApplyTo(r.Origin), t.ApplyTo(r.Direction))
+	return &Ray{t.ApplyTo(r.Origin), t.ApplyTo(r.Direction), r.Time, r.stats}
 }
@@ -12,6 +12,10 @@ func TestNewRay(t *testing.T) {
 	r := NewRay(origin, direction)
 	assert.True(t, r.Origin.Equals(origin))
 	assert.True(t, r.Direction.Equals(direction))
+	assert.Zero(t, r.Time)
+
+	r = NewRayAt(origin, direction, .25)
+	assert.Equal(t, .25, r.Time)
 }
 
 func TestRay_Position(t *testing.T) {
@@ -36,4 +40,8 @@ func TestRay_Transform(t *testing.T) {
 	r2 = r1.Transform(m)
 	assert.Equal(t, NewPoint(2, 6, 12), r2.Origin)
 	assert.Equal(t, NewVector(0, 3, 0), r2.Direction)
+
+	// the ray keeps its time
+	r2 = NewRayAt(NewPoint(1, 2, 3), NewVector(0, 1, 0), .5).Transform(m)
+	assert.Equal(t, .5, r2.Time)
 }
@@ -0,0 +1,323 @@
+package rt
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RenderProgress describes how far along a render is.
+type RenderProgress struct {
+	TilesDone  int
+	TilesTotal int
+	Elapsed    time.Duration
+
+	// ETA is the estimated time remaining, based on the average time per tile so far.
+	ETA time.Duration
+
+	// RaysPerSecond is the number of camera rays traced per second so far.
+	RaysPerSecond float64
+
+	// Stats counts the work done by the tiles finished so far, and goes on changing until the
+	// render returns.
+	Stats *RenderStats
+}
+
+// Render renders the specified world.
+func (c *Camera) Render(world *World) *Canvas {
+	image, _ := c.RenderContext(context.Background(), world, nil)
+	return image
+}
+
+// RenderContext renders the specified world one tile at a time, calling progress (if not nil)
+// each time a tile is finished. Calls to progress are never made concurrently.
+//
+// If the context is canceled before the render is complete, RenderContext stops as soon as the
+// tiles in progress are finished and returns the partially rendered canvas along with ctx.Err().
+func (c *Camera) RenderContext(ctx context.Context, world *World, progress func(RenderProgress)) (*Canvas, error) {
+	return c.render(ctx, world, renderOptions{}, progress)
+}
+
+// RenderWithStats renders the specified world as RenderContext does, and returns statistics
+// about the work it took.
+func (c *Camera) RenderWithStats(ctx context.Context, world *World, progress func(RenderProgress)) (*Canvas, *RenderStats, error) {
+	stats := NewRenderStats()
+	canvas, err := c.render(ctx, world, renderOptions{stats: stats}, progress)
+	return canvas, stats, err
+}
+
+// RenderRegion renders only the pixels within a rectangle of the image (see CropWindow), as
+// RenderContext does. The pixels are written to a canvas the size of the image, which may be a
+// prior render to update, leaving its other pixels as they are. If canvas is nil, a new canvas
+// is used, with its other pixels unset; see Canvas.Crop.
+//
+// Since each pixel's samples are seeded by the pixel, a region's pixels are the same as they are
+// in a full render.
+func (c *Camera) RenderRegion(ctx context.Context, world *World, region image.Rectangle, canvas *Canvas, progress func(RenderProgress)) (*Canvas, error) {
+	if canvas != nil && (canvas.width != c.HSize || canvas.height != c.VSize) {
+		return nil, fmt.Errorf("canvas is %dx%d, not %dx%d like the image", canvas.width, canvas.height, c.HSize, c.VSize)
+	}
+
+	clipped := region.Intersect(image.Rect(0, 0, c.HSize, c.VSize))
+	if clipped.Empty() {
+		return nil, fmt.Errorf("region %v is outside the %dx%d image", region, c.HSize, c.VSize)
+	}
+
+	return c.render(ctx, world, renderOptions{canvas: canvas, region: clipped}, progress)
+}
+
+// CropWindow returns the rectangle of pixels covering a window of the image, whose edges are given
+// as fractions of the image's width and height from its top left corner.
+func (c *Camera) CropWindow(minX float64, minY float64, maxX float64, maxY float64) image.Rectangle {
+	return image.Rect(
+		int(math.Floor(minX*float64(c.HSize))), int(math.Floor(minY*float64(c.VSize))),
+		int(math.Ceil(maxX*float64(c.HSize))), int(math.Ceil(maxY*float64(c.VSize))),
+	).Intersect(image.Rect(0, 0, c.HSize, c.VSize))
+}
+
+// Options for a render.
+type renderOptions struct {
+	// passes, if not nil, are rendered along with the image
+	passes *renderPasses
+
+	// checkpoint, if not nil, holds the tiles already finished and records the rest
+	checkpoint *checkpointer
+
+	// canvas, if not nil, is rendered into instead of a new canvas
+	canvas *Canvas
+
+	// region, if not empty, is the only part of the image rendered
+	region image.Rectangle
+
+	// stats, if not nil, counts the render's work
+	stats *RenderStats
+}
+
+// Renders the world with the options, as RenderContext does.
+func (c *Camera) render(ctx context.Context, world *World, options renderOptions, progress func(RenderProgress)) (*Canvas, error) {
+	canvas := options.canvas
+	if canvas == nil {
+		canvas = NewCanvas(c.HSize, c.VSize)
+	}
+
+	passes, checkpoint := options.passes, options.checkpoint
+	tiles := c.tiles()
+	pending := make([]int, 0, len(tiles))
+	resumed := 0
+	for i := range tiles {
+		if !options.region.Empty() {
+			if tiles[i] = tiles[i].Intersect(options.region); tiles[i].Empty() {
+				continue
+			}
+		}
+
+		if checkpoint != nil && checkpoint.restore(canvas, tiles[i], i) {
+			resumed++
+			continue
+		}
+
+		pending = append(pending, i)
+	}
+
+	if checkpoint != nil {
+		var cancel func()
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		checkpoint.cancel = cancel
+	}
+
+	stats := options.stats
+	if stats == nil {
+		stats = NewRenderStats()
+	}
+
+	// each tile counts its work in its own stats, which are merged when it's finished
+	tileStats := make([]*RenderStats, len(tiles))
+	start := time.Now()
+	tilesDone := c.forEachTile(ctx, pending, func(i int) {
+		tileStats[i] = c.renderTile(world, canvas, passes, tiles[i])
+	}, func(i int, tilesDone int) {
+		if checkpoint != nil {
+			checkpoint.record(canvas, tiles[i], i)
+		}
+
+		stats.Merge(tileStats[i])
+		if progress != nil {
+			elapsed := time.Since(start)
+			progress(RenderProgress{
+				TilesDone:     resumed + tilesDone,
+				TilesTotal:    resumed + len(pending),
+				Elapsed:       elapsed,
+				ETA:           elapsed * time.Duration(len(pending)-tilesDone) / time.Duration(tilesDone),
+				RaysPerSecond: float64(stats.PrimaryRays) / elapsed.Seconds(),
+				Stats:         stats,
+			})
+		}
+	})
+
+	var err error
+	if checkpoint != nil {
+		err = checkpoint.finish()
+	}
+
+	if err == nil && tilesDone < len(pending) {
+		err = ctx.Err()
+	}
+
+	return canvas, err
+}
+
+// Calls render with each of the indices of tiles, on the camera's threads, and then done (if not
+// nil) with the index and the number of tiles finished so far. Calls to done are never made
+// concurrently. If the context is canceled, no more tiles are started, and forEachTile returns
+// once the tiles in progress are finished. Returns the number of tiles finished.
+func (c *Camera) forEachTile(ctx context.Context, tiles []int, render func(i int), done func(i int, tilesDone int)) int {
+	queue := make(chan int, len(tiles))
+	for _, i := range tiles {
+		queue <- i
+	}
+
+	close(queue)
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	tilesDone := 0
+	for i := 0; i < c.threads(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+
+				render(i)
+
+				mutex.Lock()
+				tilesDone++
+				if done != nil {
+					done(i, tilesDone)
+				}
+
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	return tilesDone
+}
+
+// Renders the pixels within a tile of the canvas, returning the work it took.
+func (c *Camera) renderTile(world *World, canvas *Canvas, passes *renderPasses, tile image.Rectangle) *RenderStats {
+	start := time.Now()
+	stats := NewRenderStats()
+	rng := newSampleRand()
+	integrator := c.integrator()
+	sampler := c.sampler()
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			if passes == nil {
+				canvas.WritePixel(x, y, c.colorAtPixel(world, integrator, sampler, passes, rng, stats, x, y))
+				continue
+			}
+
+			passes.renderPixel(c, world, x, y)
+			pixelStart, tests := time.Now(), stats.TotalIntersectionTests()
+			canvas.WritePixel(x, y, c.colorAtPixel(world, integrator, sampler, passes, rng, stats, x, y))
+			passes.writeCost(x, y, stats.TotalIntersectionTests()-tests, time.Since(pixelStart).Nanoseconds())
+		}
+	}
+
+	stats.Tiles = []TileTime{{tile, time.Since(start)}}
+	return stats
+}
+
+// Returns the color of a pixel by averaging the samples the sampler chooses across it, adding
+// each sample to the passes, if any.
+func (c *Camera) colorAtPixel(world *World, integrator Integrator, sampler Sampler, passes *renderPasses, rng *rand.Rand, stats *RenderStats, x int, y int) Color {
+	n := c.Samples * c.Samples
+	if n < 1 {
+		n = 1
+	}
+
+	color := NewColor(0, 0, 0)
+	for i := 0; i < n; i++ {
+		ray := c.sampleRay(sampler, rng, stats, x, y, i, n)
+		if passes != nil {
+			color = color.Add(passes.renderSample(world, integrator, ray, rng, x, y, 1/float64(n)))
+		} else {
+			color = color.Add(integrator.Radiance(world, ray, rng))
+		}
+	}
+
+	return color.Multiply(1 / float64(n))
+}
+
+// Returns the ray for sample i of n of pixel x, y, seeding rng for tracing it and counting the
+// work of tracing it in stats.
+func (c *Camera) sampleRay(sampler Sampler, rng *rand.Rand, stats *RenderStats, x int, y int, i int, n int) *Ray {
+	dx, dy, t := sampler.Sample(c.Seed, x, y, i, n)
+	c.seedSample(rng, x, y, i)
+	ray := c.rayForPixelOffset(x, y, dx, dy)
+	ray.Time = c.shutterTime(t)
+	ray.stats = stats
+	stats.addPrimaryRay()
+	return ray
+}
+
+// Returns the time a fraction of the way through the shutter interval, or the time the shutter
+// opens if it doesn't stay open.
+func (c *Camera) shutterTime(fraction float64) float64 {
+	if c.ShutterClose <= c.ShutterOpen {
+		return c.ShutterOpen
+	}
+
+	return c.ShutterOpen + fraction*(c.ShutterClose-c.ShutterOpen)
+}
+
+// Returns the integrator to render with.
+func (c *Camera) integrator() Integrator {
+	if c.Integrator != nil {
+		return c.Integrator
+	}
+
+	return NewWhittedIntegrator()
+}
+
+// Returns the number of goroutines to render with.
+func (c *Camera) threads() int {
+	if c.Threads > 0 {
+		return c.Threads
+	}
+
+	return runtime.NumCPU()
+}
+
+// Returns the width and height of the tiles the image is divided into.
+func (c *Camera) tileSize() int {
+	if c.TileSize > 0 {
+		return c.TileSize
+	}
+
+	return DefaultTileSize
+}
+
+// Returns the tiles covering the image, in rows from top to bottom.
+func (c *Camera) tiles() []image.Rectangle {
+	size := c.tileSize()
+	bounds := image.Rect(0, 0, c.HSize, c.VSize)
+	tiles := []image.Rectangle{}
+	for y := 0; y < c.VSize; y += size {
+		for x := 0; x < c.HSize; x += size {
+			tiles = append(tiles, image.Rect(x, y, x+size, y+size).Intersect(bounds))
+		}
+	}
+
+	return tiles
+}
@@ -0,0 +1,173 @@
+package rt
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamera_Render(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	from := NewPoint(0, 0, -5)
+	to := Origin()
+	up := NewVector(0, 1, 0)
+	c.Transform = NewViewTransform(from, to, up)
+	image := c.Render(w)
+	assert.True(t, image.PixelAt(5, 5).Equals(NewColor(.38066, .47583, .2855)))
+}
+
+func TestCamera_Render_samples(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	single := c.Render(w)
+
+	c.Samples = 3
+	multi := c.Render(w)
+
+	// the middle sample of each pixel is the single-sample ray, so a pixel entirely
+	// covered by a flat-colored region is unchanged
+	assert.True(t, multi.PixelAt(0, 0).Equals(single.PixelAt(0, 0)))
+
+	// but pixels along the edge of the sphere are blended with the background
+	blended := 0
+	for y := 0; y < 11; y++ {
+		for x := 0; x < 11; x++ {
+			if !multi.PixelAt(x, y).Equals(single.PixelAt(x, y)) {
+				blended++
+			}
+		}
+	}
+
+	assert.NotZero(t, blended)
+}
+
+func TestCamera_Render_motionBlur(t *testing.T) {
+	w := NewWorld()
+	w.Light = NewPointLight(NewPoint(0, 0, -10), NewColor(1, 1, 1))
+	s := NewSphere()
+	s.Material.Ambient = 1
+	s.Material.Diffuse = 0
+	s.Material.Specular = 0
+	motion, err := NewMotion(NewTranslation(-1, 0, 0), NewTranslation(1, 0, 0))
+	require.NoError(t, err)
+	s.SetMotion(motion)
+	w.AddObjects(s)
+
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.Samples = 4
+
+	// with the shutter closed, the sphere is sharp where it starts
+	still := c.Render(w)
+	assert.True(t, still.PixelAt(4, 5).Equals(white))
+	assert.True(t, still.PixelAt(6, 5).Equals(black))
+
+	// but with it open, pixels the sphere passes over are partly covered
+	c.ShutterClose = 1
+	blurred := c.Render(w)
+	for _, x := range []int{4, 6} {
+		color := blurred.PixelAt(x, 5)
+		assert.True(t, color.Red() > .1 && color.Red() < .9, "pixel %d is %v", x, color)
+	}
+}
+
+func TestCamera_RenderContext(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.Threads = 3
+	c.TileSize = 4
+
+	calls := 0
+	image, err := c.RenderContext(context.Background(), w, func(p RenderProgress) {
+		calls++
+		assert.Equal(t, calls, p.TilesDone)
+		assert.Equal(t, 9, p.TilesTotal)
+		assert.True(t, p.RaysPerSecond > 0)
+		if p.TilesDone == p.TilesTotal {
+			assert.Zero(t, p.ETA)
+		}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 9, calls)
+	assert.True(t, image.PixelAt(5, 5).Equals(NewColor(.38066, .47583, .2855)))
+}
+
+func TestCamera_RenderContext_canceled(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.Threads = 1
+	c.TileSize = 4
+
+	// cancel after the first tile is finished
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	image, err := c.RenderContext(ctx, w, func(p RenderProgress) {
+		cancel()
+	})
+
+	assert.Equal(t, context.Canceled, err)
+	assert.NotNil(t, image.PixelAt(3, 3))
+	assert.Nil(t, image.PixelAt(4, 0))
+	assert.Nil(t, image.PixelAt(10, 10))
+}
+
+func TestCamera_RenderRegion(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.TileSize = 4
+	full := c.Render(w)
+
+	// only the region is traced
+	region := image.Rect(3, 2, 9, 6)
+	calls := 0
+	canvas, err := c.RenderRegion(context.Background(), w, region, nil, func(p RenderProgress) {
+		calls++
+		assert.Equal(t, 6, p.TilesTotal)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 6, calls)
+	assert.Nil(t, canvas.PixelAt(2, 2))
+	assert.Nil(t, canvas.PixelAt(9, 5))
+	assert.Equal(t, full.Crop(region), canvas.Crop(region))
+
+	// a region can be rendered into a prior render, updating just its pixels
+	prior := full.clone()
+	w.Objects[0].GetMaterial().Color = NewColor(1, 0, 0)
+	updated, err := c.RenderRegion(context.Background(), w, region, prior, nil)
+	require.NoError(t, err)
+	assert.Same(t, prior, updated)
+	assert.Equal(t, full.PixelAt(5, 8), updated.PixelAt(5, 8))
+	assert.Equal(t, c.Render(w).Crop(region), updated.Crop(region))
+
+	_, err = c.RenderRegion(context.Background(), w, region, NewCanvas(4, 4), nil)
+	assert.EqualError(t, err, "canvas is 4x4, not 11x11 like the image")
+	_, err = c.RenderRegion(context.Background(), w, image.Rect(20, 20, 30, 30), nil, nil)
+	assert.EqualError(t, err, "region (20,20)-(30,30) is outside the 11x11 image")
+}
+
+func TestCamera_CropWindow(t *testing.T) {
+	c := NewCamera(10, 5, math.Pi/2)
+	assert.Equal(t, image.Rect(2, 1, 8, 4), c.CropWindow(.25, .25, .75, .75))
+	assert.Equal(t, image.Rect(0, 0, 10, 5), c.CropWindow(-1, 0, 2, 1))
+}
+
+func TestCamera_tiles(t *testing.T) {
+	c := NewCamera(10, 5, math.Pi/2)
+	c.TileSize = 4
+	assert.Equal(t, []image.Rectangle{
+		image.Rect(0, 0, 4, 4), image.Rect(4, 0, 8, 4), image.Rect(8, 0, 10, 4),
+		image.Rect(0, 4, 4, 5), image.Rect(4, 4, 8, 5), image.Rect(8, 4, 10, 5),
+	}, c.tiles())
+
+	c.TileSize = 0
+	assert.Equal(t, []image.Rectangle{image.Rect(0, 0, 10, 5)}, c.tiles())
+}
@@ -0,0 +1,233 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// A Sampler chooses a pixel's camera samples: where in the pixel each of its rays passes, and
+// when while the shutter is open it's traced. A sample depends only on the seed, its pixel, and
+// its index, so it's the same however the image is divided among threads, tiles, or workers.
+type Sampler interface {
+	// Sample returns the offset of sample i of n within pixel x, y from the pixel's corner, and
+	// the fraction of the shutter interval at which it's traced, each between 0 and 1.
+	Sample(seed int64, x int, y int, i int, n int) (dx float64, dy float64, t float64)
+}
+
+// The dimensions of a pixel's samples, each of which is scrambled with its own hash of the pixel.
+const (
+	sampleCells = iota
+	sampleSlices
+	sampleJitter
+	sampleShifts
+	sampleRadiance
+)
+
+// A StratifiedSampler divides each pixel into a grid of cells, one per sample, and traces each
+// sample through the middle of its cell. A sample count that isn't square leaves some cells of
+// the smallest grid that fits empty, chosen at random. The shutter interval is divided the same
+// way, with each sample traced at a random time within its slice and the slices shuffled across
+// the cells.
+type StratifiedSampler struct{}
+
+// NewStratifiedSampler creates a new StratifiedSampler.
+func NewStratifiedSampler() *StratifiedSampler {
+	return &StratifiedSampler{}
+}
+
+// Sample returns the offset and shutter time of sample i of n within pixel x, y.
+func (s *StratifiedSampler) Sample(seed int64, x int, y int, i int, n int) (float64, float64, float64) {
+	m := 1
+	for m*m < n {
+		m++
+	}
+
+	cell := permute(i, m*m, sampleHash(seed, x, y, sampleCells))
+	slice := permute(i, n, sampleHash(seed, x, y, sampleSlices))
+	jitter := hashFloat(sampleHash(seed, x, y, sampleJitter, i))
+	return (float64(cell%m) + .5) / float64(m), (float64(cell/m) + .5) / float64(m), (float64(slice) + jitter) / float64(n)
+}
+
+// A HaltonSampler takes each pixel's samples from the Halton sequence in bases 2, 3, and 5, a
+// low-discrepancy sequence that covers the pixel and the shutter interval evenly however many
+// samples are taken. The sequence is shifted by a random amount in each pixel (a Cranley-
+// Patterson rotation), so neighboring pixels don't share the same pattern.
+type HaltonSampler struct{}
+
+// NewHaltonSampler creates a new HaltonSampler.
+func NewHaltonSampler() *HaltonSampler {
+	return &HaltonSampler{}
+}
+
+// Sample returns the offset and shutter time of sample i within pixel x, y.
+func (s *HaltonSampler) Sample(seed int64, x int, y int, i int, n int) (float64, float64, float64) {
+	var values [3]float64
+	for d, base := range [...]int{2, 3, 5} {
+		shift := hashFloat(sampleHash(seed, x, y, sampleShifts, d))
+		_, values[d] = math.Modf(radicalInverse(base, i) + shift)
+	}
+
+	return values[0], values[1], values[2]
+}
+
+// NewSampler creates a sampler by name: "stratified" or "halton".
+func NewSampler(name string) (Sampler, error) {
+	switch name {
+	case "stratified":
+		return NewStratifiedSampler(), nil
+	case "halton":
+		return NewHaltonSampler(), nil
+	}
+
+	return nil, fmt.Errorf("unknown sampler '%s'", name)
+}
+
+// Returns the name of a sampler.
+func samplerName(sampler Sampler) (string, error) {
+	switch sampler.(type) {
+	case *StratifiedSampler:
+		return "stratified", nil
+	case *HaltonSampler:
+		return "halton", nil
+	}
+
+	return "", fmt.Errorf("unsupported sampler type %T", sampler)
+}
+
+// Returns the sampler to render with.
+func (c *Camera) sampler() Sampler {
+	if c.Sampler != nil {
+		return c.Sampler
+	}
+
+	return NewStratifiedSampler()
+}
+
+// Returns a source of random numbers for tracing samples, to be seeded for each one by seedSample.
+func newSampleRand() *rand.Rand {
+	return rand.New(&pcgSource{})
+}
+
+// Seeds a source of random numbers for tracing sample i of pixel x, y, so that the integrator
+// draws the same numbers for the sample whenever it's traced.
+func (c *Camera) seedSample(rng *rand.Rand, x int, y int, i int) {
+	rng.Seed(int64(sampleHash(c.Seed, x, y, sampleRadiance, i)))
+}
+
+// A pcgSource is a rand.Source64 that generates numbers with a PCG generator (PCG-XSH-RR), which
+// is small enough to reseed for every sample.
+type pcgSource struct {
+	state uint64
+}
+
+const (
+	pcgMultiplier = 6364136223846793005
+	pcgIncrement  = 1442695040888963407
+)
+
+// Seed starts the generator from a seed.
+func (p *pcgSource) Seed(seed int64) {
+	p.state = 0
+	p.next()
+	p.state += uint64(seed)
+	p.next()
+}
+
+// Returns the next 32 bits from the generator.
+func (p *pcgSource) next() uint32 {
+	old := p.state
+	p.state = old*pcgMultiplier + pcgIncrement
+	xorShifted := uint32(((old >> 18) ^ old) >> 27)
+	rotation := uint32(old >> 59)
+	return xorShifted>>rotation | xorShifted<<((-rotation)&31)
+}
+
+// Uint64 returns the next 64 bits from the generator.
+func (p *pcgSource) Uint64() uint64 {
+	return uint64(p.next())<<32 | uint64(p.next())
+}
+
+// Int63 returns the next 63 bits from the generator, as a non-negative int64.
+func (p *pcgSource) Int63() int64 {
+	return int64(p.Uint64() >> 1)
+}
+
+// Returns a hash of a seed, a pixel, and the values identifying one of its samples.
+func sampleHash(seed int64, x int, y int, values ...int) uint64 {
+	h := mix64(uint64(seed))
+	h = mix64(h ^ uint64(x))
+	h = mix64(h ^ uint64(y))
+	for _, v := range values {
+		h = mix64(h ^ uint64(v))
+	}
+
+	return h
+}
+
+// Scrambles the bits of a 64-bit value (the finalizer of SplitMix64).
+func mix64(h uint64) uint64 {
+	h += 0x9e3779b97f4a7c15
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+// Returns a number between 0 and 1 made from the high bits of a hash.
+func hashFloat(h uint64) float64 {
+	return float64(h>>11) / (1 << 53)
+}
+
+// Returns the element at i of a random permutation of the numbers less than n, chosen by a hash,
+// without computing the rest of the permutation (Kensler's permutation from "Correlated
+// Multi-Jittered Sampling").
+func permute(i int, n int, hash uint64) int {
+	p := uint32(hash)
+	l := uint32(n)
+	w := l - 1
+	w |= w >> 1
+	w |= w >> 2
+	w |= w >> 4
+	w |= w >> 8
+	w |= w >> 16
+
+	// the hash is a bijection on the numbers up to the next power of two, applied until the
+	// result is in range
+	v := uint32(i)
+	for {
+		v ^= p
+		v *= 0xe170893d
+		v ^= p >> 16
+		v ^= (v & w) >> 4
+		v ^= p >> 8
+		v *= 0x0929eb3f
+		v ^= p >> 23
+		v ^= (v & w) >> 1
+		v *= 1 | p>>27
+		v *= 0x6935fa69
+		v ^= (v & w) >> 11
+		v *= 0x74dcb303
+		v ^= (v & w) >> 2
+		v *= 0x9e501cc3
+		v ^= (v & w) >> 2
+		v *= 0xc860a3df
+		v &= w
+		v ^= v >> 5
+		if v < l {
+			break
+		}
+	}
+
+	return int((v + p) % l)
+}
+
+// Returns the radical inverse of i in a base: its digits mirrored about the decimal point.
+func radicalInverse(base int, i int) float64 {
+	inverse, scale := 0.0, 1.0
+	for ; i > 0; i /= base {
+		scale /= float64(base)
+		inverse += float64(i%base) * scale
+	}
+
+	return inverse
+}
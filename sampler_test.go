@@ -0,0 +1,148 @@
+package rt
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermute(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 16, 100} {
+		for hash := uint64(0); hash < 4; hash++ {
+			seen := map[int]bool{}
+			for i := 0; i < n; i++ {
+				v := permute(i, n, mix64(hash))
+				assert.True(t, v >= 0 && v < n)
+				seen[v] = true
+			}
+
+			assert.Len(t, seen, n, "permutation of %d", n)
+		}
+	}
+}
+
+func TestRadicalInverse(t *testing.T) {
+	assert.Equal(t, []float64{0, .5, .25, .75}, []float64{
+		radicalInverse(2, 0), radicalInverse(2, 1), radicalInverse(2, 2), radicalInverse(2, 3),
+	})
+	assert.InDelta(t, 1.0/3+2.0/9, radicalInverse(3, 7), 1e-12)
+}
+
+func TestPCGSource(t *testing.T) {
+	rng := newSampleRand()
+	rng.Seed(42)
+	first := []float64{rng.Float64(), rng.Float64(), rng.Float64()}
+	assert.NotEqual(t, first[0], first[1])
+
+	// reseeding repeats the numbers
+	rng.Seed(42)
+	assert.Equal(t, first, []float64{rng.Float64(), rng.Float64(), rng.Float64()})
+	rng.Seed(43)
+	assert.NotEqual(t, first[0], rng.Float64())
+}
+
+func TestStratifiedSampler(t *testing.T) {
+	s := NewStratifiedSampler()
+
+	// a single sample is traced through the middle of the pixel
+	dx, dy, _ := s.Sample(0, 3, 4, 0, 1)
+	assert.Equal(t, []float64{.5, .5}, []float64{dx, dy})
+
+	// a square number of samples covers every cell of the grid, and every slice of the shutter
+	cells, slices := map[[2]float64]bool{}, map[int]bool{}
+	for i := 0; i < 9; i++ {
+		dx, dy, time := s.Sample(7, 3, 4, i, 9)
+		cells[[2]float64{dx, dy}] = true
+		slices[int(time*9)] = true
+		var again [3]float64
+		again[0], again[1], again[2] = s.Sample(7, 3, 4, i, 9)
+		assert.Equal(t, [3]float64{dx, dy, time}, again)
+	}
+
+	assert.Len(t, cells, 9)
+	assert.True(t, cells[[2]float64{.5 / 3, .5 / 3}])
+	assert.True(t, cells[[2]float64{2.5 / 3, 2.5 / 3}])
+	assert.Len(t, slices, 9)
+
+	// other counts use distinct cells of the smallest grid that fits
+	cells = map[[2]float64]bool{}
+	for i := 0; i < 5; i++ {
+		dx, dy, _ := s.Sample(7, 3, 4, i, 5)
+		cells[[2]float64{dx, dy}] = true
+	}
+
+	assert.Len(t, cells, 5)
+}
+
+func TestHaltonSampler(t *testing.T) {
+	s := NewHaltonSampler()
+
+	// each pixel's sequence is shifted by its own amount, so consecutive samples are still spread
+	// evenly across the pixel
+	dx0, dy0, t0 := s.Sample(0, 0, 0, 0, 4)
+	dx1, dy1, t1 := s.Sample(0, 0, 0, 1, 4)
+	assert.InDelta(t, .5, math.Abs(dx1-dx0), 1e-12)
+	assert.True(t, dy0 != dy1 && t0 != t1)
+	for _, v := range []float64{dx0, dy0, t0, dx1, dy1, t1} {
+		assert.True(t, v >= 0 && v < 1)
+	}
+
+	other, _, _ := s.Sample(0, 1, 0, 0, 4)
+	assert.NotEqual(t, dx0, other)
+}
+
+// Returns a camera that path traces the Cornell box with motion blur, so that every sample draws
+// random numbers.
+func randomCamera() (*Camera, *World) {
+	w, c := newCornellBox(12)
+	c.ShutterClose = 1
+	c.Samples = 2
+	return c, w
+}
+
+func TestCamera_Render_seeded(t *testing.T) {
+	c, w := randomCamera()
+	c.Threads = 1
+	c.TileSize = 3
+	image := c.Render(w)
+
+	// the image doesn't depend on the threads or tiles it's rendered with
+	c.Threads = 4
+	c.TileSize = 5
+	assert.Equal(t, image, c.Render(w))
+
+	// but does on the seed
+	c.Seed = 1
+	assert.NotEqual(t, image, c.Render(w))
+
+	// as it does for the Halton sampler
+	c.Sampler = NewHaltonSampler()
+	halton := c.Render(w)
+	assert.NotEqual(t, image, halton)
+	c.TileSize = 16
+	assert.Equal(t, halton, c.Render(w))
+}
+
+func TestCamera_RenderRegion_seeded(t *testing.T) {
+	c, w := randomCamera()
+	c.TileSize = 4
+
+	// a region that cuts across tiles matches the full render
+	region := image.Rect(3, 2, 9, 7)
+	canvas, err := c.RenderRegion(context.Background(), w, region, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, c.Render(w).Crop(region), canvas.Crop(region))
+}
+
+func TestCamera_RenderProgressive_seeded(t *testing.T) {
+	c, w := randomCamera()
+
+	// once every sample is taken, the image is the same as a render's
+	image, err := c.RenderProgressive(context.Background(), w, c.Samples*c.Samples, nil)
+	require.NoError(t, err)
+	assert.Equal(t, c.Render(w), image)
+}
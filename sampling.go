@@ -0,0 +1,50 @@
+package rt
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Returns two unit vectors that together with the unit vector n form an orthonormal basis.
+func orthonormalBasis(n Tuple) (tangent Tuple, bitangent Tuple) {
+	// from "Building an Orthonormal Basis, Revisited" by Duff et al.
+	sign := math.Copysign(1, n.Z())
+	a := -1 / (sign + n.Z())
+	b := n.X() * n.Y() * a
+	tangent = NewVector(1+sign*n.X()*n.X()*a, sign*b, -sign*n.X())
+	bitangent = NewVector(b, sign+n.Y()*n.Y()*a, -n.Y())
+	return tangent, bitangent
+}
+
+// Returns a random direction in the hemisphere around the unit vector normal, chosen with a
+// probability proportional to the cosine of its angle with the normal, which is cos / π.
+func cosineSampleHemisphere(normal Tuple, rng *rand.Rand) Tuple {
+	// pick a point on the unit disk and project it up onto the hemisphere
+	r := math.Sqrt(rng.Float64())
+	phi := 2 * math.Pi * rng.Float64()
+	x, y := r*math.Cos(phi), r*math.Sin(phi)
+	z := math.Sqrt(math.Max(0, 1-x*x-y*y))
+
+	tangent, bitangent := orthonormalBasis(normal)
+	return tangent.Multiply(x).Add(bitangent.Multiply(y)).Add(normal.Multiply(z))
+}
+
+// Returns a random direction, chosen uniformly from all directions, which is a random point on
+// the unit sphere. The probability density is 1 / 4π.
+func uniformSampleSphere(rng *rand.Rand) Tuple {
+	z := 1 - 2*rng.Float64()
+	r := math.Sqrt(math.Max(0, 1-z*z))
+	phi := 2 * math.Pi * rng.Float64()
+	return NewVector(r*math.Cos(phi), r*math.Sin(phi), z)
+}
+
+// Returns the weight of a sample taken with probability density pdf, when the same light could
+// also have been sampled with probability density otherPDF, using the power heuristic of
+// multiple importance sampling.
+func powerHeuristic(pdf float64, otherPDF float64) float64 {
+	if pdf <= 0 {
+		return 0
+	}
+
+	return pdf * pdf / (pdf*pdf + otherPDF*otherPDF)
+}
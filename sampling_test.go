@@ -0,0 +1,58 @@
+package rt
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrthonormalBasis(t *testing.T) {
+	normals := []Tuple{
+		NewVector(0, 0, 1),
+		NewVector(0, 0, -1),
+		NewVector(1, 0, 0),
+		NewVector(1, 2, 3).Normalize(),
+		NewVector(-.3, .1, -2).Normalize(),
+	}
+
+	for _, n := range normals {
+		tangent, bitangent := orthonormalBasis(n)
+		assert.True(t, eq(1, tangent.Magnitude()), n.String())
+		assert.True(t, eq(1, bitangent.Magnitude()), n.String())
+		assert.True(t, eq(0, tangent.Dot(n)), n.String())
+		assert.True(t, eq(0, bitangent.Dot(n)), n.String())
+		assert.True(t, eq(0, tangent.Dot(bitangent)), n.String())
+	}
+}
+
+func TestCosineSampleHemisphere(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	normal := NewVector(1, 1, 0).Normalize()
+	const samples = 10000
+	sum := 0.0
+	for i := 0; i < samples; i++ {
+		direction := cosineSampleHemisphere(normal, rng)
+		assert.True(t, eq(1, direction.Magnitude()))
+		cosine := direction.Dot(normal)
+		assert.GreaterOrEqual(t, cosine, 0.0)
+		sum += cosine
+	}
+
+	// the mean cosine of a cosine-weighted distribution is 2/3
+	assert.InDelta(t, 2.0/3, sum/samples, .01)
+}
+
+func TestUniformSampleSphere(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const samples = 10000
+	sum := NewVector(0, 0, 0)
+	for i := 0; i < samples; i++ {
+		direction := uniformSampleSphere(rng)
+		assert.True(t, eq(1, direction.Magnitude()))
+		sum = sum.Add(direction)
+	}
+
+	// directions are spread evenly, so they cancel out
+	assert.InDelta(t, 0, sum.Magnitude()/samples, .02)
+}
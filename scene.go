@@ -0,0 +1,258 @@
+package rt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// A Scene is a World along with the Camera used to view it.
+type Scene struct {
+	Camera *Camera
+	World  *World
+
+	// Animation, if set, changes the scene over time.
+	Animation *Animation
+}
+
+// NewScene creates a new Scene.
+func NewScene(camera *Camera, world *World) *Scene {
+	return &Scene{Camera: camera, World: world}
+}
+
+// LoadScene reads a scene file, choosing the format (YAML or JSON) from the file's extension.
+func LoadScene(filename string) (*Scene, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return LoadSceneYAML(filename)
+	case ".json":
+		return LoadSceneJSON(filename)
+	}
+
+	return nil, fmt.Errorf("%s: unsupported scene format; use .yaml, .yml, or .json", filename)
+}
+
+// LoadImages loads the images of image patterns that don't have one yet, such as those in a
+// scene decoded directly with json.Unmarshal. Relative image file names are resolved against dir.
+func (s *Scene) LoadImages(dir string) error {
+	return s.World.visitImages(func(file string, canvas **Canvas) error {
+		if *canvas != nil {
+			return nil
+		}
+
+		loaded, err := LoadCanvas(resolvePath(dir, file))
+		if err != nil {
+			return err
+		}
+
+		*canvas = loaded
+		return nil
+	})
+}
+
+// Calls visit with the file name and canvas of each image the world's environment and materials
+// sample. The canvas is nil if the image hasn't been loaded, and visit may set it.
+func (w *World) visitImages(visit func(file string, canvas **Canvas) error) error {
+	if e, ok := w.Environment.(*ImageEnvironment); ok {
+		if err := visit(e.File, &e.Canvas); err != nil {
+			return err
+		}
+	}
+
+	for _, object := range w.Objects {
+		material := object.GetMaterial()
+		if material == nil {
+			continue
+		}
+
+		if material.Pattern != nil {
+			if err := visitPatternImages(material.Pattern, visit); err != nil {
+				return err
+			}
+		}
+
+		if material.PBR != nil {
+			for _, pattern := range material.PBR.patterns() {
+				if err := visitPatternImages(pattern, visit); err != nil {
+					return err
+				}
+			}
+		}
+
+		var err error
+		switch p := material.Perturber.(type) {
+		case *NormalMap:
+			err = visitUVPatternImage(p.UVPattern, visit)
+		case *BumpMap:
+			err = visitPatternImages(p.Pattern, visit)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Visits the images of a pattern and its subpatterns.
+func visitPatternImages(pattern Pattern, visit func(file string, canvas **Canvas) error) error {
+	var uvPatterns []UVPattern
+	switch p := pattern.(type) {
+	case *TextureMapPattern:
+		uvPatterns = []UVPattern{p.UVPattern}
+	case *CubeMapPattern:
+		uvPatterns = p.Faces[:]
+	}
+
+	for _, uvPattern := range uvPatterns {
+		if err := visitUVPatternImage(uvPattern, visit); err != nil {
+			return err
+		}
+	}
+
+	for _, subpattern := range pattern.subpatterns() {
+		if err := visitPatternImages(subpattern, visit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Visits the image of a UV pattern, if it's an image pattern.
+func visitUVPatternImage(uvPattern UVPattern, visit func(file string, canvas **Canvas) error) error {
+	if image, ok := uvPattern.(*UVImagePattern); ok {
+		return visit(image.File, &image.Canvas)
+	}
+
+	return nil
+}
+
+// Returns a file name relative to dir, unless it's already absolute.
+func resolvePath(dir string, filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+
+	return filepath.Join(dir, filename)
+}
+
+// Shape constructors available to scene descriptions, keyed by their type name.
+var sceneShapes = map[string]func() Shape{
+	"sphere": func() Shape { return NewSphere() },
+	"plane":  func() Shape { return NewPlane() },
+}
+
+// Easing constructors available to scene descriptions, keyed by their name. The ease curves
+// match the CSS timing functions of the same names.
+var sceneEasings = map[string]func() Easing{
+	"linear":      func() Easing { return LinearEasing{} },
+	"smoothstep":  func() Easing { return SmoothstepEasing{} },
+	"ease-in":     func() Easing { return NewBezierEasing(.42, 0, 1, 1) },
+	"ease-out":    func() Easing { return NewBezierEasing(0, 0, .58, 1) },
+	"ease-in-out": func() Easing { return NewBezierEasing(.42, 0, .58, 1) },
+}
+
+// Composite pattern constructors available to scene descriptions, keyed by their type name.
+var scenePatterns = map[string]func(a Pattern, b Pattern) Pattern{
+	"stripes":    func(a Pattern, b Pattern) Pattern { return NewStripePattern(a, b) },
+	"gradient":   func(a Pattern, b Pattern) Pattern { return NewGradientPattern(a, b) },
+	"rings":      func(a Pattern, b Pattern) Pattern { return NewRingPattern(a, b) },
+	"checkers":   func(a Pattern, b Pattern) Pattern { return NewCheckerPattern(a, b) },
+	"blended":    func(a Pattern, b Pattern) Pattern { return NewBlendedPattern(a, b) },
+	"turbulence": func(a Pattern, b Pattern) Pattern { return NewTurbulencePattern(a, b) },
+	"fbm":        func(a Pattern, b Pattern) Pattern { return NewFBMPattern(a, b) },
+	"marble":     func(a Pattern, b Pattern) Pattern { return NewMarblePattern(a, b) },
+	"wood":       func(a Pattern, b Pattern) Pattern { return NewWoodPattern(a, b) },
+}
+
+// Constructors for patterns that wrap a single subpattern, keyed by their type name.
+var sceneWrapperPatterns = map[string]func(pattern Pattern) Pattern{
+	"perturbed": func(pattern Pattern) Pattern { return NewPerturbedPattern(pattern, .1) },
+}
+
+// A patternParameter is a numeric property of a pattern that scene descriptions can set.
+// Exactly one of float and integer is set.
+type patternParameter struct {
+	name    string
+	float   *float64
+	integer *int
+}
+
+// Returns the parameters of a pattern that scene descriptions can set.
+func patternParameters(pattern Pattern) []patternParameter {
+	switch p := pattern.(type) {
+	case *PerturbedPattern:
+		return []patternParameter{{"scale", &p.Scale, nil}, {"octaves", nil, &p.Octaves}}
+	case *TurbulencePattern:
+		return []patternParameter{{"octaves", nil, &p.Octaves}}
+	case *FBMPattern:
+		return []patternParameter{{"octaves", nil, &p.Octaves}}
+	case *MarblePattern:
+		return []patternParameter{{"octaves", nil, &p.Octaves}, {"strength", &p.Strength, nil}}
+	case *WoodPattern:
+		return []patternParameter{{"octaves", nil, &p.Octaves}, {"strength", &p.Strength, nil}}
+	}
+
+	return nil
+}
+
+// Returns the scene description type name of a shape.
+func shapeTypeName(shape Shape) (string, error) {
+	switch shape.(type) {
+	case *Sphere:
+		return "sphere", nil
+	case *Plane:
+		return "plane", nil
+	}
+
+	return "", fmt.Errorf("unsupported shape type %T", shape)
+}
+
+// Returns the scene description type name of a pattern.
+func patternTypeName(pattern Pattern) (string, error) {
+	switch pattern.(type) {
+	case *SolidPattern:
+		return "solid", nil
+	case *StripePattern:
+		return "stripes", nil
+	case *GradientPattern:
+		return "gradient", nil
+	case *RingPattern:
+		return "rings", nil
+	case *CheckerPattern:
+		return "checkers", nil
+	case *BlendedPattern:
+		return "blended", nil
+	case *PerturbedPattern:
+		return "perturbed", nil
+	case *TurbulencePattern:
+		return "turbulence", nil
+	case *FBMPattern:
+		return "fbm", nil
+	case *MarblePattern:
+		return "marble", nil
+	case *WoodPattern:
+		return "wood", nil
+	case *TextureMapPattern:
+		return "texture-map", nil
+	case *CubeMapPattern:
+		return "cube-map", nil
+	}
+
+	return "", fmt.Errorf("unsupported pattern type %T", pattern)
+}
+
+// A SceneError is an error found at a specific position within a scene description file.
+type SceneError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+// Error returns the error message prefixed with the file and position of the error.
+func (e *SceneError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
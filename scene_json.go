@@ -0,0 +1,1354 @@
+package rt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// LoadSceneJSON reads and decodes a JSON scene file. Images are loaded relative to the scene file.
+func LoadSceneJSON(filename string) (*Scene, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	scene := &Scene{}
+	if err := json.Unmarshal(data, scene); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	if err := scene.LoadImages(filepath.Dir(filename)); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return scene, nil
+}
+
+// SaveSceneJSON encodes a scene as indented JSON and writes it to a file.
+func SaveSceneJSON(filename string, scene *Scene) error {
+	data, err := json.MarshalIndent(scene, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// JSON representations of the scene types. Points, vectors, and colors are encoded as
+// three-element arrays and transformations as 4x4 row-major arrays.
+
+type jsonTriple [3]float64
+
+type jsonCamera struct {
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	FieldOfView float64         `json:"fieldOfView"`
+	Transform   Transformation  `json:"transform"`
+	Samples     int             `json:"samples,omitempty"`
+	Integrator  *jsonIntegrator `json:"integrator,omitempty"`
+	Shutter     *[2]float64     `json:"shutter,omitempty"`
+	Sampler     string          `json:"sampler,omitempty"`
+	Seed        int64           `json:"seed,omitempty"`
+}
+
+type jsonIntegrator struct {
+	Type          string `json:"type"`
+	MaxDepth      *int   `json:"maxDepth,omitempty"`
+	RouletteDepth *int   `json:"rouletteDepth,omitempty"`
+}
+
+type jsonLight struct {
+	Position  jsonTriple `json:"position"`
+	Intensity jsonTriple `json:"intensity"`
+}
+
+type jsonWorld struct {
+	Light       *jsonLight        `json:"light,omitempty"`
+	Environment json.RawMessage   `json:"environment,omitempty"`
+	Objects     []json.RawMessage `json:"objects"`
+}
+
+type jsonShape struct {
+	Type      string         `json:"type"`
+	Transform Transformation `json:"transform"`
+	Material  *Material      `json:"material"`
+	Motion    []jsonKeyframe `json:"motion,omitempty"`
+}
+
+type jsonKeyframe struct {
+	Time      float64        `json:"time"`
+	Transform Transformation `json:"transform"`
+}
+
+type jsonMaterial struct {
+	Color        jsonTriple      `json:"color"`
+	Ambient      float64         `json:"ambient"`
+	Diffuse      float64         `json:"diffuse"`
+	Specular     float64         `json:"specular"`
+	Shininess    float64         `json:"shininess"`
+	Emission     *jsonTriple     `json:"emission,omitempty"`
+	Pattern      json.RawMessage `json:"pattern,omitempty"`
+	Perturbation json.RawMessage `json:"perturbation,omitempty"`
+	PBR          *PBRMaterial    `json:"pbr,omitempty"`
+}
+
+type jsonScene struct {
+	Camera    *Camera        `json:"camera"`
+	World     *World         `json:"world"`
+	Animation *jsonAnimation `json:"animation,omitempty"`
+}
+
+type jsonAnimation struct {
+	Frames int         `json:"frames"`
+	Start  float64     `json:"start"`
+	End    float64     `json:"end"`
+	Tracks []jsonTrack `json:"tracks"`
+}
+
+type jsonTrack struct {
+	Target    string             `json:"target"`
+	Object    *int               `json:"object,omitempty"`
+	Parameter string             `json:"parameter,omitempty"`
+	Keys      []jsonAnimationKey `json:"keys"`
+}
+
+type jsonAnimationKey struct {
+	Time      float64         `json:"time"`
+	Easing    json.RawMessage `json:"easing,omitempty"`
+	From      *jsonTriple     `json:"from,omitempty"`
+	To        *jsonTriple     `json:"to,omitempty"`
+	Up        *jsonTriple     `json:"up,omitempty"`
+	Position  *jsonTriple     `json:"position,omitempty"`
+	Transform Transformation  `json:"transform,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+}
+
+func newJSONTriple(t []float64) jsonTriple {
+	return jsonTriple{t[0], t[1], t[2]}
+}
+
+// MarshalJSON encodes the scene as JSON. Animation tracks refer to the objects they change by
+// their index in the world's list of objects.
+func (s *Scene) MarshalJSON() ([]byte, error) {
+	js := jsonScene{Camera: s.Camera, World: s.World}
+	if a := s.Animation; a != nil {
+		js.Animation = &jsonAnimation{Frames: a.Frames, Start: a.Start, End: a.End, Tracks: []jsonTrack{}}
+		for _, track := range a.Tracks {
+			jt, err := s.marshalTrackJSON(track)
+			if err != nil {
+				return nil, err
+			}
+
+			js.Animation.Tracks = append(js.Animation.Tracks, jt)
+		}
+	}
+
+	return json.Marshal(js)
+}
+
+func (s *Scene) marshalTrackJSON(track Track) (jsonTrack, error) {
+	objectIndex := func(matches func(shape Shape) bool) (*int, error) {
+		for i, object := range s.World.Objects {
+			if matches(object) {
+				i := i
+				return &i, nil
+			}
+		}
+
+		return nil, fmt.Errorf("animation track changes an object that isn't in the world")
+	}
+
+	var jt jsonTrack
+	var err error
+	addKey := func(key jsonAnimationKey, easing Easing) {
+		if err == nil {
+			key.Easing, err = marshalEasingJSON(easing)
+			jt.Keys = append(jt.Keys, key)
+		}
+	}
+
+	switch tr := track.(type) {
+	case *CameraTrack:
+		if tr.Camera != s.Camera {
+			return jt, fmt.Errorf("animation track moves a camera that isn't the scene's")
+		}
+
+		jt.Target = "camera"
+		for _, key := range tr.Keys {
+			from, to, up := newJSONTriple(key.From), newJSONTriple(key.To), newJSONTriple(key.Up)
+			addKey(jsonAnimationKey{Time: key.Time, From: &from, To: &to, Up: &up}, key.Easing)
+		}
+	case *LightTrack:
+		if tr.Light != s.World.Light {
+			return jt, fmt.Errorf("animation track moves a light that isn't the scene's")
+		}
+
+		jt.Target = "light"
+		for _, key := range tr.Keys {
+			position := newJSONTriple(key.Position)
+			addKey(jsonAnimationKey{Time: key.Time, Position: &position}, key.Easing)
+		}
+	case *TransformTrack:
+		jt.Target = "transform"
+		jt.Object, err = objectIndex(func(shape Shape) bool { return shape == tr.Shape })
+		for _, key := range tr.Keys {
+			addKey(jsonAnimationKey{Time: key.Time, Transform: key.Transform}, key.Easing)
+		}
+	case *MaterialTrack:
+		jt.Target = "material"
+		jt.Parameter = tr.Parameter
+		jt.Object, err = objectIndex(func(shape Shape) bool { return shape.GetMaterial() == tr.Material })
+		for _, key := range tr.Keys {
+			// single values are written as plain numbers
+			var value interface{} = key.Value
+			if len(key.Value) == 1 {
+				value = key.Value[0]
+			}
+
+			data, _ := json.Marshal(value)
+			addKey(jsonAnimationKey{Time: key.Time, Value: data}, key.Easing)
+		}
+	default:
+		return jt, fmt.Errorf("unsupported animation track type %T", track)
+	}
+
+	return jt, err
+}
+
+// Encodes an easing as its name, or as the list of its control points if it's a Bézier curve.
+// Nil easings are omitted.
+func marshalEasingJSON(easing Easing) ([]byte, error) {
+	switch e := easing.(type) {
+	case nil:
+		return nil, nil
+	case LinearEasing:
+		return json.Marshal("linear")
+	case SmoothstepEasing:
+		return json.Marshal("smoothstep")
+	case *BezierEasing:
+		return json.Marshal([]float64{e.X1, e.Y1, e.X2, e.Y2})
+	}
+
+	return nil, fmt.Errorf("unsupported easing type %T", easing)
+}
+
+// Decodes an easing encoded by marshalEasingJSON, or one of the named Bézier curves.
+func unmarshalEasingJSON(data json.RawMessage) (Easing, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		newEasing, ok := sceneEasings[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown easing '%s'", name)
+		}
+
+		return newEasing(), nil
+	}
+
+	var points []float64
+	if err := json.Unmarshal(data, &points); err != nil || len(points) != 4 {
+		return nil, fmt.Errorf("easing must be a name or a list of four numbers")
+	}
+
+	return NewBezierEasing(points[0], points[1], points[2], points[3]), nil
+}
+
+// UnmarshalJSON decodes the scene from JSON.
+func (s *Scene) UnmarshalJSON(data []byte) error {
+	var js jsonScene
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+
+	if js.Camera == nil {
+		return fmt.Errorf("scene has no camera")
+	}
+
+	if js.World == nil {
+		return fmt.Errorf("scene has no world")
+	}
+
+	scene := &Scene{Camera: js.Camera, World: js.World}
+	if ja := js.Animation; ja != nil {
+		if ja.Frames <= 0 {
+			return fmt.Errorf("animation frames must be positive")
+		}
+
+		scene.Animation = NewAnimation(ja.Frames, ja.Start, ja.End)
+		for _, jt := range ja.Tracks {
+			track, err := scene.unmarshalTrackJSON(jt)
+			if err != nil {
+				return err
+			}
+
+			scene.Animation.Tracks = append(scene.Animation.Tracks, track)
+		}
+	}
+
+	*s = *scene
+	return nil
+}
+
+func (s *Scene) unmarshalTrackJSON(jt jsonTrack) (Track, error) {
+	easings := make([]Easing, len(jt.Keys))
+	for i, key := range jt.Keys {
+		easing, err := unmarshalEasingJSON(key.Easing)
+		if err != nil {
+			return nil, err
+		}
+
+		easings[i] = easing
+	}
+
+	var object Shape
+	switch jt.Target {
+	case "transform", "material":
+		if s.World == nil || jt.Object == nil || *jt.Object < 0 || *jt.Object >= len(s.World.Objects) {
+			return nil, fmt.Errorf("%s track must refer to an object of the world", jt.Target)
+		}
+
+		object = s.World.Objects[*jt.Object]
+	}
+
+	missing := func(what string) error {
+		return fmt.Errorf("%s track keys must each have %s", jt.Target, what)
+	}
+
+	switch jt.Target {
+	case "camera":
+		if s.Camera == nil {
+			return nil, fmt.Errorf("camera track requires a camera")
+		}
+
+		keys := make([]CameraKey, len(jt.Keys))
+		for i, key := range jt.Keys {
+			if key.From == nil || key.To == nil || key.Up == nil {
+				return nil, missing("a from, to, and up")
+			}
+
+			f, t, u := key.From, key.To, key.Up
+			keys[i] = CameraKey{key.Time, NewPoint(f[0], f[1], f[2]), NewPoint(t[0], t[1], t[2]), NewVector(u[0], u[1], u[2]), easings[i]}
+			if !NewViewTransform(keys[i].From, keys[i].To, keys[i].Up).IsInvertable() {
+				return nil, fmt.Errorf("camera transform is not invertible; is up parallel to the view direction?")
+			}
+		}
+
+		return NewCameraTrack(s.Camera, keys...), nil
+	case "light":
+		if s.World == nil || s.World.Light == nil {
+			return nil, fmt.Errorf("light track requires a light")
+		}
+
+		keys := make([]LightKey, len(jt.Keys))
+		for i, key := range jt.Keys {
+			if key.Position == nil {
+				return nil, missing("a position")
+			}
+
+			p := key.Position
+			keys[i] = LightKey{key.Time, NewPoint(p[0], p[1], p[2]), easings[i]}
+		}
+
+		return NewLightTrack(s.World.Light, keys...), nil
+	case "transform":
+		keys := make([]TransformKey, len(jt.Keys))
+		for i, key := range jt.Keys {
+			if key.Transform == nil {
+				return nil, missing("a transform")
+			}
+
+			transform, err := validTransform(key.Transform)
+			if err != nil {
+				return nil, err
+			}
+
+			keys[i] = TransformKey{key.Time, transform, easings[i]}
+		}
+
+		return NewTransformTrack(object, keys...)
+	case "material":
+		if object.GetMaterial() == nil {
+			return nil, fmt.Errorf("material track refers to an object without a material")
+		}
+
+		keys := make([]MaterialKey, len(jt.Keys))
+		for i, key := range jt.Keys {
+			var value []float64
+			if len(key.Value) == 0 {
+				return nil, missing("a value")
+			}
+
+			var number float64
+			if err := json.Unmarshal(key.Value, &number); err == nil {
+				value = []float64{number}
+			} else if err := json.Unmarshal(key.Value, &value); err != nil {
+				return nil, fmt.Errorf("material track values must be numbers or colors")
+			}
+
+			keys[i] = MaterialKey{key.Time, value, easings[i]}
+		}
+
+		return NewMaterialTrack(object.GetMaterial(), jt.Parameter, keys...)
+	}
+
+	return nil, fmt.Errorf("unknown animation track target '%s'", jt.Target)
+}
+
+// MarshalJSON encodes the camera as JSON.
+func (c *Camera) MarshalJSON() ([]byte, error) {
+	jc := jsonCamera{c.HSize, c.VSize, c.FOV, c.Transform, c.Samples, nil, nil, "", c.Seed}
+	if c.ShutterOpen != 0 || c.ShutterClose != 0 {
+		jc.Shutter = &[2]float64{c.ShutterOpen, c.ShutterClose}
+	}
+
+	if c.Integrator != nil {
+		name, err := integratorName(c.Integrator)
+		if err != nil {
+			return nil, err
+		}
+
+		jc.Integrator = &jsonIntegrator{Type: name}
+		if pt, ok := c.Integrator.(*PathTracer); ok {
+			jc.Integrator.MaxDepth = &pt.MaxDepth
+			jc.Integrator.RouletteDepth = &pt.RouletteDepth
+		}
+	}
+
+	if c.Sampler != nil {
+		name, err := samplerName(c.Sampler)
+		if err != nil {
+			return nil, err
+		}
+
+		jc.Sampler = name
+	}
+
+	return json.Marshal(jc)
+}
+
+// UnmarshalJSON decodes the camera from JSON.
+func (c *Camera) UnmarshalJSON(data []byte) error {
+	var jc jsonCamera
+	if err := json.Unmarshal(data, &jc); err != nil {
+		return err
+	}
+
+	if jc.Width <= 0 || jc.Height <= 0 {
+		return fmt.Errorf("camera width and height must be positive")
+	}
+
+	transform, err := validTransform(jc.Transform)
+	if err != nil {
+		return err
+	}
+
+	if jc.Samples < 0 {
+		return fmt.Errorf("camera samples must be positive")
+	}
+
+	if jc.Shutter != nil && jc.Shutter[1] < jc.Shutter[0] {
+		return fmt.Errorf("shutter must open before it closes")
+	}
+
+	var integrator Integrator
+	if jc.Integrator != nil {
+		if integrator, err = NewIntegrator(jc.Integrator.Type); err != nil {
+			return err
+		}
+
+		pt, ok := integrator.(*PathTracer)
+		if !ok && (jc.Integrator.MaxDepth != nil || jc.Integrator.RouletteDepth != nil) {
+			return fmt.Errorf("%s integrator has no depth settings", jc.Integrator.Type)
+		}
+
+		if jc.Integrator.MaxDepth != nil {
+			pt.MaxDepth = *jc.Integrator.MaxDepth
+		}
+
+		if jc.Integrator.RouletteDepth != nil {
+			pt.RouletteDepth = *jc.Integrator.RouletteDepth
+		}
+	}
+
+	var sampler Sampler
+	if jc.Sampler != "" {
+		if sampler, err = NewSampler(jc.Sampler); err != nil {
+			return err
+		}
+	}
+
+	*c = *NewCamera(jc.Width, jc.Height, jc.FieldOfView)
+	c.Transform = transform
+	c.Integrator = integrator
+	c.Sampler = sampler
+	c.Seed = jc.Seed
+	if jc.Shutter != nil {
+		c.ShutterOpen, c.ShutterClose = jc.Shutter[0], jc.Shutter[1]
+	}
+
+	if jc.Samples > 0 {
+		c.Samples = jc.Samples
+	}
+
+	return nil
+}
+
+// MarshalJSON encodes the world as JSON.
+func (w *World) MarshalJSON() ([]byte, error) {
+	jw := jsonWorld{Objects: []json.RawMessage{}}
+	if w.Light != nil {
+		jw.Light = &jsonLight{newJSONTriple(w.Light.Position), newJSONTriple(w.Light.Intensity)}
+	}
+
+	if w.Environment != nil {
+		if _, ok := w.Environment.(json.Marshaler); !ok {
+			return nil, fmt.Errorf("unsupported environment type %T", w.Environment)
+		}
+
+		data, err := json.Marshal(w.Environment)
+		if err != nil {
+			return nil, err
+		}
+
+		jw.Environment = data
+	}
+
+	for _, obj := range w.Objects {
+		data, err := marshalShapeJSON(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		jw.Objects = append(jw.Objects, data)
+	}
+
+	return json.Marshal(jw)
+}
+
+// UnmarshalJSON decodes the world from JSON.
+func (w *World) UnmarshalJSON(data []byte) error {
+	var jw jsonWorld
+	if err := json.Unmarshal(data, &jw); err != nil {
+		return err
+	}
+
+	world := NewWorld()
+	if jw.Light != nil {
+		p, i := jw.Light.Position, jw.Light.Intensity
+		world.Light = NewPointLight(NewPoint(p[0], p[1], p[2]), NewColor(i[0], i[1], i[2]))
+	}
+
+	if jw.Environment != nil && string(jw.Environment) != "null" {
+		environment, err := UnmarshalEnvironmentJSON(jw.Environment)
+		if err != nil {
+			return err
+		}
+
+		world.Environment = environment
+	}
+
+	for _, data := range jw.Objects {
+		shape, err := UnmarshalShapeJSON(data)
+		if err != nil {
+			return err
+		}
+
+		world.AddObjects(shape)
+	}
+
+	*w = *world
+	return nil
+}
+
+// MarshalJSON encodes the material as JSON.
+func (m *Material) MarshalJSON() ([]byte, error) {
+	jm := jsonMaterial{
+		Color:     newJSONTriple(m.Color),
+		Ambient:   m.Ambient,
+		Diffuse:   m.Diffuse,
+		Specular:  m.Specular,
+		Shininess: m.Shininess,
+		PBR:       m.PBR,
+	}
+
+	if !isBlack(m.Emission) {
+		emission := newJSONTriple(m.Emission)
+		jm.Emission = &emission
+	}
+
+	if m.Pattern != nil {
+		data, err := json.Marshal(m.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		jm.Pattern = data
+	}
+
+	if m.Perturber != nil {
+		if _, ok := m.Perturber.(json.Marshaler); !ok {
+			return nil, fmt.Errorf("unsupported perturbation type %T", m.Perturber)
+		}
+
+		data, err := json.Marshal(m.Perturber)
+		if err != nil {
+			return nil, err
+		}
+
+		jm.Perturbation = data
+	}
+
+	return json.Marshal(jm)
+}
+
+// UnmarshalJSON decodes the material from JSON. Omitted properties keep their default values.
+func (m *Material) UnmarshalJSON(data []byte) error {
+	defaults := NewMaterial()
+	jm := jsonMaterial{
+		Color:     newJSONTriple(defaults.Color),
+		Ambient:   defaults.Ambient,
+		Diffuse:   defaults.Diffuse,
+		Specular:  defaults.Specular,
+		Shininess: defaults.Shininess,
+	}
+
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+
+	material := &Material{
+		Color:     NewColor(jm.Color[0], jm.Color[1], jm.Color[2]),
+		Ambient:   jm.Ambient,
+		Diffuse:   jm.Diffuse,
+		Specular:  jm.Specular,
+		Shininess: jm.Shininess,
+		Emission:  defaults.Emission,
+		PBR:       jm.PBR,
+	}
+
+	if jm.Emission != nil {
+		material.Emission = NewColor(jm.Emission[0], jm.Emission[1], jm.Emission[2])
+	}
+
+	if jm.Pattern != nil && string(jm.Pattern) != "null" {
+		pattern, err := UnmarshalPatternJSON(jm.Pattern)
+		if err != nil {
+			return err
+		}
+
+		material.Pattern = pattern
+	}
+
+	if jm.Perturbation != nil && string(jm.Perturbation) != "null" {
+		perturber, err := UnmarshalPerturberJSON(jm.Perturbation)
+		if err != nil {
+			return err
+		}
+
+		material.Perturber = perturber
+	}
+
+	*m = *material
+	return nil
+}
+
+// MarshalJSON encodes the sphere as JSON.
+func (s *Sphere) MarshalJSON() ([]byte, error) {
+	return marshalShapeJSON(s)
+}
+
+// MarshalJSON encodes the plane as JSON.
+func (p *Plane) MarshalJSON() ([]byte, error) {
+	return marshalShapeJSON(p)
+}
+
+func marshalShapeJSON(shape Shape) ([]byte, error) {
+	name, err := shapeTypeName(shape)
+	if err != nil {
+		return nil, err
+	}
+
+	js := jsonShape{name, shape.GetTransform(), shape.GetMaterial(), nil}
+	if motion := shape.GetMotion(); motion != nil {
+		for _, keyframe := range motion.Keyframes {
+			js.Motion = append(js.Motion, jsonKeyframe{keyframe.Time, keyframe.Transform})
+		}
+	}
+
+	return json.Marshal(js)
+}
+
+// UnmarshalShapeJSON decodes a Shape of any type from JSON, using its "type" property to choose
+// which kind of shape to create.
+func UnmarshalShapeJSON(data []byte) (Shape, error) {
+	var js jsonShape
+	if err := json.Unmarshal(data, &js); err != nil {
+		return nil, err
+	}
+
+	newShape, ok := sceneShapes[js.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown shape type '%s'", js.Type)
+	}
+
+	shape := newShape()
+	if js.Material != nil {
+		shape.SetMaterial(js.Material)
+	}
+
+	transform, err := validTransform(js.Transform)
+	if err != nil {
+		return nil, err
+	}
+
+	shape.SetTransform(transform)
+	if len(js.Motion) > 0 {
+		keyframes := make([]Keyframe, len(js.Motion))
+		for i, jk := range js.Motion {
+			transform, err := validTransform(jk.Transform)
+			if err != nil {
+				return nil, err
+			}
+
+			keyframes[i] = Keyframe{jk.Time, transform}
+		}
+
+		motion, err := NewKeyframeMotion(keyframes...)
+		if err != nil {
+			return nil, err
+		}
+
+		shape.SetMotion(motion)
+	}
+
+	return shape, nil
+}
+
+// MarshalJSON encodes the pattern as JSON. The method is shared by all of the built-in patterns.
+//
+// Patterns are encoded as objects with a "type", a "transform", and either a "color" (for solid
+// patterns), a single subpattern "pattern", or two subpatterns "a" and "b", along with any
+// numeric parameters of the pattern type.
+func (props *PatternProps) MarshalJSON() ([]byte, error) {
+	name, err := patternTypeName(props.p)
+	if err != nil {
+		return nil, err
+	}
+
+	jp := map[string]interface{}{
+		"type":      name,
+		"transform": props.Transform,
+	}
+
+	switch p := props.p.(type) {
+	case *SolidPattern:
+		jp["color"] = newJSONTriple(p.color)
+	case *TextureMapPattern:
+		jp["mapping"] = p.Mapping.String()
+		jp["uvPattern"] = p.UVPattern
+	case *CubeMapPattern:
+		for i, face := range CubeFaceNames {
+			jp[face] = p.Faces[i]
+		}
+	default:
+		if _, ok := sceneWrapperPatterns[name]; ok {
+			jp["pattern"] = props.A
+		} else {
+			jp["a"] = props.A
+			jp["b"] = props.B
+		}
+	}
+
+	for _, param := range patternParameters(props.p) {
+		if param.float != nil {
+			jp[param.name] = *param.float
+		} else {
+			jp[param.name] = *param.integer
+		}
+	}
+
+	return json.Marshal(jp)
+}
+
+// UnmarshalPatternJSON decodes a Pattern of any type from JSON, using its "type" property to
+// choose which kind of pattern to create.
+func UnmarshalPatternJSON(data []byte) (Pattern, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	var patternType string
+	if err := json.Unmarshal(fields["type"], &patternType); err != nil {
+		return nil, fmt.Errorf("pattern has no type")
+	}
+
+	subpattern := func(key string) (Pattern, error) {
+		data, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("%s pattern has no '%s' pattern", patternType, key)
+		}
+
+		return UnmarshalPatternJSON(data)
+	}
+
+	var pattern Pattern
+	known := map[string]bool{"type": true, "transform": true}
+	if patternType == "solid" {
+		var color jsonTriple
+		if data, ok := fields["color"]; !ok {
+			return nil, fmt.Errorf("solid pattern has no color")
+		} else if err := json.Unmarshal(data, &color); err != nil {
+			return nil, err
+		}
+
+		pattern = NewSolidPattern(NewColor(color[0], color[1], color[2]))
+		known["color"] = true
+	} else if patternType == "texture-map" {
+		var mappingName string
+		if err := json.Unmarshal(fields["mapping"], &mappingName); err != nil {
+			return nil, fmt.Errorf("texture-map pattern has no mapping")
+		}
+
+		mapping, err := ParseUVMapping(mappingName)
+		if err != nil {
+			return nil, err
+		}
+
+		uvPattern, err := unmarshalUVPatternField(fields, patternType, "uvPattern")
+		if err != nil {
+			return nil, err
+		}
+
+		pattern = NewTextureMapPattern(uvPattern, mapping)
+		known["mapping"], known["uvPattern"] = true, true
+	} else if patternType == "cube-map" {
+		var faces [6]UVPattern
+		for i, name := range CubeFaceNames {
+			face, err := unmarshalUVPatternField(fields, patternType, name)
+			if err != nil {
+				return nil, err
+			}
+
+			faces[i] = face
+			known[name] = true
+		}
+
+		pattern = NewCubeMapPattern(faces[0], faces[1], faces[2], faces[3], faces[4], faces[5])
+	} else if newPattern, ok := sceneWrapperPatterns[patternType]; ok {
+		wrapped, err := subpattern("pattern")
+		if err != nil {
+			return nil, err
+		}
+
+		pattern = newPattern(wrapped)
+		known["pattern"] = true
+	} else if newPattern, ok := scenePatterns[patternType]; ok {
+		a, err := subpattern("a")
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := subpattern("b")
+		if err != nil {
+			return nil, err
+		}
+
+		pattern = newPattern(a, b)
+		known["a"], known["b"] = true, true
+	} else {
+		return nil, fmt.Errorf("unknown pattern type '%s'", patternType)
+	}
+
+	for _, param := range patternParameters(pattern) {
+		data, ok := fields[param.name]
+		if !ok {
+			continue
+		}
+
+		var err error
+		if param.float != nil {
+			err = json.Unmarshal(data, param.float)
+		} else {
+			err = json.Unmarshal(data, param.integer)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%s pattern %s: %v", patternType, param.name, err)
+		}
+
+		known[param.name] = true
+	}
+
+	for key := range fields {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown %s pattern property '%s'", patternType, key)
+		}
+	}
+
+	var transform Transformation
+	if data, ok := fields["transform"]; ok {
+		if err := json.Unmarshal(data, &transform); err != nil {
+			return nil, err
+		}
+	}
+
+	transform, err := validTransform(transform)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern.SetTransform(transform)
+	return pattern, nil
+}
+
+// Each property of a physically based material is either a value or a pattern.
+type jsonPBRMaterial struct {
+	BaseColor json.RawMessage `json:"baseColor,omitempty"`
+	Metallic  json.RawMessage `json:"metallic,omitempty"`
+	Roughness json.RawMessage `json:"roughness,omitempty"`
+	Emission  json.RawMessage `json:"emission,omitempty"`
+}
+
+// MarshalJSON encodes the material as JSON.
+func (m *PBRMaterial) MarshalJSON() ([]byte, error) {
+	var jm jsonPBRMaterial
+	properties := []struct {
+		data    *json.RawMessage
+		value   interface{}
+		pattern Pattern
+	}{
+		{&jm.BaseColor, newJSONTriple(m.BaseColor), m.BaseColorPattern},
+		{&jm.Metallic, m.Metallic, m.MetallicPattern},
+		{&jm.Roughness, m.Roughness, m.RoughnessPattern},
+		{&jm.Emission, newJSONTriple(m.Emission), m.EmissionPattern},
+	}
+
+	for _, property := range properties {
+		value := property.value
+		if property.pattern != nil {
+			value = property.pattern
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+
+		*property.data = data
+	}
+
+	return json.Marshal(jm)
+}
+
+// UnmarshalJSON decodes the material from JSON. Omitted properties keep their default values.
+func (m *PBRMaterial) UnmarshalJSON(data []byte) error {
+	var jm jsonPBRMaterial
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+
+	material := NewPBRMaterial()
+	var err error
+	if material.BaseColorPattern, err = unmarshalColorOrPattern(jm.BaseColor, &material.BaseColor); err != nil {
+		return err
+	}
+
+	if material.MetallicPattern, err = unmarshalFloatOrPattern(jm.Metallic, &material.Metallic); err != nil {
+		return err
+	}
+
+	if material.RoughnessPattern, err = unmarshalFloatOrPattern(jm.Roughness, &material.Roughness); err != nil {
+		return err
+	}
+
+	if material.EmissionPattern, err = unmarshalColorOrPattern(jm.Emission, &material.Emission); err != nil {
+		return err
+	}
+
+	*m = *material
+	return nil
+}
+
+// Decodes either a color, which is stored in color, or a pattern, which is returned.
+// Missing data leaves the color unchanged.
+func unmarshalColorOrPattern(data json.RawMessage, color *Color) (Pattern, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if data[0] == '{' {
+		return UnmarshalPatternJSON(data)
+	}
+
+	var t jsonTriple
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+
+	*color = NewColor(t[0], t[1], t[2])
+	return nil, nil
+}
+
+// Decodes either a number, which is stored in value, or a pattern, which is returned.
+// Missing data leaves the value unchanged.
+func unmarshalFloatOrPattern(data json.RawMessage, value *float64) (Pattern, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if data[0] == '{' {
+		return UnmarshalPatternJSON(data)
+	}
+
+	return nil, json.Unmarshal(data, value)
+}
+
+type jsonNormalMap struct {
+	Type      string          `json:"type"`
+	Mapping   string          `json:"mapping"`
+	UVPattern json.RawMessage `json:"uvPattern"`
+	Transform Transformation  `json:"transform"`
+	Strength  float64         `json:"strength"`
+}
+
+type jsonBumpMap struct {
+	Type    string          `json:"type"`
+	Pattern json.RawMessage `json:"pattern"`
+	Scale   float64         `json:"scale"`
+	Delta   float64         `json:"delta"`
+}
+
+// MarshalJSON encodes the normal map as JSON.
+func (m *NormalMap) MarshalJSON() ([]byte, error) {
+	uvPattern, err := json.Marshal(m.UVPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonNormalMap{"normal-map", m.Mapping.String(), uvPattern, m.Transform, m.Strength})
+}
+
+// MarshalJSON encodes the bump map as JSON.
+func (m *BumpMap) MarshalJSON() ([]byte, error) {
+	pattern, err := json.Marshal(m.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonBumpMap{"bump-map", pattern, m.Scale, m.Delta})
+}
+
+// UnmarshalPerturberJSON decodes a NormalPerturber of any type from JSON, using its "type"
+// property to choose which kind of perturbation to create. Omitted numeric properties keep
+// their default values.
+func UnmarshalPerturberJSON(data []byte) (NormalPerturber, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	switch header.Type {
+	case "normal-map":
+		defaults := NewNormalMap(nil, SphericalMapping)
+		jm := jsonNormalMap{Strength: defaults.Strength}
+		if err := json.Unmarshal(data, &jm); err != nil {
+			return nil, err
+		}
+
+		mapping, err := ParseUVMapping(jm.Mapping)
+		if err != nil {
+			return nil, err
+		}
+
+		if jm.UVPattern == nil {
+			return nil, fmt.Errorf("normal-map perturbation has no 'uvPattern' UV pattern")
+		}
+
+		uvPattern, err := UnmarshalUVPatternJSON(jm.UVPattern)
+		if err != nil {
+			return nil, err
+		}
+
+		transform, err := validTransform(jm.Transform)
+		if err != nil {
+			return nil, err
+		}
+
+		return &NormalMap{uvPattern, mapping, transform, jm.Strength}, nil
+	case "bump-map":
+		defaults := NewBumpMap(nil, 1)
+		jm := jsonBumpMap{Scale: defaults.Scale, Delta: defaults.Delta}
+		if err := json.Unmarshal(data, &jm); err != nil {
+			return nil, err
+		}
+
+		if jm.Pattern == nil {
+			return nil, fmt.Errorf("bump-map perturbation has no 'pattern' pattern")
+		}
+
+		pattern, err := UnmarshalPatternJSON(jm.Pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		return &BumpMap{pattern, jm.Scale, jm.Delta}, nil
+	}
+
+	return nil, fmt.Errorf("unknown perturbation type '%s'", header.Type)
+}
+
+// Decodes a UV pattern that is a required property of a pattern.
+func unmarshalUVPatternField(fields map[string]json.RawMessage, patternType string, key string) (UVPattern, error) {
+	data, ok := fields[key]
+	if !ok {
+		return nil, fmt.Errorf("%s pattern has no '%s' UV pattern", patternType, key)
+	}
+
+	return UnmarshalUVPatternJSON(data)
+}
+
+type jsonUVCheckersPattern struct {
+	Type   string     `json:"type"`
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	A      jsonTriple `json:"a"`
+	B      jsonTriple `json:"b"`
+}
+
+type jsonUVAlignCheckPattern struct {
+	Type        string     `json:"type"`
+	Main        jsonTriple `json:"main"`
+	UpperLeft   jsonTriple `json:"upperLeft"`
+	UpperRight  jsonTriple `json:"upperRight"`
+	BottomLeft  jsonTriple `json:"bottomLeft"`
+	BottomRight jsonTriple `json:"bottomRight"`
+}
+
+type jsonUVImagePattern struct {
+	Type   string `json:"type"`
+	File   string `json:"file"`
+	Filter string `json:"filter,omitempty"`
+	Wrap   string `json:"wrap,omitempty"`
+}
+
+// MarshalJSON encodes the UV pattern as JSON.
+func (p *UVCheckersPattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonUVCheckersPattern{"checkers", p.Width, p.Height, newJSONTriple(p.A), newJSONTriple(p.B)})
+}
+
+// MarshalJSON encodes the UV pattern as JSON.
+func (p *UVAlignCheckPattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonUVAlignCheckPattern{
+		"align-check",
+		newJSONTriple(p.Main),
+		newJSONTriple(p.UpperLeft),
+		newJSONTriple(p.UpperRight),
+		newJSONTriple(p.BottomLeft),
+		newJSONTriple(p.BottomRight),
+	})
+}
+
+// MarshalJSON encodes the UV pattern as JSON. The image itself isn't encoded, only the name of
+// its file, so the pattern must have been loaded from one.
+func (p *UVImagePattern) MarshalJSON() ([]byte, error) {
+	if p.File == "" {
+		return nil, fmt.Errorf("image UV pattern has no file")
+	}
+
+	return json.Marshal(jsonUVImagePattern{"image", p.File, p.Filter.String(), p.Wrap.String()})
+}
+
+// UnmarshalUVPatternJSON decodes a UVPattern of any type from JSON, using its "type" property
+// to choose which kind of pattern to create.
+func UnmarshalUVPatternJSON(data []byte) (UVPattern, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	color := func(t jsonTriple) Color {
+		return NewColor(t[0], t[1], t[2])
+	}
+
+	switch header.Type {
+	case "checkers":
+		var jp jsonUVCheckersPattern
+		if err := json.Unmarshal(data, &jp); err != nil {
+			return nil, err
+		}
+
+		return NewUVCheckersPattern(jp.Width, jp.Height, color(jp.A), color(jp.B)), nil
+	case "align-check":
+		var jp jsonUVAlignCheckPattern
+		if err := json.Unmarshal(data, &jp); err != nil {
+			return nil, err
+		}
+
+		return NewUVAlignCheckPattern(color(jp.Main), color(jp.UpperLeft), color(jp.UpperRight),
+			color(jp.BottomLeft), color(jp.BottomRight)), nil
+	case "image":
+		var jp jsonUVImagePattern
+		if err := json.Unmarshal(data, &jp); err != nil {
+			return nil, err
+		}
+
+		if jp.File == "" {
+			return nil, fmt.Errorf("image UV pattern has no file")
+		}
+
+		// the image is loaded later by Scene.LoadImages, once the scene's directory is known
+		pattern := &UVImagePattern{Filter: BilinearFilter, Wrap: RepeatWrap, File: jp.File}
+		var err error
+		if jp.Filter != "" {
+			if pattern.Filter, err = ParseTextureFilter(jp.Filter); err != nil {
+				return nil, err
+			}
+		}
+
+		if jp.Wrap != "" {
+			if pattern.Wrap, err = ParseTextureWrap(jp.Wrap); err != nil {
+				return nil, err
+			}
+		}
+
+		return pattern, nil
+	}
+
+	return nil, fmt.Errorf("unknown UV pattern type '%s'", header.Type)
+}
+
+// Returns the identity transformation if none was given, or an error if the transformation isn't 4x4
+// or can't be inverted.
+func validTransform(t Transformation) (Transformation, error) {
+	if t == nil {
+		return NewTransform(), nil
+	}
+
+	if len(t) != 4 {
+		return nil, fmt.Errorf("transform must be a 4x4 matrix")
+	}
+
+	for _, row := range t {
+		if len(row) != 4 {
+			return nil, fmt.Errorf("transform must be a 4x4 matrix")
+		}
+	}
+
+	if !t.IsInvertable() {
+		return nil, fmt.Errorf("transform is not invertible")
+	}
+
+	return t, nil
+}
+
+type jsonSolidEnvironment struct {
+	Type  string     `json:"type"`
+	Color jsonTriple `json:"color"`
+}
+
+type jsonSkyEnvironment struct {
+	Type    string     `json:"type"`
+	Zenith  jsonTriple `json:"zenith"`
+	Horizon jsonTriple `json:"horizon"`
+	Ground  jsonTriple `json:"ground"`
+}
+
+type jsonImageEnvironment struct {
+	Type      string         `json:"type"`
+	File      string         `json:"file"`
+	Intensity float64        `json:"intensity"`
+	Transform Transformation `json:"transform"`
+}
+
+// MarshalJSON encodes the environment as JSON.
+func (e *SolidEnvironment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonSolidEnvironment{"solid", newJSONTriple(e.Color)})
+}
+
+// MarshalJSON encodes the environment as JSON.
+func (e *SkyEnvironment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonSkyEnvironment{"sky", newJSONTriple(e.Zenith), newJSONTriple(e.Horizon), newJSONTriple(e.Ground)})
+}
+
+// MarshalJSON encodes the environment as JSON. The image is referred to by its file name, so
+// the environment must have one.
+func (e *ImageEnvironment) MarshalJSON() ([]byte, error) {
+	if e.File == "" {
+		return nil, fmt.Errorf("image environment has no file")
+	}
+
+	return json.Marshal(jsonImageEnvironment{"image", e.File, e.Intensity, e.Transform})
+}
+
+// UnmarshalEnvironmentJSON decodes an environment from JSON. Image environments are decoded
+// without their images; see Scene.LoadImages.
+func UnmarshalEnvironmentJSON(data []byte) (Environment, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	toColor := func(t jsonTriple) Color {
+		return NewColor(t[0], t[1], t[2])
+	}
+
+	switch header.Type {
+	case "solid":
+		var je jsonSolidEnvironment
+		if err := json.Unmarshal(data, &je); err != nil {
+			return nil, err
+		}
+
+		return NewSolidEnvironment(toColor(je.Color)), nil
+	case "sky":
+		defaults := NewSkyEnvironment()
+		je := jsonSkyEnvironment{
+			Zenith:  newJSONTriple(defaults.Zenith),
+			Horizon: newJSONTriple(defaults.Horizon),
+			Ground:  newJSONTriple(defaults.Ground),
+		}
+
+		if err := json.Unmarshal(data, &je); err != nil {
+			return nil, err
+		}
+
+		return &SkyEnvironment{toColor(je.Zenith), toColor(je.Horizon), toColor(je.Ground)}, nil
+	case "image":
+		je := jsonImageEnvironment{Intensity: 1}
+		if err := json.Unmarshal(data, &je); err != nil {
+			return nil, err
+		}
+
+		if je.File == "" {
+			return nil, fmt.Errorf("image environment has no file")
+		}
+
+		transform, err := validTransform(je.Transform)
+		if err != nil {
+			return nil, err
+		}
+
+		environment := NewImageEnvironment(nil)
+		environment.File = je.File
+		environment.Intensity = je.Intensity
+		environment.Transform = transform
+		return environment, nil
+	}
+
+	return nil, fmt.Errorf("unknown environment type '%s'", header.Type)
+}
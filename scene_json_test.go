@@ -0,0 +1,557 @@
+package rt
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamera_JSON(t *testing.T) {
+	c := NewCamera(160, 120, .5)
+	c.Transform = NewTranslation(1, 2, 3)
+	c.Samples = 4
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	decoded := &Camera{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, c, decoded)
+
+	// the transform defaults to the identity
+	require.NoError(t, json.Unmarshal([]byte(`{"width": 10, "height": 5, "fieldOfView": 1}`), decoded))
+	assert.Equal(t, NewCamera(10, 5, 1), decoded)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"width": 0, "height": 5, "fieldOfView": 1}`), decoded))
+	assert.Error(t, json.Unmarshal([]byte(`{"width": 1, "height": 1, "fieldOfView": 1, "transform": [[1]]}`), decoded))
+}
+
+func TestCamera_JSON_integrator(t *testing.T) {
+	c := NewCamera(10, 5, 1)
+	pt := NewPathTracer()
+	pt.MaxDepth = 4
+	c.Integrator = pt
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	decoded := &Camera{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, c, decoded)
+
+	// omitted settings take their default values
+	require.NoError(t, json.Unmarshal([]byte(`{"width": 1, "height": 1, "fieldOfView": 1, "integrator": {"type": "path"}}`), decoded))
+	assert.Equal(t, NewPathTracer(), decoded.Integrator)
+	require.NoError(t, json.Unmarshal([]byte(`{"width": 1, "height": 1, "fieldOfView": 1, "integrator": {"type": "whitted"}}`), decoded))
+	assert.Equal(t, NewWhittedIntegrator(), decoded.Integrator)
+
+	err = json.Unmarshal([]byte(`{"width": 1, "height": 1, "fieldOfView": 1, "integrator": {"type": "bidirectional"}}`), decoded)
+	assert.EqualError(t, err, "unknown integrator 'bidirectional'")
+	err = json.Unmarshal([]byte(`{"width": 1, "height": 1, "fieldOfView": 1, "integrator": {"type": "whitted", "maxDepth": 2}}`), decoded)
+	assert.EqualError(t, err, "whitted integrator has no depth settings")
+}
+
+func TestCamera_JSON_shutter(t *testing.T) {
+	c := NewCamera(10, 5, 1)
+	c.ShutterOpen, c.ShutterClose = .25, .75
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"shutter":[0.25,0.75]`)
+
+	decoded := &Camera{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, c, decoded)
+
+	err = json.Unmarshal([]byte(`{"width": 1, "height": 1, "fieldOfView": 1, "shutter": [1, 0]}`), decoded)
+	assert.EqualError(t, err, "shutter must open before it closes")
+}
+
+func TestCamera_JSON_sampler(t *testing.T) {
+	c := NewCamera(10, 5, 1)
+	c.Sampler = NewHaltonSampler()
+	c.Seed = 42
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"sampler":"halton","seed":42`)
+
+	decoded := &Camera{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, c, decoded)
+
+	err = json.Unmarshal([]byte(`{"width": 1, "height": 1, "fieldOfView": 1, "sampler": "sobol"}`), decoded)
+	assert.EqualError(t, err, "unknown sampler 'sobol'")
+}
+
+func TestMaterial_JSON(t *testing.T) {
+	m := NewMaterial()
+	m.Color = NewColor(.1, .2, .3)
+	m.Ambient = .4
+	m.Emission = NewColor(2, 1, 0)
+	m.Pattern = NewStripePattern(NewSolidPattern(white), NewSolidPattern(black))
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	decoded := &Material{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, m, decoded)
+
+	// omitted properties take their default values
+	require.NoError(t, json.Unmarshal([]byte(`{"diffuse": 0.5}`), decoded))
+	expected := NewMaterial()
+	expected.Diffuse = .5
+	assert.Equal(t, expected, decoded)
+
+	// materials that don't glow leave out their emission
+	data, err = json.Marshal(NewMaterial())
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "emission")
+}
+
+func TestMaterial_JSON_pbr(t *testing.T) {
+	m := NewMaterial()
+	m.PBR = NewPBRMaterial()
+	m.PBR.BaseColor = NewColor(.9, .6, .2)
+	m.PBR.Metallic = 1
+	m.PBR.RoughnessPattern = NewCheckerPattern(NewSolidPattern(NewColor(.2, .2, .2)), NewSolidPattern(NewColor(.7, .7, .7)))
+	m.PBR.EmissionPattern = NewSolidPattern(NewColor(0, 0, .1))
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+
+	decoded := &Material{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, m, decoded)
+
+	// omitted properties take their default values
+	require.NoError(t, json.Unmarshal([]byte(`{"pbr": {"metallic": 0.25}}`), decoded))
+	expected := NewPBRMaterial()
+	expected.Metallic = .25
+	assert.Equal(t, expected, decoded.PBR)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"pbr": {"roughness": "smooth"}}`), decoded))
+	assert.Error(t, json.Unmarshal([]byte(`{"pbr": {"baseColor": {"type": "plaid"}}}`), decoded))
+}
+
+func TestMaterial_JSON_perturbation(t *testing.T) {
+	normalMap := NewNormalMap(NewUVCheckersPattern(4, 4, NewColor(.5, .5, 1), NewColor(.6, .5, .9)), PlanarMapping)
+	normalMap.Transform = NewScaling(2, 2, 2)
+	normalMap.Strength = .5
+	bumpMap := NewBumpMap(NewMarblePattern(NewSolidPattern(white), NewSolidPattern(black)), .05)
+	bumpMap.Delta = .01
+
+	for _, perturber := range []NormalPerturber{normalMap, bumpMap} {
+		m := NewMaterial()
+		m.Perturber = perturber
+		data, err := json.Marshal(m)
+		require.NoError(t, err)
+
+		decoded := &Material{}
+		require.NoError(t, json.Unmarshal(data, decoded))
+		assert.Equal(t, m, decoded)
+	}
+
+	// omitted numeric properties take their default values
+	perturber, err := UnmarshalPerturberJSON([]byte(`{"type": "bump-map", "pattern": {"type": "solid", "color": [1, 1, 1]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, NewBumpMap(NewSolidPattern(white), 1), perturber)
+
+	_, err = UnmarshalPerturberJSON([]byte(`{"type": "displacement"}`))
+	assert.EqualError(t, err, "unknown perturbation type 'displacement'")
+	_, err = UnmarshalPerturberJSON([]byte(`{"type": "normal-map", "mapping": "planar"}`))
+	assert.EqualError(t, err, "normal-map perturbation has no 'uvPattern' UV pattern")
+	_, err = UnmarshalPerturberJSON([]byte(`{"type": "bump-map"}`))
+	assert.EqualError(t, err, "bump-map perturbation has no 'pattern' pattern")
+}
+
+func TestPattern_JSON(t *testing.T) {
+	inner := NewCheckerPattern(NewSolidPattern(NewColor(1, 0, 0)), NewSolidPattern(NewColor(0, 0, 1)))
+	inner.SetTransform(NewScaling(.5, .5, .5))
+	patterns := []Pattern{
+		NewSolidPattern(NewColor(.5, .5, .5)),
+		NewBlendedPattern(inner, NewSolidPattern(white)),
+		NewStripePattern(NewSolidPattern(white), inner),
+		NewGradientPattern(NewSolidPattern(white), NewSolidPattern(black)),
+		NewRingPattern(NewSolidPattern(white), NewSolidPattern(black)),
+		inner,
+		NewPerturbedPattern(inner, .3),
+		NewTurbulencePattern(NewSolidPattern(white), inner),
+		NewFBMPattern(NewSolidPattern(white), NewSolidPattern(black)),
+		NewMarblePattern(NewSolidPattern(white), NewSolidPattern(black)),
+		NewWoodPattern(NewSolidPattern(white), NewSolidPattern(black)),
+	}
+
+	align := NewUVAlignCheckPattern(white, black, NewColor(1, 0, 0), NewColor(0, 1, 0), NewColor(0, 0, 1))
+	checkers := NewUVCheckersPattern(16, 8, black, white)
+	patterns = append(patterns,
+		NewTextureMapPattern(checkers, CylindricalMapping),
+		NewCubeMapPattern(align, checkers, align, checkers, align, checkers),
+	)
+
+	wood := patterns[len(patterns)-3].(*WoodPattern)
+	wood.Octaves = 3
+	wood.Strength = .25
+
+	for _, p := range patterns {
+		data, err := json.Marshal(p)
+		require.NoError(t, err)
+		decoded, err := UnmarshalPatternJSON(data)
+		require.NoError(t, err)
+		assert.Equal(t, p, decoded)
+	}
+
+	// patterns that are not part of the scene format can't be encoded
+	_, err := json.Marshal(newTestPattern(solidWhite, solidBlack))
+	assert.Error(t, err)
+
+	_, err = UnmarshalPatternJSON([]byte(`{"type": "plaid"}`))
+	assert.EqualError(t, err, "unknown pattern type 'plaid'")
+	_, err = UnmarshalPatternJSON([]byte(`{"type": "stripes", "a": {"type": "solid", "color": [1, 1, 1]}}`))
+	assert.EqualError(t, err, "stripes pattern has no 'b' pattern")
+	_, err = UnmarshalPatternJSON([]byte(`{"type": "perturbed", "pattern": {"type": "solid", "color": [1, 1, 1]}, "size": 1}`))
+	assert.EqualError(t, err, "unknown perturbed pattern property 'size'")
+	_, err = UnmarshalPatternJSON([]byte(`{"type": "texture-map", "mapping": "planar", "uvPattern": {"type": "plaid"}}`))
+	assert.EqualError(t, err, "unknown UV pattern type 'plaid'")
+	_, err = UnmarshalPatternJSON([]byte(`{"type": "texture-map", "mapping": "conical"}`))
+	assert.EqualError(t, err, "unknown UV mapping 'conical'")
+	_, err = UnmarshalPatternJSON([]byte(`{"type": "solid"}`))
+	assert.EqualError(t, err, "solid pattern has no color")
+}
+
+func TestShape_JSON(t *testing.T) {
+	s := NewSphere()
+	s.Transform = NewScaling(1, 2, 3)
+	s.Material.Color = NewColor(1, 0, 0)
+	p := NewPlane()
+	p.Transform = NewTranslation(0, -1, 0)
+
+	for _, shape := range []Shape{s, p} {
+		data, err := json.Marshal(shape)
+		require.NoError(t, err)
+		decoded, err := UnmarshalShapeJSON(data)
+		require.NoError(t, err)
+		assert.Equal(t, shape, decoded)
+	}
+
+	decoded, err := UnmarshalShapeJSON([]byte(`{"type": "sphere"}`))
+	require.NoError(t, err)
+	assert.Equal(t, NewSphere(), decoded)
+
+	_, err = UnmarshalShapeJSON([]byte(`{"type": "teapot"}`))
+	assert.EqualError(t, err, "unknown shape type 'teapot'")
+
+	// moving shapes keep their keyframes
+	s = NewSphere()
+	motion, err := NewKeyframeMotion(Keyframe{0, NewTransform()}, Keyframe{.5, NewTranslation(1, 0, 0)})
+	require.NoError(t, err)
+	s.SetMotion(motion)
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	decoded, err = UnmarshalShapeJSON(data)
+	require.NoError(t, err)
+	assert.Equal(t, s, decoded)
+
+	_, err = UnmarshalShapeJSON([]byte(`{"type": "sphere", "motion": [{"time": 0, "transform": [[1]]}]}`))
+	assert.EqualError(t, err, "transform must be a 4x4 matrix")
+
+	// shapes are placed by inverting their transforms
+	_, err = UnmarshalShapeJSON([]byte(`{"type": "sphere", "transform": [[0, 0, 0, 0], [0, 1, 0, 0], [0, 0, 1, 0], [0, 0, 0, 1]]}`))
+	assert.EqualError(t, err, "transform is not invertible")
+	_, err = UnmarshalShapeJSON([]byte(`{"type": "sphere", "transform": [[1, 1, 0.1, 3], [1, 1, 0.1, -1], [0.1, 0.7, 0, -1], [0, 0, 0, 1]]}`))
+	assert.EqualError(t, err, "transform is not invertible")
+	_, err = UnmarshalShapeJSON([]byte(`{"type": "sphere", "motion": [{"time": 0}, {"time": 1, "transform": [[-1, 0, 0, 0], [0, 1, 0, 0], [0, 0, 1, 0], [0, 0, 0, 1]]}]}`))
+	assert.EqualError(t, err, "transforms at times 0 and 1 can't differ in whether they mirror the shape")
+}
+
+func TestWorld_JSON(t *testing.T) {
+	w := NewDefaultWorld()
+	data, err := json.Marshal(w)
+	require.NoError(t, err)
+
+	decoded := &World{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, w, decoded)
+
+	// a world without a light or objects
+	data, err = json.Marshal(NewWorld())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"objects": []}`, string(data))
+}
+
+func TestSceneJSON_roundTrip(t *testing.T) {
+	scene, err := LoadSceneYAML("scenes/demo.yaml")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "scene")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "demo.json")
+	require.NoError(t, SaveSceneJSON(filename, scene))
+	decoded, err := LoadSceneJSON(filename)
+	require.NoError(t, err)
+	assert.Equal(t, scene, decoded)
+
+	// both scenes render identically
+	c1 := NewCamera(50, 25, scene.Camera.FOV)
+	c1.Transform = scene.Camera.Transform
+	c2 := NewCamera(50, 25, decoded.Camera.FOV)
+	c2.Transform = decoded.Camera.Transform
+	assert.Equal(t, c1.Render(scene.World), c2.Render(decoded.World))
+
+	// scenes must have a camera and a world
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{}`), 0644))
+	_, err = LoadSceneJSON(filename)
+	assert.EqualError(t, err, filename+": scene has no camera")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`{"camera": {"width": 1, "height": 1, "fieldOfView": 1}}`), 0644))
+	_, err = LoadSceneJSON(filename)
+	assert.EqualError(t, err, filename+": scene has no world")
+}
+
+func TestSceneJSON_animation(t *testing.T) {
+	scene, err := LoadSceneYAML("scenes/turntable.yaml")
+	require.NoError(t, err)
+	material, err := NewMaterialTrack(scene.World.Objects[1].GetMaterial(), "color",
+		MaterialKey{0, []float64{1, 0, 0}, LinearEasing{}}, MaterialKey{4, []float64{0, 0, 1}, nil})
+	require.NoError(t, err)
+	scene.Animation.Tracks = append(scene.Animation.Tracks, material)
+
+	data, err := json.Marshal(scene)
+	require.NoError(t, err)
+	decoded := &Scene{}
+	require.NoError(t, json.Unmarshal(data, decoded))
+	assert.Equal(t, scene, decoded)
+
+	// the decoded tracks change the decoded scene
+	decoded.Animation.Apply(2)
+	assert.Equal(t, NewColor(.5, 0, .5), decoded.World.Objects[1].GetMaterial().Color)
+	assert.Equal(t, NewColor(1, 1, 1), scene.World.Objects[1].GetMaterial().Color)
+
+	tests := []struct {
+		animation string
+		expected  string
+	}{
+		{`{"frames": 0, "tracks": []}`, "animation frames must be positive"},
+		{`{"frames": 1, "tracks": [{"target": "sky", "keys": []}]}`, "unknown animation track target 'sky'"},
+		{`{"frames": 1, "tracks": [{"target": "transform", "object": 1, "keys": []}]}`, "transform track must refer to an object of the world"},
+		{`{"frames": 1, "tracks": [{"target": "camera", "keys": [{"time": 0, "from": [0, 0, 0]}]}]}`, "camera track keys must each have a from, to, and up"},
+		{`{"frames": 1, "tracks": [{"target": "camera", "keys": [{"time": 0, "from": [0, 0, 0], "to": [0, 1, 0], "up": [0, 1, 0]}]}]}`, "camera transform is not invertible; is up parallel to the view direction?"},
+		{`{"frames": 1, "tracks": [{"target": "transform", "object": 0, "keys": [{"time": 0, "transform": [[1, 0, 0, 0], [0, 1, 0, 0], [0, 0, 1, 0], [0, 0, 0, 1]]}, {"time": 1, "transform": [[-1, 0, 0, 0], [0, 1, 0, 0], [0, 0, 1, 0], [0, 0, 0, 1]]}]}]}`, "transforms at times 0 and 1 can't differ in whether they mirror the shape"},
+		{`{"frames": 1, "tracks": [{"target": "light", "keys": [{"time": 0}]}]}`, "light track keys must each have a position"},
+		{`{"frames": 1, "tracks": [{"target": "material", "object": 0, "parameter": "color", "keys": [{"time": 0, "value": 1}]}]}`, "material color takes 3 values, got 1"},
+		{`{"frames": 1, "tracks": [{"target": "material", "object": 0, "parameter": "ambient", "keys": [{"time": 0, "value": "high"}]}]}`, "material track values must be numbers or colors"},
+		{`{"frames": 1, "tracks": [{"target": "light", "keys": [{"time": 0, "position": [0, 0, 0], "easing": "bounce"}]}]}`, "unknown easing 'bounce'"},
+		{`{"frames": 1, "tracks": [{"target": "light", "keys": [{"time": 0, "position": [0, 0, 0], "easing": [1]}]}]}`, "easing must be a name or a list of four numbers"},
+	}
+
+	for _, test := range tests {
+		data := `{"camera": {"width": 1, "height": 1, "fieldOfView": 1}, "world": {"light": {"position": [0, 0, 0], "intensity": [1, 1, 1]}, "objects": [{"type": "sphere"}]}, "animation": ` + test.animation + `}`
+		err := json.Unmarshal([]byte(data), decoded)
+		assert.EqualError(t, err, test.expected)
+	}
+
+	// tracks must change the scene they're encoded with
+	other := NewScene(NewCamera(1, 1, 1), NewWorld())
+	other.Animation = NewAnimation(1, 0, 1)
+	other.Animation.Tracks = []Track{&TransformTrack{Shape: NewSphere()}}
+	_, err = json.Marshal(other)
+	assert.Error(t, err)
+}
+
+func TestSceneJSON_images(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scene")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, newTestTexture().Save(filepath.Join(dir, "texture.ppm")))
+	image, err := LoadUVImagePattern(filepath.Join(dir, "texture.ppm"))
+	require.NoError(t, err)
+	image.File = "texture.ppm"
+	image.Filter = NearestFilter
+	image.Wrap = MirrorWrap
+
+	sphere := NewSphere()
+	sphere.Material.Pattern = NewTextureMapPattern(image, PlanarMapping)
+	world := NewWorld()
+	world.Light = NewPointLight(NewPoint(0, 10, 0), white)
+	world.Objects = append(world.Objects, sphere)
+	scene := NewScene(NewCamera(10, 10, 1), world)
+
+	// images are saved by name and loaded relative to the scene file
+	filename := filepath.Join(dir, "scene.json")
+	require.NoError(t, SaveSceneJSON(filename, scene))
+	decoded, err := LoadSceneJSON(filename)
+	require.NoError(t, err)
+	assert.Equal(t, scene, decoded)
+
+	// decoding alone leaves the image to be loaded later
+	data, err := json.Marshal(image)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "image", "file": "texture.ppm", "filter": "nearest", "wrap": "mirror"}`, string(data))
+	decodedImage, err := UnmarshalUVPatternJSON([]byte(`{"type": "image", "file": "texture.ppm"}`))
+	require.NoError(t, err)
+	assert.Equal(t, &UVImagePattern{Filter: BilinearFilter, Wrap: RepeatWrap, File: "texture.ppm"}, decodedImage)
+
+	// images that weren't loaded from a file can't be saved
+	_, err = json.Marshal(NewUVImagePattern(newTestTexture()))
+	assert.Error(t, err)
+
+	_, err = UnmarshalUVPatternJSON([]byte(`{"type": "image"}`))
+	assert.EqualError(t, err, "image UV pattern has no file")
+	_, err = UnmarshalUVPatternJSON([]byte(`{"type": "image", "file": "texture.ppm", "wrap": "border"}`))
+	assert.EqualError(t, err, "unknown texture wrap mode 'border'")
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "texture.ppm")))
+	_, err = LoadSceneJSON(filename)
+	assert.Error(t, err)
+}
+
+func TestWorld_JSON_environment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scene")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sky := NewSkyEnvironment()
+	sky.Ground = NewColor(.1, .2, .1)
+	for _, environment := range []Environment{NewSolidEnvironment(NewColor(.1, .2, .3)), sky} {
+		world := NewWorld()
+		world.Environment = environment
+		data, err := json.Marshal(world)
+		require.NoError(t, err)
+		decoded := &World{}
+		require.NoError(t, json.Unmarshal(data, decoded))
+		assert.Equal(t, world, decoded)
+	}
+
+	// omitted sky colors take their default values
+	decoded, err := UnmarshalEnvironmentJSON([]byte(`{"type": "sky", "zenith": [0, 0, 1]}`))
+	require.NoError(t, err)
+	expected := NewSkyEnvironment()
+	expected.Zenith = NewColor(0, 0, 1)
+	assert.Equal(t, expected, decoded)
+
+	// images are saved by name and loaded relative to the scene file
+	canvas := NewCanvas(2, 1)
+	canvas.WritePixel(0, 0, NewColor(8, 4, 2))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sky.hdr"), encodeHDR(canvas, false), 0644))
+	image, err := LoadImageEnvironment(filepath.Join(dir, "sky.hdr"))
+	require.NoError(t, err)
+	image.File = "sky.hdr"
+	image.Intensity = 2
+	image.Transform = NewRotationY(1)
+	world := NewWorld()
+	world.Environment = image
+	scene := NewScene(NewCamera(10, 10, 1), world)
+	filename := filepath.Join(dir, "scene.json")
+	require.NoError(t, SaveSceneJSON(filename, scene))
+	loaded, err := LoadSceneJSON(filename)
+	require.NoError(t, err)
+	assert.Equal(t, scene, loaded)
+
+	decoded, err = UnmarshalEnvironmentJSON([]byte(`{"type": "image", "file": "sky.hdr"}`))
+	require.NoError(t, err)
+	assert.Equal(t, &ImageEnvironment{File: "sky.hdr", Intensity: 1, Transform: NewTransform()}, decoded)
+
+	_, err = json.Marshal(NewImageEnvironment(canvas))
+	assert.Error(t, err)
+	_, err = UnmarshalEnvironmentJSON([]byte(`{"type": "image"}`))
+	assert.EqualError(t, err, "image environment has no file")
+	_, err = UnmarshalEnvironmentJSON([]byte(`{"type": "starfield"}`))
+	assert.EqualError(t, err, "unknown environment type 'starfield'")
+	_, err = UnmarshalEnvironmentJSON([]byte(`{"type": "image", "file": "sky.hdr", "transform": [[1]]}`))
+	assert.EqualError(t, err, "transform must be a 4x4 matrix")
+	_, err = json.Marshal(&World{Environment: unsampledEnvironment{sky}})
+	assert.Error(t, err)
+}
+
+func TestSceneSchema(t *testing.T) {
+	data, err := ioutil.ReadFile("schema/scene.schema.json")
+	require.NoError(t, err)
+
+	var schema struct {
+		Definitions struct {
+			Shape struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"shape"`
+			WrapperPattern struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"wrapperPattern"`
+			CompositePattern struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"compositePattern"`
+			Integrator struct {
+				Properties struct {
+					Type struct {
+						Enum []string `json:"enum"`
+					} `json:"type"`
+				} `json:"properties"`
+			} `json:"integrator"`
+			Track struct {
+				Properties struct {
+					Parameter struct {
+						Enum []string `json:"enum"`
+					} `json:"parameter"`
+				} `json:"properties"`
+			} `json:"track"`
+			Easing struct {
+				OneOf []struct {
+					Enum []string `json:"enum"`
+				} `json:"oneOf"`
+			} `json:"easing"`
+		} `json:"definitions"`
+	}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	// the schema lists every type the decoder supports
+	keys := func(m map[string]bool) []string {
+		list := []string{}
+		for k := range m {
+			list = append(list, k)
+		}
+
+		sort.Strings(list)
+		return list
+	}
+
+	shapes := map[string]bool{}
+	for name := range sceneShapes {
+		shapes[name] = true
+	}
+
+	patterns := map[string]bool{}
+	for name := range scenePatterns {
+		patterns[name] = true
+	}
+
+	wrappers := map[string]bool{}
+	for name := range sceneWrapperPatterns {
+		wrappers[name] = true
+	}
+
+	assert.Equal(t, keys(shapes), schema.Definitions.Shape.Properties.Type.Enum)
+	assert.Equal(t, keys(wrappers), schema.Definitions.WrapperPattern.Properties.Type.Enum)
+	assert.Equal(t, keys(patterns), schema.Definitions.CompositePattern.Properties.Type.Enum)
+	assert.Equal(t, IntegratorNames, schema.Definitions.Integrator.Properties.Type.Enum)
+	assert.Equal(t, MaterialParameters, schema.Definitions.Track.Properties.Parameter.Enum)
+
+	easings := map[string]bool{}
+	for name := range sceneEasings {
+		easings[name] = true
+	}
+
+	assert.Equal(t, keys(easings), schema.Definitions.Easing.OneOf[0].Enum)
+}
@@ -0,0 +1,50 @@
+package rt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadScene(t *testing.T) {
+	scene, err := LoadScene("scenes/demo.yaml")
+	require.NoError(t, err)
+	assert.Len(t, scene.World.Objects, 4)
+
+	_, err = LoadScene("scenes/demo.obj")
+	assert.EqualError(t, err, "scenes/demo.obj: unsupported scene format; use .yaml, .yml, or .json")
+}
+
+func TestSceneError_Error(t *testing.T) {
+	err := &SceneError{"scene.yaml", 3, 7, "something is wrong"}
+	assert.Equal(t, "scene.yaml:3:7: something is wrong", err.Error())
+}
+
+func TestScene_LoadImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scene")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, newTestTexture().Save(filepath.Join(dir, "texture.png")))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sky.hdr"), encodeHDR(newTestTexture(), false), 0644))
+
+	// images are found throughout materials and the environment
+	image := &UVImagePattern{File: "texture.png"}
+	sphere := NewSphere()
+	sphere.Material.PBR = NewPBRMaterial()
+	sphere.Material.PBR.RoughnessPattern = NewTextureMapPattern(image, SphericalMapping)
+	world := NewWorld()
+	world.AddObjects(sphere)
+	world.Environment = &ImageEnvironment{File: "sky.hdr"}
+	scene := NewScene(NewCamera(1, 1, 1), world)
+	require.NoError(t, scene.LoadImages(dir))
+	assert.Equal(t, 2, image.Canvas.Width())
+	assert.Equal(t, 2, world.Environment.(*ImageEnvironment).Canvas.Width())
+
+	world.Environment = &ImageEnvironment{File: "missing.hdr"}
+	assert.Error(t, scene.LoadImages(dir))
+}
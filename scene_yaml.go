@@ -0,0 +1,1606 @@
+package rt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadSceneYAML reads and parses a YAML scene description file.
+func LoadSceneYAML(filename string) (*Scene, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSceneYAML(filename, data)
+}
+
+// ParseSceneYAML parses a YAML scene description. The filename is used for error messages and
+// to find images, which are loaded relative to the scene file.
+//
+// A scene is a list of commands. An "add" command adds a camera, a light, or a shape, and a
+// "define" command names a material, pattern, or transform so that later commands can refer to it.
+// A definition may "extend" an earlier one. Transforms are applied in the order they are listed.
+// The camera, light, and shapes may "animate" through a list of keys, once the scene adds an
+// animation. See scenes/demo.yaml and scenes/turntable.yaml for examples.
+func ParseSceneYAML(filename string, data []byte) (*Scene, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	p := &yamlSceneParser{
+		filename: filename,
+		defs:     map[string]*yaml.Node{},
+		world:    NewWorld(),
+	}
+
+	if err := p.parseDocument(&doc); err != nil {
+		return nil, err
+	}
+
+	scene := NewScene(p.camera, p.world)
+	scene.Animation = p.animation
+	return scene, nil
+}
+
+type yamlSceneParser struct {
+	filename string
+	defs     map[string]*yaml.Node
+	camera   *Camera
+	world    *World
+
+	// animation is set by the animation command, and tracks are added by the objects' keys
+	animation    *Animation
+	animationEnd bool
+	tracks       []Track
+	lastKeyTime  float64
+	firstAnimate *yaml.Node
+}
+
+func (p *yamlSceneParser) errorf(node *yaml.Node, format string, args ...interface{}) error {
+	return &SceneError{p.filename, node.Line, node.Column, fmt.Sprintf(format, args...)}
+}
+
+func (p *yamlSceneParser) parseDocument(doc *yaml.Node) error {
+	if doc.Kind == 0 {
+		return &SceneError{p.filename, 1, 1, "scene is empty"}
+	}
+
+	root := doc
+	if root.Kind == yaml.DocumentNode {
+		root = root.Content[0]
+	}
+
+	if root.Kind != yaml.SequenceNode {
+		return p.errorf(root, "scene must be a list of commands")
+	}
+
+	for _, command := range root.Content {
+		if err := p.parseCommand(command); err != nil {
+			return err
+		}
+	}
+
+	if p.camera == nil {
+		return p.errorf(root, "scene does not add a camera")
+	}
+
+	if p.world.Light == nil && p.world.Environment == nil && len(p.world.emitters()) == 0 {
+		return p.errorf(root, "scene does not add a light, an environment, or an emissive object")
+	}
+
+	if p.animation == nil {
+		if p.firstAnimate != nil {
+			return p.errorf(p.firstAnimate, "scene animates objects but does not add an animation")
+		}
+
+		return nil
+	}
+
+	// the animation ends at the last key unless it says otherwise
+	p.animation.Tracks = p.tracks
+	if !p.animationEnd {
+		p.animation.End = math.Max(p.animation.Start, p.lastKeyTime)
+	}
+
+	return nil
+}
+
+func (p *yamlSceneParser) parseCommand(node *yaml.Node) error {
+	fields, err := p.mapping(node)
+	if err != nil {
+		return err
+	}
+
+	if what, ok := fields.get("add"); ok {
+		return p.parseAdd(node, what, fields)
+	}
+
+	if name, ok := fields.get("define"); ok {
+		return p.parseDefine(node, name, fields)
+	}
+
+	return p.errorf(node, "command must be either 'add' or 'define'")
+}
+
+func (p *yamlSceneParser) parseAdd(node *yaml.Node, what *yaml.Node, fields yamlFields) error {
+	kind, err := p.string(what)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "camera":
+		return p.parseCamera(node, fields)
+	case "light":
+		return p.parseLight(node, fields)
+	case "environment":
+		return p.parseEnvironment(node, fields)
+	case "animation":
+		return p.parseAnimation(node, fields)
+	}
+
+	newShape, ok := sceneShapes[kind]
+	if !ok {
+		return p.errorf(what, "unknown object type '%s'", kind)
+	}
+
+	shape := newShape()
+	var endTransform Transformation
+	for _, field := range fields {
+		key, value := field.key, field.value
+		switch key {
+		case "add", "animate":
+		case "end-transform":
+			if _, ok := fields.get("keyframes"); ok {
+				return p.errorf(value, "%s can't have both end-transform and keyframes", kind)
+			}
+
+			if endTransform, err = p.transform(value); err != nil {
+				return err
+			}
+		case "keyframes":
+			motion, err := p.keyframes(value)
+			if err != nil {
+				return err
+			}
+
+			shape.SetMotion(motion)
+		case "material":
+			material, err := p.material(value)
+			if err != nil {
+				return err
+			}
+
+			shape.SetMaterial(material)
+		case "transform":
+			transform, err := p.transform(value)
+			if err != nil {
+				return err
+			}
+
+			shape.SetTransform(transform)
+		default:
+			return p.errorf(value, "unknown %s attribute '%s'", kind, key)
+		}
+	}
+
+	// the shape moves from its transform at time 0 to its end transform at time 1
+	if endTransform != nil {
+		motion, err := NewMotion(shape.GetTransform(), endTransform)
+		if err != nil {
+			value, _ := fields.get("end-transform")
+			return p.errorf(value, "%v", err)
+		}
+
+		shape.SetMotion(motion)
+	}
+
+	if animate, ok := fields.get("animate"); ok {
+		if err := p.shapeTracks(animate, shape, kind); err != nil {
+			return err
+		}
+	}
+
+	p.world.AddObjects(shape)
+	return nil
+}
+
+// Parses a list of keyframes, each with a time and a transform.
+func (p *yamlSceneParser) keyframes(node *yaml.Node) (*Motion, error) {
+	node, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return nil, p.errorf(node, "keyframes must be a list")
+	}
+
+	var keyframes []Keyframe
+	for _, item := range node.Content {
+		fields, err := p.mapping(item)
+		if err != nil {
+			return nil, err
+		}
+
+		keyframe := Keyframe{Transform: NewTransform()}
+		if _, ok := fields.get("time"); !ok {
+			return nil, p.errorf(item, "keyframe has no time")
+		}
+
+		for _, field := range fields {
+			key, value := field.key, field.value
+			switch key {
+			case "time":
+				keyframe.Time, err = p.float(value)
+			case "transform":
+				keyframe.Transform, err = p.transform(value)
+			default:
+				err = p.errorf(value, "unknown keyframe attribute '%s'", key)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		keyframes = append(keyframes, keyframe)
+	}
+
+	motion, err := NewKeyframeMotion(keyframes...)
+	if err != nil {
+		return nil, p.errorf(node, "%v", err)
+	}
+
+	return motion, nil
+}
+
+func (p *yamlSceneParser) parseCamera(node *yaml.Node, fields yamlFields) error {
+	if p.camera != nil {
+		return p.errorf(node, "scene already has a camera")
+	}
+
+	var width, height int
+	var fov float64
+	var integrator Integrator
+	var shutter [2]float64
+	var sampler Sampler
+	var seed int
+	samples := 1
+	from, to, up := NewPoint(0, 0, 0), NewPoint(0, 0, -1), NewVector(0, 1, 0)
+	for _, field := range fields {
+		key, value := field.key, field.value
+		var err error
+		switch key {
+		case "add", "animate":
+		case "width":
+			width, err = p.int(value)
+		case "height":
+			height, err = p.int(value)
+		case "field-of-view":
+			fov, err = p.float(value)
+		case "from":
+			from, err = p.point(value)
+		case "to":
+			to, err = p.point(value)
+		case "up":
+			up, err = p.vector(value)
+		case "samples":
+			if samples, err = p.int(value); err == nil && samples <= 0 {
+				err = p.errorf(value, "camera samples must be positive")
+			}
+		case "integrator":
+			integrator, err = p.integrator(value)
+		case "shutter":
+			shutter, err = p.shutter(value)
+		case "sampler":
+			sampler, err = p.sampler(value)
+		case "seed":
+			seed, err = p.int(value)
+		default:
+			err = p.errorf(value, "unknown camera attribute '%s'", key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, key := range []string{"width", "height", "field-of-view"} {
+		if _, ok := fields.get(key); !ok {
+			return p.errorf(node, "camera is missing '%s'", key)
+		}
+	}
+
+	if width <= 0 || height <= 0 {
+		return p.errorf(node, "camera width and height must be positive")
+	}
+
+	transform, err := p.viewTransform(node, from, to, up)
+	if err != nil {
+		return err
+	}
+
+	p.camera = NewCamera(width, height, fov)
+	p.camera.Transform = transform
+	p.camera.Samples = samples
+	p.camera.Integrator = integrator
+	p.camera.ShutterOpen, p.camera.ShutterClose = shutter[0], shutter[1]
+	p.camera.Sampler = sampler
+	p.camera.Seed = int64(seed)
+	if animate, ok := fields.get("animate"); ok {
+		return p.cameraTrack(animate, from, to, up)
+	}
+
+	return nil
+}
+
+// Parses the times a camera's shutter opens and closes.
+func (p *yamlSceneParser) shutter(node *yaml.Node) ([2]float64, error) {
+	var times [2]float64
+	if node.Kind != yaml.SequenceNode || len(node.Content) != 2 {
+		return times, p.errorf(node, "expected a list of two times")
+	}
+
+	for i, item := range node.Content {
+		f, err := p.float(item)
+		if err != nil {
+			return times, err
+		}
+
+		times[i] = f
+	}
+
+	if times[1] < times[0] {
+		return times, p.errorf(node, "shutter must open before it closes")
+	}
+
+	return times, nil
+}
+
+// Parses the name of a sampler.
+func (p *yamlSceneParser) sampler(node *yaml.Node) (Sampler, error) {
+	name, err := p.string(node)
+	if err != nil {
+		return nil, err
+	}
+
+	sampler, err := NewSampler(name)
+	if err != nil {
+		return nil, p.errorf(node, "%v", err)
+	}
+
+	return sampler, nil
+}
+
+// Parses an integrator, which is either the name of one with default settings or a mapping
+// with the name as its type along with its settings.
+func (p *yamlSceneParser) integrator(node *yaml.Node) (Integrator, error) {
+	typeNode := node
+	var fields yamlFields
+	if node.Kind == yaml.MappingNode {
+		var err error
+		if fields, err = p.mapping(node); err != nil {
+			return nil, err
+		}
+
+		var ok bool
+		if typeNode, ok = fields.get("type"); !ok {
+			return nil, p.errorf(node, "integrator has no type")
+		}
+	}
+
+	name, err := p.string(typeNode)
+	if err != nil {
+		return nil, err
+	}
+
+	integrator, err := NewIntegrator(name)
+	if err != nil {
+		return nil, p.errorf(typeNode, "%v", err)
+	}
+
+	for _, field := range fields {
+		key, value := field.key, field.value
+		pt, isPathTracer := integrator.(*PathTracer)
+		switch {
+		case key == "type":
+		case key == "max-depth" && isPathTracer:
+			pt.MaxDepth, err = p.int(value)
+		case key == "roulette-depth" && isPathTracer:
+			pt.RouletteDepth, err = p.int(value)
+		default:
+			err = p.errorf(value, "unknown %s integrator attribute '%s'", name, key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return integrator, nil
+}
+
+func (p *yamlSceneParser) parseLight(node *yaml.Node, fields yamlFields) error {
+	if p.world.Light != nil {
+		return p.errorf(node, "scene already has a light; only one light is supported")
+	}
+
+	position, intensity := Origin(), NewColor(1, 1, 1)
+	for _, field := range fields {
+		key, value := field.key, field.value
+		var err error
+		switch key {
+		case "add", "animate":
+		case "at":
+			position, err = p.point(value)
+		case "intensity":
+			intensity, err = p.color(value)
+		default:
+			err = p.errorf(value, "unknown light attribute '%s'", key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	p.world.Light = NewPointLight(position, intensity)
+	if animate, ok := fields.get("animate"); ok {
+		return p.lightTrack(animate)
+	}
+
+	return nil
+}
+
+// Parses the animation's frames and the times it starts and ends.
+func (p *yamlSceneParser) parseAnimation(node *yaml.Node, fields yamlFields) error {
+	if p.animation != nil {
+		return p.errorf(node, "scene already has an animation")
+	}
+
+	if _, ok := fields.get("frames"); !ok {
+		return p.errorf(node, "animation is missing 'frames'")
+	}
+
+	animation := NewAnimation(0, 0, 0)
+	for _, field := range fields {
+		key, value := field.key, field.value
+		var err error
+		switch key {
+		case "add":
+		case "frames":
+			if animation.Frames, err = p.int(value); err == nil && animation.Frames <= 0 {
+				err = p.errorf(value, "animation frames must be positive")
+			}
+		case "start":
+			animation.Start, err = p.float(value)
+		case "end":
+			animation.End, err = p.float(value)
+			p.animationEnd = true
+		default:
+			err = p.errorf(value, "unknown animation attribute '%s'", key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.animationEnd && animation.End < animation.Start {
+		return p.errorf(node, "animation must end after it starts")
+	}
+
+	p.animation = animation
+	return nil
+}
+
+// A yamlKey is a key of an object's animation: a time, the easing toward the next key, and the
+// attributes the key sets.
+type yamlKey struct {
+	node   *yaml.Node
+	time   float64
+	easing Easing
+	fields yamlFields
+}
+
+// Parses a list of animation keys for an object, which may set only the given attributes.
+func (p *yamlSceneParser) animationKeys(node *yaml.Node, object string, attributes ...string) ([]yamlKey, error) {
+	if p.firstAnimate == nil {
+		p.firstAnimate = node
+	}
+
+	node, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return nil, p.errorf(node, "animate must be a list of keys")
+	}
+
+	keys := make([]yamlKey, len(node.Content))
+	for i, item := range node.Content {
+		fields, err := p.mapping(item)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := fields.get("time"); !ok {
+			return nil, p.errorf(item, "animation key has no time")
+		}
+
+		key := &keys[i]
+		key.node = item
+		for _, field := range fields {
+			switch field.key {
+			case "time":
+				key.time, err = p.float(field.value)
+				p.lastKeyTime = math.Max(p.lastKeyTime, key.time)
+			case "easing":
+				key.easing, err = p.easing(field.value)
+			default:
+				err = p.errorf(field.value, "unknown %s key attribute '%s'", object, field.key)
+				for _, attribute := range attributes {
+					if field.key == attribute {
+						key.fields = append(key.fields, field)
+						err = nil
+					}
+				}
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return keys, nil
+}
+
+// Parses an easing, which is either the name of one or a list of the control points of a
+// Bézier curve.
+func (p *yamlSceneParser) easing(node *yaml.Node) (Easing, error) {
+	if node.Kind == yaml.ScalarNode {
+		newEasing, ok := sceneEasings[node.Value]
+		if !ok {
+			return nil, p.errorf(node, "unknown easing '%s'", node.Value)
+		}
+
+		return newEasing(), nil
+	}
+
+	if node.Kind != yaml.SequenceNode || len(node.Content) != 4 {
+		return nil, p.errorf(node, "easing must be a name or a list of four numbers")
+	}
+
+	var points [4]float64
+	for i, item := range node.Content {
+		f, err := p.float(item)
+		if err != nil {
+			return nil, err
+		}
+
+		points[i] = f
+	}
+
+	return NewBezierEasing(points[0], points[1], points[2], points[3]), nil
+}
+
+// Returns the camera's view transform from a viewpoint, which must be invertible.
+func (p *yamlSceneParser) viewTransform(node *yaml.Node, from Tuple, to Tuple, up Tuple) (Transformation, error) {
+	transform := NewViewTransform(from, to, up)
+	if !transform.IsInvertable() {
+		return nil, p.errorf(node, "camera transform is not invertible; is up parallel to the view direction?")
+	}
+
+	return transform, nil
+}
+
+// Parses the keys of the camera's animation, whose viewpoints default to the camera's own.
+func (p *yamlSceneParser) cameraTrack(node *yaml.Node, from Tuple, to Tuple, up Tuple) error {
+	keys, err := p.animationKeys(node, "camera", "from", "to", "up")
+	if err != nil {
+		return err
+	}
+
+	cameraKeys := make([]CameraKey, len(keys))
+	for i, key := range keys {
+		ck := CameraKey{key.time, from, to, up, key.easing}
+		for _, field := range key.fields {
+			switch field.key {
+			case "from":
+				ck.From, err = p.point(field.value)
+			case "to":
+				ck.To, err = p.point(field.value)
+			case "up":
+				ck.Up, err = p.vector(field.value)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := p.viewTransform(key.node, ck.From, ck.To, ck.Up); err != nil {
+			return err
+		}
+
+		cameraKeys[i] = ck
+	}
+
+	p.tracks = append(p.tracks, NewCameraTrack(p.camera, cameraKeys...))
+	return nil
+}
+
+// Parses the keys of the light's animation.
+func (p *yamlSceneParser) lightTrack(node *yaml.Node) error {
+	keys, err := p.animationKeys(node, "light", "at")
+	if err != nil {
+		return err
+	}
+
+	var lightKeys []LightKey
+	for _, key := range keys {
+		if value, ok := key.fields.get("at"); ok {
+			position, err := p.point(value)
+			if err != nil {
+				return err
+			}
+
+			lightKeys = append(lightKeys, LightKey{key.time, position, key.easing})
+		}
+	}
+
+	if len(lightKeys) > 0 {
+		p.tracks = append(p.tracks, NewLightTrack(p.world.Light, lightKeys...))
+	}
+
+	return nil
+}
+
+// Parses the keys of a shape's animation, which may change its transform and the parameters
+// of its material. Each property is animated through the keys that set it.
+func (p *yamlSceneParser) shapeTracks(node *yaml.Node, shape Shape, kind string) error {
+	keys, err := p.animationKeys(node, kind, "transform", "material")
+	if err != nil {
+		return err
+	}
+
+	var transformKeys []TransformKey
+	materialKeys := map[string][]MaterialKey{}
+	for _, key := range keys {
+		if value, ok := key.fields.get("transform"); ok {
+			transform, err := p.transform(value)
+			if err != nil {
+				return err
+			}
+
+			transformKeys = append(transformKeys, TransformKey{key.time, transform, key.easing})
+		}
+
+		value, ok := key.fields.get("material")
+		if !ok {
+			continue
+		}
+
+		fields, err := p.mapping(value)
+		if err != nil {
+			return err
+		}
+
+		for _, field := range fields {
+			var value []float64
+			switch size, _ := materialParameterSize(field.key); size {
+			case 1:
+				var f float64
+				f, err = p.float(field.value)
+				value = []float64{f}
+			case 3:
+				var triple [3]float64
+				triple, err = p.triple(field.value)
+				value = triple[:]
+			default:
+				err = p.errorf(field.value, "unknown animated material attribute '%s'", field.key)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			materialKeys[field.key] = append(materialKeys[field.key], MaterialKey{key.time, value, key.easing})
+		}
+	}
+
+	if len(transformKeys) > 0 {
+		track, err := NewTransformTrack(shape, transformKeys...)
+		if err != nil {
+			return p.errorf(node, "%v", err)
+		}
+
+		p.tracks = append(p.tracks, track)
+	}
+
+	// tracks are added in a fixed order, so scenes always animate the same way
+	for _, parameter := range MaterialParameters {
+		if keys, ok := materialKeys[parameter]; ok {
+			track, err := NewMaterialTrack(shape.GetMaterial(), parameter, keys...)
+			if err != nil {
+				return err
+			}
+
+			p.tracks = append(p.tracks, track)
+		}
+	}
+
+	return nil
+}
+
+// Parses the environment, which is a solid color, a sky gradient, or an image.
+func (p *yamlSceneParser) parseEnvironment(node *yaml.Node, fields yamlFields) error {
+	if p.world.Environment != nil {
+		return p.errorf(node, "scene already has an environment")
+	}
+
+	typeNode, ok := fields.get("type")
+	if !ok {
+		return p.errorf(node, "environment has no type")
+	}
+
+	environmentType, err := p.string(typeNode)
+	if err != nil {
+		return err
+	}
+
+	solid := NewSolidEnvironment(NewColor(0, 0, 0))
+	sky := NewSkyEnvironment()
+	var image *ImageEnvironment
+	switch environmentType {
+	case "solid":
+		p.world.Environment = solid
+	case "sky":
+		p.world.Environment = sky
+	case "image":
+		fileNode, ok := fields.get("file")
+		if !ok {
+			return p.errorf(node, "image environment has no file")
+		}
+
+		file, err := p.string(fileNode)
+		if err != nil {
+			return err
+		}
+
+		canvas, err := LoadCanvas(resolvePath(filepath.Dir(p.filename), file))
+		if err != nil {
+			return p.errorf(fileNode, "%v", err)
+		}
+
+		image = NewImageEnvironment(canvas)
+		image.File = file
+		p.world.Environment = image
+	default:
+		return p.errorf(typeNode, "unknown environment type '%s'", environmentType)
+	}
+
+	for _, field := range fields {
+		key, value := field.key, field.value
+		switch {
+		case key == "add", key == "type":
+		case key == "color" && environmentType == "solid":
+			solid.Color, err = p.color(value)
+		case key == "zenith" && environmentType == "sky":
+			sky.Zenith, err = p.color(value)
+		case key == "horizon" && environmentType == "sky":
+			sky.Horizon, err = p.color(value)
+		case key == "ground" && environmentType == "sky":
+			sky.Ground, err = p.color(value)
+		case key == "file" && image != nil:
+		case key == "intensity" && image != nil:
+			image.Intensity, err = p.float(value)
+		case key == "transform" && image != nil:
+			image.Transform, err = p.transform(value)
+		default:
+			err = p.errorf(value, "unknown %s environment attribute '%s'", environmentType, key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *yamlSceneParser) parseDefine(node *yaml.Node, nameNode *yaml.Node, fields yamlFields) error {
+	name, err := p.string(nameNode)
+	if err != nil {
+		return err
+	}
+
+	value, ok := fields.get("value")
+	if !ok {
+		return p.errorf(node, "definition '%s' has no value", name)
+	}
+
+	for _, field := range fields {
+		if field.key != "define" && field.key != "value" && field.key != "extend" {
+			return p.errorf(field.value, "unknown definition attribute '%s'", field.key)
+		}
+	}
+
+	if extend, ok := fields.get("extend"); ok {
+		base, err := p.resolve(extend)
+		if err != nil {
+			return err
+		}
+
+		if value, err = p.extend(base, value); err != nil {
+			return err
+		}
+	}
+
+	p.defs[name] = value
+	return nil
+}
+
+// Combines a definition with the one it extends. Mappings are merged with the extending
+// values taking precedence; lists are concatenated with the base list first.
+func (p *yamlSceneParser) extend(base *yaml.Node, value *yaml.Node) (*yaml.Node, error) {
+	value, err := p.resolve(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Kind != value.Kind {
+		return nil, p.errorf(value, "cannot extend a definition of a different kind")
+	}
+
+	merged := *value
+	switch value.Kind {
+	case yaml.MappingNode:
+		merged.Content = nil
+		overridden := map[string]bool{}
+		for i := 0; i < len(value.Content); i += 2 {
+			overridden[value.Content[i].Value] = true
+		}
+
+		for i := 0; i < len(base.Content); i += 2 {
+			if !overridden[base.Content[i].Value] {
+				merged.Content = append(merged.Content, base.Content[i], base.Content[i+1])
+			}
+		}
+
+		merged.Content = append(merged.Content, value.Content...)
+	case yaml.SequenceNode:
+		merged.Content = append(append([]*yaml.Node{}, base.Content...), value.Content...)
+	default:
+		return nil, p.errorf(value, "only mappings and lists can be extended")
+	}
+
+	return &merged, nil
+}
+
+// Returns the node a definition name refers to, or the node itself if it isn't a name.
+func (p *yamlSceneParser) resolve(node *yaml.Node) (*yaml.Node, error) {
+	if node.Kind != yaml.ScalarNode {
+		return node, nil
+	}
+
+	def, ok := p.defs[node.Value]
+	if !ok {
+		return nil, p.errorf(node, "undefined name '%s'", node.Value)
+	}
+
+	return def, nil
+}
+
+func (p *yamlSceneParser) material(node *yaml.Node) (*Material, error) {
+	fields, err := p.mapping(node)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewMaterial()
+	for _, field := range fields {
+		key, value := field.key, field.value
+		switch key {
+		case "color":
+			m.Color, err = p.color(value)
+		case "ambient":
+			m.Ambient, err = p.float(value)
+		case "diffuse":
+			m.Diffuse, err = p.float(value)
+		case "specular":
+			m.Specular, err = p.float(value)
+		case "shininess":
+			m.Shininess, err = p.float(value)
+		case "emission":
+			m.Emission, err = p.color(value)
+		case "pattern":
+			m.Pattern, err = p.pattern(value)
+		case "perturbation":
+			m.Perturber, err = p.perturbation(value)
+		case "pbr":
+			m.PBR, err = p.pbrMaterial(value)
+		default:
+			err = p.errorf(value, "unknown material attribute '%s'", key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Parses a physically based material. Each property is either a value or a pattern.
+func (p *yamlSceneParser) pbrMaterial(node *yaml.Node) (*PBRMaterial, error) {
+	fields, err := p.mapping(node)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewPBRMaterial()
+	for _, field := range fields {
+		key, value := field.key, field.value
+		switch key {
+		case "base-color":
+			m.BaseColorPattern, err = p.colorOrPattern(value, &m.BaseColor)
+		case "metallic":
+			m.MetallicPattern, err = p.floatOrPattern(value, &m.Metallic)
+		case "roughness":
+			m.RoughnessPattern, err = p.floatOrPattern(value, &m.Roughness)
+		case "emission":
+			m.EmissionPattern, err = p.colorOrPattern(value, &m.Emission)
+		default:
+			err = p.errorf(value, "unknown pbr material attribute '%s'", key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Parses either a color, which is stored in color, or a pattern, which is returned.
+func (p *yamlSceneParser) colorOrPattern(node *yaml.Node, color *Color) (Pattern, error) {
+	resolved, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolved.Kind == yaml.SequenceNode {
+		*color, err = p.color(resolved)
+		return nil, err
+	}
+
+	return p.pattern(resolved)
+}
+
+// Parses either a number, which is stored in value, or a pattern, which is returned.
+func (p *yamlSceneParser) floatOrPattern(node *yaml.Node, value *float64) (Pattern, error) {
+	if def, ok := p.defs[node.Value]; ok && node.Kind == yaml.ScalarNode {
+		node = def
+	}
+
+	if node.Kind == yaml.ScalarNode {
+		var err error
+		*value, err = p.float(node)
+		return nil, err
+	}
+
+	return p.pattern(node)
+}
+
+// Parses a normal perturbation, which is either a normal map or a bump map.
+func (p *yamlSceneParser) perturbation(node *yaml.Node) (NormalPerturber, error) {
+	node, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := p.mapping(node)
+	if err != nil {
+		return nil, err
+	}
+
+	typeNode, ok := fields.get("type")
+	if !ok {
+		return nil, p.errorf(node, "perturbation has no type")
+	}
+
+	perturbationType, err := p.string(typeNode)
+	if err != nil {
+		return nil, err
+	}
+
+	var required []string
+	var setField func(key string, value *yaml.Node) error
+	var perturber NormalPerturber
+	switch perturbationType {
+	case "normal-map":
+		m := NewNormalMap(nil, SphericalMapping)
+		required = []string{"mapping", "uv-pattern"}
+		setField = func(key string, value *yaml.Node) (err error) {
+			switch key {
+			case "mapping":
+				var name string
+				if name, err = p.string(value); err == nil {
+					if m.Mapping, err = ParseUVMapping(name); err != nil {
+						err = p.errorf(value, "%v", err)
+					}
+				}
+			case "uv-pattern":
+				m.UVPattern, err = p.uvPattern(value)
+			case "strength":
+				m.Strength, err = p.float(value)
+			case "transform":
+				m.Transform, err = p.transform(value)
+			default:
+				err = p.errorf(value, "unknown normal-map attribute '%s'", key)
+			}
+
+			return err
+		}
+
+		perturber = m
+	case "bump-map":
+		m := NewBumpMap(nil, 1)
+		required = []string{"pattern"}
+		setField = func(key string, value *yaml.Node) (err error) {
+			switch key {
+			case "pattern":
+				m.Pattern, err = p.pattern(value)
+			case "scale":
+				m.Scale, err = p.float(value)
+			case "delta":
+				m.Delta, err = p.float(value)
+			default:
+				err = p.errorf(value, "unknown bump-map attribute '%s'", key)
+			}
+
+			return err
+		}
+
+		perturber = m
+	default:
+		return nil, p.errorf(typeNode, "unknown perturbation type '%s'", perturbationType)
+	}
+
+	for _, key := range required {
+		if _, ok := fields.get(key); !ok {
+			return nil, p.errorf(node, "%s has no %s", perturbationType, key)
+		}
+	}
+
+	for _, field := range fields {
+		if field.key != "type" {
+			if err := setField(field.key, field.value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return perturber, nil
+}
+
+// Parses a pattern, which is either a color or a mapping with a type, its subpatterns, and a transform.
+func (p *yamlSceneParser) pattern(node *yaml.Node) (Pattern, error) {
+	node, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Kind == yaml.SequenceNode {
+		color, err := p.color(node)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSolidPattern(color), nil
+	}
+
+	fields, err := p.mapping(node)
+	if err != nil {
+		return nil, err
+	}
+
+	typeNode, ok := fields.get("type")
+	if !ok {
+		return nil, p.errorf(node, "pattern has no type")
+	}
+
+	patternType, err := p.string(typeNode)
+	if err != nil {
+		return nil, err
+	}
+
+	// returns a required field of the pattern, and marks it as handled
+	handled := map[string]bool{"type": true, "transform": true}
+	field := func(key string) (*yaml.Node, error) {
+		value, ok := fields.get(key)
+		if !ok {
+			return nil, p.errorf(node, "%s pattern has no %s", patternType, key)
+		}
+
+		handled[key] = true
+		return value, nil
+	}
+
+	var pattern Pattern
+	switch patternType {
+	case "solid":
+		colorNode, err := field("color")
+		if err != nil {
+			return nil, err
+		}
+
+		color, err := p.color(colorNode)
+		if err != nil {
+			return nil, err
+		}
+
+		pattern = NewSolidPattern(color)
+	case "texture-map":
+		mappingNode, err := field("mapping")
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := p.string(mappingNode)
+		if err != nil {
+			return nil, err
+		}
+
+		mapping, err := ParseUVMapping(name)
+		if err != nil {
+			return nil, p.errorf(mappingNode, "%v", err)
+		}
+
+		uvNode, err := field("uv-pattern")
+		if err != nil {
+			return nil, err
+		}
+
+		uvPattern, err := p.uvPattern(uvNode)
+		if err != nil {
+			return nil, err
+		}
+
+		pattern = NewTextureMapPattern(uvPattern, mapping)
+	case "cube-map":
+		var faces [6]UVPattern
+		for i, name := range CubeFaceNames {
+			faceNode, err := field(name)
+			if err != nil {
+				return nil, err
+			}
+
+			if faces[i], err = p.uvPattern(faceNode); err != nil {
+				return nil, err
+			}
+		}
+
+		pattern = NewCubeMapPattern(faces[0], faces[1], faces[2], faces[3], faces[4], faces[5])
+	default:
+		if newPattern, ok := sceneWrapperPatterns[patternType]; ok {
+			subpattern, err := field("pattern")
+			if err != nil {
+				return nil, err
+			}
+
+			wrapped, err := p.pattern(subpattern)
+			if err != nil {
+				return nil, err
+			}
+
+			pattern = newPattern(wrapped)
+			break
+		}
+
+		newPattern, ok := scenePatterns[patternType]
+		if !ok {
+			return nil, p.errorf(typeNode, "unknown pattern type '%s'", patternType)
+		}
+
+		subpatterns, err := field("colors")
+		if err != nil {
+			return nil, err
+		}
+
+		subpatterns, err = p.resolve(subpatterns)
+		if err != nil {
+			return nil, err
+		}
+
+		if subpatterns.Kind != yaml.SequenceNode || len(subpatterns.Content) != 2 {
+			return nil, p.errorf(subpatterns, "%s pattern needs a list of two colors or patterns", patternType)
+		}
+
+		a, err := p.pattern(subpatterns.Content[0])
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := p.pattern(subpatterns.Content[1])
+		if err != nil {
+			return nil, err
+		}
+
+		pattern = newPattern(a, b)
+	}
+
+	parameters := patternParameters(pattern)
+	for _, field := range fields {
+		key, value := field.key, field.value
+		if key == "transform" {
+			transform, err := p.transform(value)
+			if err != nil {
+				return nil, err
+			}
+
+			pattern.SetTransform(transform)
+		} else if !handled[key] {
+			if err := p.setPatternParameter(parameters, patternType, key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return pattern, nil
+}
+
+// Parses a 2D pattern used by texture-map and cube-map patterns.
+func (p *yamlSceneParser) uvPattern(node *yaml.Node) (UVPattern, error) {
+	node, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := p.mapping(node)
+	if err != nil {
+		return nil, err
+	}
+
+	typeNode, ok := fields.get("type")
+	if !ok {
+		return nil, p.errorf(node, "UV pattern has no type")
+	}
+
+	patternType, err := p.string(typeNode)
+	if err != nil {
+		return nil, err
+	}
+
+	switch patternType {
+	case "checkers":
+		pattern := NewUVCheckersPattern(2, 2, NewColor(1, 1, 1), NewColor(0, 0, 0))
+		for _, field := range fields {
+			key, value := field.key, field.value
+			switch key {
+			case "type":
+			case "width":
+				pattern.Width, err = p.int(value)
+			case "height":
+				pattern.Height, err = p.int(value)
+			case "colors":
+				if value.Kind != yaml.SequenceNode || len(value.Content) != 2 {
+					return nil, p.errorf(value, "checkers UV pattern needs a list of two colors")
+				}
+
+				if pattern.A, err = p.color(value.Content[0]); err == nil {
+					pattern.B, err = p.color(value.Content[1])
+				}
+			default:
+				err = p.errorf(value, "unknown checkers UV pattern attribute '%s'", key)
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return pattern, nil
+	case "align-check":
+		pattern := &UVAlignCheckPattern{}
+		colors := map[string]*Color{
+			"main":         &pattern.Main,
+			"upper-left":   &pattern.UpperLeft,
+			"upper-right":  &pattern.UpperRight,
+			"bottom-left":  &pattern.BottomLeft,
+			"bottom-right": &pattern.BottomRight,
+		}
+
+		for _, field := range fields {
+			if field.key == "type" {
+				continue
+			}
+
+			color, ok := colors[field.key]
+			if !ok {
+				return nil, p.errorf(field.value, "unknown align-check UV pattern attribute '%s'", field.key)
+			}
+
+			if *color, err = p.color(field.value); err != nil {
+				return nil, err
+			}
+
+			delete(colors, field.key)
+		}
+
+		for _, key := range []string{"main", "upper-left", "upper-right", "bottom-left", "bottom-right"} {
+			if _, ok := colors[key]; ok {
+				return nil, p.errorf(node, "align-check UV pattern has no %s", key)
+			}
+		}
+
+		return pattern, nil
+	case "image":
+		return p.imagePattern(node, fields)
+	}
+
+	return nil, p.errorf(typeNode, "unknown UV pattern type '%s'", patternType)
+}
+
+// Parses an image UV pattern, loading its image relative to the scene file.
+func (p *yamlSceneParser) imagePattern(node *yaml.Node, fields yamlFields) (UVPattern, error) {
+	fileNode, ok := fields.get("file")
+	if !ok {
+		return nil, p.errorf(node, "image UV pattern has no file")
+	}
+
+	file, err := p.string(fileNode)
+	if err != nil {
+		return nil, err
+	}
+
+	canvas, err := LoadCanvas(resolvePath(filepath.Dir(p.filename), file))
+	if err != nil {
+		return nil, p.errorf(fileNode, "%v", err)
+	}
+
+	pattern := NewUVImagePattern(canvas)
+	pattern.File = file
+	for _, field := range fields {
+		key, value := field.key, field.value
+		switch key {
+		case "type", "file":
+		case "filter":
+			var name string
+			if name, err = p.string(value); err == nil {
+				if pattern.Filter, err = ParseTextureFilter(name); err != nil {
+					err = p.errorf(value, "%v", err)
+				}
+			}
+		case "wrap":
+			var name string
+			if name, err = p.string(value); err == nil {
+				if pattern.Wrap, err = ParseTextureWrap(name); err != nil {
+					err = p.errorf(value, "%v", err)
+				}
+			}
+		default:
+			err = p.errorf(value, "unknown image UV pattern attribute '%s'", key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return pattern, nil
+}
+
+// Sets the pattern parameter with the given name.
+func (p *yamlSceneParser) setPatternParameter(parameters []patternParameter, patternType string, name string, value *yaml.Node) error {
+	for _, param := range parameters {
+		if param.name != name {
+			continue
+		}
+
+		var err error
+		if param.float != nil {
+			*param.float, err = p.float(value)
+		} else {
+			*param.integer, err = p.int(value)
+		}
+
+		return err
+	}
+
+	return p.errorf(value, "unknown %s pattern attribute '%s'", patternType, name)
+}
+
+// Parses a list of transformations, each of which is an operation or the name of another list.
+func (p *yamlSceneParser) transform(node *yaml.Node) (Transformation, error) {
+	node, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Kind != yaml.SequenceNode {
+		return nil, p.errorf(node, "transform must be a list")
+	}
+
+	transform := NewTransform()
+	for _, item := range node.Content {
+		if item.Kind == yaml.ScalarNode {
+			named, err := p.transform(item)
+			if err != nil {
+				return nil, err
+			}
+
+			transform = named.CombineWith(transform)
+			continue
+		}
+
+		if item.Kind != yaml.SequenceNode || len(item.Content) == 0 {
+			return nil, p.errorf(item, "transformation must be a list like [translate, x, y, z]")
+		}
+
+		op, err := p.string(item.Content[0])
+		if err != nil {
+			return nil, err
+		}
+
+		args := make([]float64, len(item.Content)-1)
+		for i, arg := range item.Content[1:] {
+			if args[i], err = p.float(arg); err != nil {
+				return nil, err
+			}
+		}
+
+		want := map[string]int{
+			"translate": 3,
+			"scale":     3,
+			"rotate-x":  1,
+			"rotate-y":  1,
+			"rotate-z":  1,
+			"shear":     6,
+		}
+
+		n, ok := want[op]
+		if !ok {
+			return nil, p.errorf(item.Content[0], "unknown transformation '%s'", op)
+		}
+
+		if len(args) != n {
+			return nil, p.errorf(item, "%s takes %d arguments, got %d", op, n, len(args))
+		}
+
+		switch op {
+		case "translate":
+			transform = transform.Translate(args[0], args[1], args[2])
+		case "scale":
+			transform = transform.Scale(args[0], args[1], args[2])
+		case "rotate-x":
+			transform = transform.RotateX(args[0])
+		case "rotate-y":
+			transform = transform.RotateY(args[0])
+		case "rotate-z":
+			transform = transform.RotateZ(args[0])
+		case "shear":
+			transform = transform.Shear(args[0], args[1], args[2], args[3], args[4], args[5])
+		}
+	}
+
+	// shapes and patterns are placed by inverting their transforms
+	if !transform.IsInvertable() {
+		return nil, p.errorf(node, "transform is not invertible")
+	}
+
+	return transform, nil
+}
+
+// The key/value pairs of a mapping, in the order they appear in the file.
+type yamlFields []yamlField
+
+type yamlField struct {
+	key   string
+	value *yaml.Node
+}
+
+// Returns the value of the named field, if present.
+func (fields yamlFields) get(key string) (*yaml.Node, bool) {
+	for _, field := range fields {
+		if field.key == key {
+			return field.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns the key/value pairs of a mapping, following a definition name if necessary.
+func (p *yamlSceneParser) mapping(node *yaml.Node) (yamlFields, error) {
+	node, err := p.resolve(node)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, p.errorf(node, "expected a mapping")
+	}
+
+	fields := yamlFields{}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if _, ok := fields.get(key.Value); ok {
+			return nil, p.errorf(key, "duplicate attribute '%s'", key.Value)
+		}
+
+		fields = append(fields, yamlField{key.Value, node.Content[i+1]})
+	}
+
+	return fields, nil
+}
+
+func (p *yamlSceneParser) string(node *yaml.Node) (string, error) {
+	if node.Kind != yaml.ScalarNode || strings.TrimSpace(node.Value) == "" {
+		return "", p.errorf(node, "expected a name")
+	}
+
+	return node.Value, nil
+}
+
+func (p *yamlSceneParser) float(node *yaml.Node) (float64, error) {
+	var f float64
+	if node.Kind != yaml.ScalarNode || node.Decode(&f) != nil {
+		return 0, p.errorf(node, "expected a number")
+	}
+
+	return f, nil
+}
+
+func (p *yamlSceneParser) int(node *yaml.Node) (int, error) {
+	var i int
+	if node.Kind != yaml.ScalarNode || node.Decode(&i) != nil {
+		return 0, p.errorf(node, "expected an integer")
+	}
+
+	return i, nil
+}
+
+func (p *yamlSceneParser) triple(node *yaml.Node) ([3]float64, error) {
+	var values [3]float64
+	if node.Kind != yaml.SequenceNode || len(node.Content) != 3 {
+		return values, p.errorf(node, "expected a list of three numbers")
+	}
+
+	for i, item := range node.Content {
+		f, err := p.float(item)
+		if err != nil {
+			return values, err
+		}
+
+		values[i] = f
+	}
+
+	return values, nil
+}
+
+func (p *yamlSceneParser) point(node *yaml.Node) (Tuple, error) {
+	v, err := p.triple(node)
+	return NewPoint(v[0], v[1], v[2]), err
+}
+
+func (p *yamlSceneParser) vector(node *yaml.Node) (Tuple, error) {
+	v, err := p.triple(node)
+	return NewVector(v[0], v[1], v[2]), err
+}
+
+func (p *yamlSceneParser) color(node *yaml.Node) (Color, error) {
+	v, err := p.triple(node)
+	return NewColor(v[0], v[1], v[2]), err
+}
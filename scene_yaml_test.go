@@ -0,0 +1,792 @@
+package rt
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSceneYAML(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 100
+  height: 50
+  field-of-view: 0.785
+  from: [0, 1.5, -5]
+  to: [0, 1, 0]
+  up: [0, 1, 0]
+  samples: 2
+
+- add: light
+  at: [-10, 10, -10]
+  intensity: [1, .5, 1]
+
+- add: sphere
+  material:
+    color: [1, 0, 0]
+    diffuse: .7
+    ambient: .2
+    specular: .3
+    shininess: 50
+  transform:
+    - [scale, 2, 2, 2]
+    - [translate, 1, 2, 3]
+
+- add: plane
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+
+	c := scene.Camera
+	assert.Equal(t, 100, c.HSize)
+	assert.Equal(t, 50, c.VSize)
+	assert.Equal(t, .785, c.FOV)
+	assert.Equal(t, 2, c.Samples)
+	assert.True(t, c.Transform.Equals(NewViewTransform(NewPoint(0, 1.5, -5), NewPoint(0, 1, 0), NewVector(0, 1, 0))))
+
+	w := scene.World
+	assert.Equal(t, NewPointLight(NewPoint(-10, 10, -10), NewColor(1, .5, 1)), w.Light)
+	require.Len(t, w.Objects, 2)
+
+	s, ok := w.Objects[0].(*Sphere)
+	require.True(t, ok)
+	assert.Equal(t, NewColor(1, 0, 0), s.Material.Color)
+	assert.Equal(t, .7, s.Material.Diffuse)
+	assert.Equal(t, .2, s.Material.Ambient)
+	assert.Equal(t, .3, s.Material.Specular)
+	assert.Equal(t, 50.0, s.Material.Shininess)
+	assert.True(t, s.Transform.Equals(NewTransform().Scale(2, 2, 2).Translate(1, 2, 3)))
+
+	p, ok := w.Objects[1].(*Plane)
+	require.True(t, ok)
+	assert.Equal(t, NewMaterial(), p.Material)
+	assert.Equal(t, NewTransform(), p.Transform)
+}
+
+func TestParseSceneYAML_emission(t *testing.T) {
+	// emissive objects can light a scene without a light
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+  integrator: path
+- add: sphere
+  material:
+    emission: [4, 4, 2]
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+	assert.Nil(t, scene.World.Light)
+	require.Len(t, scene.World.Objects, 1)
+	assert.Equal(t, NewColor(4, 4, 2), scene.World.Objects[0].GetMaterial().Emission)
+
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "[4, 4, 2]", "[0, 0, 0]", 1)))
+	assert.EqualError(t, err, "test.yaml:2:1: scene does not add a light, an environment, or an emissive object")
+}
+
+func TestLoadSceneYAML_environment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scene")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	canvas := NewCanvas(2, 1)
+	canvas.WritePixel(1, 0, NewColor(8, 4, 2))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sky.hdr"), encodeHDR(canvas, false), 0644))
+
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: environment
+  type: image
+  file: sky.hdr
+  intensity: 2
+  transform:
+    - [rotate-y, 1]
+`
+	filename := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(yaml), 0644))
+	scene, err := LoadSceneYAML(filename)
+	require.NoError(t, err)
+
+	// images are loaded relative to the scene file, and an environment can light the scene
+	assert.Nil(t, scene.World.Light)
+	image, ok := scene.World.Environment.(*ImageEnvironment)
+	require.True(t, ok)
+	assert.Equal(t, "sky.hdr", image.File)
+	assert.Equal(t, 2.0, image.Intensity)
+	assert.True(t, NewRotationY(1).Equals(image.Transform))
+	assert.True(t, NewColor(8, 4, 2).Equals(image.Canvas.PixelAt(1, 0)))
+
+	solid := "- add: environment\n  type: solid\n  color: [.1, .2, .3]\n"
+	scene, err = ParseSceneYAML("test.yaml", []byte(yaml[:strings.Index(yaml, "- add: environment")]+solid))
+	require.NoError(t, err)
+	assert.Equal(t, NewSolidEnvironment(NewColor(.1, .2, .3)), scene.World.Environment)
+
+	sky := "- add: environment\n  type: sky\n  zenith: [0, 0, 1]\n  horizon: [1, 1, 0]\n  ground: [0, 1, 0]\n"
+	scene, err = ParseSceneYAML("test.yaml", []byte(yaml[:strings.Index(yaml, "- add: environment")]+sky))
+	require.NoError(t, err)
+	assert.Equal(t, &SkyEnvironment{NewColor(0, 0, 1), NewColor(1, 1, 0), NewColor(0, 1, 0)}, scene.World.Environment)
+
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"type: image", "type: starfield", "7:9: unknown environment type 'starfield'"},
+		{"  type: image\n", "", "6:3: environment has no type"},
+		{"  file: sky.hdr\n", "", "6:3: image environment has no file"},
+		{"intensity: 2", "color: [1, 1, 1]", "9:10: unknown image environment attribute 'color'"},
+		{"sky.hdr", "missing.hdr", "8:9: open " + filepath.Join(dir, "missing.hdr") + ": no such file or directory"},
+		{"  intensity: 2\n", "  intensity: 2\n- add: environment\n  type: solid\n", "10:3: scene already has an environment"},
+	}
+
+	for _, test := range tests {
+		require.NoError(t, ioutil.WriteFile(filename, []byte(strings.Replace(yaml, test.old, test.new, 1)), 0644))
+		_, err := LoadSceneYAML(filename)
+		assert.EqualError(t, err, filename+":"+test.expected, test.new)
+	}
+}
+
+func TestParseSceneYAML_integrator(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+  integrator: path
+- add: light
+  at: [0, 10, 0]
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+	assert.Equal(t, NewPathTracer(), scene.Camera.Integrator)
+
+	settings := "  integrator:\n    type: path\n    max-depth: 3\n    roulette-depth: 1\n"
+	scene, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "  integrator: path\n", settings, 1)))
+	require.NoError(t, err)
+	assert.Equal(t, &PathTracer{MaxDepth: 3, RouletteDepth: 1}, scene.Camera.Integrator)
+
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"integrator: path", "integrator: photon-mapping", "test.yaml:6:15: unknown integrator 'photon-mapping'"},
+		{"integrator: path", "integrator: {max-depth: 2}", "test.yaml:6:15: integrator has no type"},
+		{"integrator: path", "integrator: {type: whitted, max-depth: 2}", "test.yaml:6:42: unknown whitted integrator attribute 'max-depth'"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, test.old, test.new, 1)))
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestParseSceneYAML_motion(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+  shutter: [0, .5]
+- add: light
+  at: [0, 10, 0]
+- add: sphere
+  end-transform:
+    - [translate, 2, 0, 0]
+  transform:
+    - [scale, 2, 2, 2]
+- add: plane
+  keyframes:
+    - time: 1
+      transform:
+        - [translate, 0, 1, 0]
+    - time: 0
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, scene.Camera.ShutterOpen)
+	assert.Equal(t, .5, scene.Camera.ShutterClose)
+
+	motion, err := NewMotion(NewScaling(2, 2, 2), NewTranslation(2, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, motion, scene.World.Objects[0].GetMotion())
+	motion, err = NewMotion(NewTransform(), NewTranslation(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, motion, scene.World.Objects[1].GetMotion())
+
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"shutter: [0, .5]", "shutter: [1, 0]", "test.yaml:6:12: shutter must open before it closes"},
+		{"shutter: [0, .5]", "shutter: 1", "test.yaml:6:12: expected a list of two times"},
+		{"  keyframes:\n", "  end-transform: []\n  keyframes:\n", "test.yaml:15:18: plane can't have both end-transform and keyframes"},
+		{"    - time: 0\n", "    - transform: []\n", "test.yaml:19:7: keyframe has no time"},
+		{"    - time: 0\n", "    - {time: 0, speed: 1}\n", "test.yaml:19:24: unknown keyframe attribute 'speed'"},
+		{"  keyframes:\n", "  keyframes: {}\n  unused:\n", "test.yaml:15:14: keyframes must be a list"},
+		{"    - [translate, 2, 0, 0]", "    - [scale, -1, 1, 1]", "test.yaml:11:5: transforms at times 0 and 1 can't differ in whether they mirror the shape"},
+		{"    - time: 0\n", "    - time: 0\n      transform: [[scale, -1, 1, 1]]\n", "test.yaml:16:5: transforms at times 0 and 1 can't differ in whether they mirror the shape"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, test.old, test.new, 1)))
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestParseSceneYAML_sampler(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+  sampler: halton
+  seed: 42
+- add: light
+  at: [0, 10, 0]
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+	assert.Equal(t, NewHaltonSampler(), scene.Camera.Sampler)
+	assert.Equal(t, int64(42), scene.Camera.Seed)
+
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "halton", "sobol", 1)))
+	assert.EqualError(t, err, "test.yaml:6:12: unknown sampler 'sobol'")
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "42", "lucky", 1)))
+	assert.EqualError(t, err, "test.yaml:7:9: expected an integer")
+}
+
+func TestParseSceneYAML_animation(t *testing.T) {
+	yaml := `
+- add: animation
+  frames: 10
+  start: 1
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+  from: [0, 0, -5]
+  animate:
+    - time: 1
+    - time: 3
+      from: [0, 0, -10]
+      easing: ease-in
+- add: light
+  at: [0, 10, 0]
+  animate:
+    - {time: 1, at: [0, 10, 0], easing: [.1, .2, .3, 1]}
+    - {time: 2, at: [5, 10, 0]}
+- add: sphere
+  animate:
+    - time: 2
+      transform:
+        - [translate, 1, 0, 0]
+      material:
+        color: [1, 0, 0]
+        ambient: .5
+    - time: 1
+      easing: smoothstep
+      material:
+        ambient: 0
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+	a := scene.Animation
+	require.NotNil(t, a)
+	assert.Equal(t, 10, a.Frames)
+	assert.Equal(t, 1.0, a.Start)
+	assert.Equal(t, 3.0, a.End)
+
+	camera, light, sphere := scene.Camera, scene.World.Light, scene.World.Objects[0]
+	up := NewVector(0, 1, 0)
+	material := sphere.GetMaterial()
+	assert.Equal(t, []Track{
+		NewCameraTrack(camera,
+			CameraKey{1, NewPoint(0, 0, -5), NewPoint(0, 0, -1), up, nil},
+			CameraKey{3, NewPoint(0, 0, -10), NewPoint(0, 0, -1), up, NewBezierEasing(.42, 0, 1, 1)},
+		),
+		NewLightTrack(light,
+			LightKey{1, NewPoint(0, 10, 0), NewBezierEasing(.1, .2, .3, 1)},
+			LightKey{2, NewPoint(5, 10, 0), nil},
+		),
+		&TransformTrack{sphere, []TransformKey{{2, NewTranslation(1, 0, 0), nil}}},
+		&MaterialTrack{material, "color", []MaterialKey{{2, []float64{1, 0, 0}, nil}}},
+		&MaterialTrack{material, "ambient", []MaterialKey{{1, []float64{0}, SmoothstepEasing{}}, {2, []float64{.5}, nil}}},
+	}, a.Tracks)
+
+	a.Apply(2)
+	assert.True(t, light.Position.Equals(NewPoint(5, 10, 0)))
+	assert.Equal(t, .5, material.Ambient)
+
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"  frames: 10\n", "  end: 0\n", "test.yaml:2:3: animation is missing 'frames'"},
+		{"frames: 10", "frames: 0", "test.yaml:3:11: animation frames must be positive"},
+		{"  start: 1\n", "  start: 1\n  end: 0\n", "test.yaml:2:3: animation must end after it starts"},
+		{"  start: 1\n", "  loop: true\n", "test.yaml:4:9: unknown animation attribute 'loop'"},
+		{"- add: animation\n  frames: 10\n  start: 1\n", "", "test.yaml:8:5: scene animates objects but does not add an animation"},
+		{"from: [0, 0, -10]", "from: [0, 10, -1]", "test.yaml:12:7: camera transform is not invertible; is up parallel to the view direction?"},
+		{"easing: ease-in", "easing: bounce", "test.yaml:14:15: unknown easing 'bounce'"},
+		{"easing: ease-in", "easing: [1, 2]", "test.yaml:14:15: easing must be a name or a list of four numbers"},
+		{"    - time: 1\n    - time: 3", "    - {}\n    - time: 3", "test.yaml:11:7: animation key has no time"},
+		{"    - time: 1\n    - time: 3", "    - {time: 1, at: [0, 0, 0]}\n    - time: 3", "test.yaml:11:21: unknown camera key attribute 'at'"},
+		{"        ambient: .5", "        gloss: .5", "test.yaml:27:16: unknown animated material attribute 'gloss'"},
+		{"      easing: smoothstep\n", "      easing: smoothstep\n      transform: [[scale, -1, 1, 1]]\n", "test.yaml:22:5: transforms at times 1 and 2 can't differ in whether they mirror the shape"},
+		{"  animate:\n    - time: 1\n    - time: 3\n      from: [0, 0, -10]\n      easing: ease-in\n", "  animate: {time: 1}\n", "test.yaml:10:12: animate must be a list of keys"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, test.old, test.new, 1)))
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestParseSceneYAML_definitions(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [0, 10, 0]
+
+- define: base-material
+  value:
+    color: [1, 1, 1]
+    diffuse: .7
+- define: red-material
+  extend: base-material
+  value:
+    color: [1, 0, 0]
+- define: small
+  value:
+    - [scale, .5, .5, .5]
+- define: small-and-high
+  extend: small
+  value:
+    - [translate, 0, 5, 0]
+
+- add: sphere
+  material: red-material
+  transform:
+    - small-and-high
+    - [rotate-x, 1]
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+	require.Len(t, scene.World.Objects, 1)
+
+	s := scene.World.Objects[0].(*Sphere)
+	assert.Equal(t, NewColor(1, 0, 0), s.Material.Color)
+	assert.Equal(t, .7, s.Material.Diffuse)
+	assert.True(t, s.Transform.Equals(NewTransform().Scale(.5, .5, .5).Translate(0, 5, 0).RotateX(1)))
+}
+
+func TestParseSceneYAML_patterns(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [0, 10, 0]
+
+- define: checks
+  value:
+    type: checkers
+    colors: [[1, 1, 1], [0, 0, 0]]
+    transform:
+      - [scale, .25, .25, .25]
+
+- add: plane
+  material:
+    pattern:
+      type: stripes
+      colors:
+        - type: solid
+          color: [1, 0, 0]
+        - checks
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+
+	pattern, ok := scene.World.Objects[0].GetMaterial().Pattern.(*StripePattern)
+	require.True(t, ok)
+	assert.Equal(t, NewColor(1, 0, 0), pattern.At(NewPoint(0, 0, 0)))
+
+	checkers, ok := pattern.B.(*CheckerPattern)
+	require.True(t, ok)
+	assert.True(t, checkers.GetTransform().Equals(NewScaling(.25, .25, .25)))
+	assert.Equal(t, NewColor(1, 1, 1), pattern.At(NewPoint(1, 0, 0)))
+	assert.Equal(t, NewColor(0, 0, 0), pattern.At(NewPoint(1.3, 0, 0)))
+}
+
+func TestParseSceneYAML_pbr(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [0, 10, 0]
+
+- define: gold
+  value: [1, .8, .3]
+- define: scratches
+  value:
+    type: stripes
+    colors: [[.2, .2, .2], [.6, .6, .6]]
+
+- add: sphere
+  material:
+    ambient: .05
+    pbr:
+      base-color: gold
+      metallic: 1
+      roughness: scratches
+      emission:
+        type: solid
+        color: [0, 0, .1]
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+
+	m := scene.World.Objects[0].GetMaterial()
+	assert.Equal(t, .05, m.Ambient)
+	expected := NewPBRMaterial()
+	expected.BaseColor = NewColor(1, .8, .3)
+	expected.Metallic = 1
+	expected.RoughnessPattern = NewStripePattern(NewSolidPattern(NewColor(.2, .2, .2)), NewSolidPattern(NewColor(.6, .6, .6)))
+	expected.EmissionPattern = NewSolidPattern(NewColor(0, 0, .1))
+	assert.Equal(t, expected, m.PBR)
+
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"metallic: 1", "metallic: very", "test.yaml:21:17: expected a number"},
+		{"metallic: 1", "shininess: 1", "test.yaml:21:18: unknown pbr material attribute 'shininess'"},
+		{"base-color: gold", "base-color: silver", "test.yaml:20:19: undefined name 'silver'"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, test.old, test.new, 1)))
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestParseSceneYAML_perturbations(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [0, 10, 0]
+
+- define: bumps
+  value:
+    type: bump-map
+    pattern:
+      type: fbm
+      colors: [[0, 0, 0], [1, 1, 1]]
+    scale: .1
+
+- add: sphere
+  material:
+    perturbation: bumps
+
+- add: plane
+  material:
+    perturbation:
+      type: normal-map
+      mapping: planar
+      uv-pattern:
+        type: checkers
+        width: 2
+        height: 2
+        colors: [[0.5, 0.5, 1], [0.6, 0.5, 0.9]]
+      strength: 2
+      transform:
+        - [scale, 4, 4, 4]
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+
+	bumpMap := NewBumpMap(NewFBMPattern(NewSolidPattern(black), NewSolidPattern(white)), .1)
+	assert.Equal(t, bumpMap, scene.World.Objects[0].GetMaterial().Perturber)
+
+	normalMap := scene.World.Objects[1].GetMaterial().Perturber.(*NormalMap)
+	assert.Equal(t, PlanarMapping, normalMap.Mapping)
+	assert.Equal(t, NewUVCheckersPattern(2, 2, NewColor(.5, .5, 1), NewColor(.6, .5, .9)), normalMap.UVPattern)
+	assert.Equal(t, 2.0, normalMap.Strength)
+	assert.True(t, normalMap.Transform.Equals(NewScaling(4, 4, 4)))
+
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"type: bump-map", "type: displacement", "test.yaml:11:11: unknown perturbation type 'displacement'"},
+		{"    scale: .1", "    height: .1", "test.yaml:15:13: unknown bump-map attribute 'height'"},
+		{"      mapping: planar\n", "", "test.yaml:24:7: normal-map has no mapping"},
+		{"mapping: planar", "mapping: conical", "test.yaml:25:16: unknown UV mapping 'conical'"},
+		{"strength: 2", "strength: lots", "test.yaml:31:17: expected a number"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, test.old, test.new, 1)))
+		assert.EqualError(t, err, test.expected)
+	}
+}
+
+func TestParseSceneYAML_noisePatterns(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [0, 10, 0]
+
+- add: sphere
+  material:
+    pattern:
+      type: perturbed
+      scale: .3
+      octaves: 2
+      pattern:
+        type: marble
+        strength: 2
+        colors: [[1, 1, 1], [0, 0, 0]]
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+
+	perturbed, ok := scene.World.Objects[0].GetMaterial().Pattern.(*PerturbedPattern)
+	require.True(t, ok)
+	assert.Equal(t, .3, perturbed.Scale)
+	assert.Equal(t, 2, perturbed.Octaves)
+
+	marble, ok := perturbed.A.(*MarblePattern)
+	require.True(t, ok)
+	assert.Equal(t, 2.0, marble.Strength)
+	assert.Equal(t, 4, marble.Octaves)
+
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "octaves: 2", "octaves: many", 1)))
+	assert.EqualError(t, err, "test.yaml:14:16: expected an integer")
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "strength", "power", 1)))
+	assert.EqualError(t, err, "test.yaml:17:16: unknown marble pattern attribute 'power'")
+}
+
+func TestParseSceneYAML_uvPatterns(t *testing.T) {
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [0, 10, 0]
+
+- define: align
+  value:
+    type: align-check
+    main: [1, 1, 1]
+    upper-left: [1, 0, 0]
+    upper-right: [1, 1, 0]
+    bottom-left: [0, 1, 0]
+    bottom-right: [0, 1, 1]
+
+- add: sphere
+  material:
+    pattern:
+      type: texture-map
+      mapping: spherical
+      uv-pattern:
+        type: checkers
+        width: 16
+        height: 8
+        colors: [[0, 0, 0], [1, 1, 1]]
+
+- add: sphere
+  material:
+    pattern:
+      type: cube-map
+      left: align
+      front: align
+      right: align
+      back: align
+      up: align
+      down: align
+`
+	scene, err := ParseSceneYAML("test.yaml", []byte(yaml))
+	require.NoError(t, err)
+
+	expected := NewTextureMapPattern(NewUVCheckersPattern(16, 8, black, white), SphericalMapping)
+	assert.Equal(t, expected, scene.World.Objects[0].GetMaterial().Pattern)
+
+	align := NewUVAlignCheckPattern(white, NewColor(1, 0, 0), NewColor(1, 1, 0), NewColor(0, 1, 0), NewColor(0, 1, 1))
+	cube := NewCubeMapPattern(align, align, align, align, align, align)
+	assert.Equal(t, cube, scene.World.Objects[1].GetMaterial().Pattern)
+
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "mapping: spherical", "mapping: conical", 1)))
+	assert.EqualError(t, err, "test.yaml:22:16: unknown UV mapping 'conical'")
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "      down: align\n", "", 1)))
+	assert.EqualError(t, err, "test.yaml:32:7: cube-map pattern has no down")
+	_, err = ParseSceneYAML("test.yaml", []byte(strings.Replace(yaml, "    bottom-right: [0, 1, 1]\n", "", 1)))
+	assert.EqualError(t, err, "test.yaml:11:5: align-check UV pattern has no bottom-right")
+}
+
+func TestParseSceneYAML_errors(t *testing.T) {
+	header := "- add: camera\n  width: 10\n  height: 10\n  field-of-view: 1\n- add: light\n  at: [0, 1, 0]\n"
+	tests := map[string]struct {
+		yaml     string
+		expected string
+	}{
+		"unknown shape": {
+			header + "- add: teapot\n",
+			"scene.yaml:7:8: unknown object type 'teapot'",
+		},
+		"undefined name": {
+			header + "- add: sphere\n  material: shiny\n",
+			"scene.yaml:8:13: undefined name 'shiny'",
+		},
+		"bad transform": {
+			header + "- add: sphere\n  transform:\n    - [translate, 1, 2]\n",
+			"scene.yaml:9:7: translate takes 3 arguments, got 2",
+		},
+		"singular transform": {
+			header + "- add: sphere\n  transform:\n    - [scale, 0, 1, 1]\n",
+			"scene.yaml:9:5: transform is not invertible",
+		},
+		"singular named transform": {
+			header + "- define: flat\n  value:\n    - [scale, 1, 0, 1]\n- add: plane\n  transform: [flat]\n",
+			"scene.yaml:9:5: transform is not invertible",
+		},
+		"singular pattern transform": {
+			header + "- add: sphere\n  material:\n    pattern:\n      type: stripes\n      colors: [[1, 1, 1], [0, 0, 0]]\n      transform: [[scale, 1, 1, 0]]\n",
+			"scene.yaml:12:18: transform is not invertible",
+		},
+		"camera looking along up": {
+			strings.Replace(header, "field-of-view: 1\n", "field-of-view: 1\n  up: [0, 0, 1]\n", 1),
+			"scene.yaml:1:3: camera transform is not invertible; is up parallel to the view direction?",
+		},
+		"bad number": {
+			header + "- add: sphere\n  material:\n    diffuse: lots\n",
+			"scene.yaml:9:14: expected a number",
+		},
+		"unknown attribute": {
+			header + "- add: sphere\n  colour: [1, 0, 0]\n",
+			"scene.yaml:8:11: unknown sphere attribute 'colour'",
+		},
+		"missing camera": {
+			"- add: light\n  at: [0, 1, 0]\n",
+			"scene.yaml:1:1: scene does not add a camera",
+		},
+		"second light": {
+			header + "- add: light\n  at: [0, 1, 0]\n",
+			"scene.yaml:7:3: scene already has a light; only one light is supported",
+		},
+		"not a list": {
+			"add: camera\n",
+			"scene.yaml:1:1: scene must be a list of commands",
+		},
+	}
+
+	for name, test := range tests {
+		_, err := ParseSceneYAML("scene.yaml", []byte(test.yaml))
+		if assert.Error(t, err, name) {
+			assert.Equal(t, test.expected, err.Error(), name)
+		}
+	}
+
+	// syntax errors are reported by the YAML parser
+	_, err := ParseSceneYAML("scene.yaml", []byte("- add: [camera\n"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "scene.yaml: yaml: line")
+}
+
+func TestLoadSceneYAML(t *testing.T) {
+	scene, err := LoadSceneYAML("scenes/demo.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, 250, scene.Camera.HSize)
+	assert.Equal(t, 125, scene.Camera.VSize)
+	assert.True(t, eq(math.Pi/3, scene.Camera.FOV))
+	assert.Len(t, scene.World.Objects, 4)
+
+	scene, err = LoadSceneYAML("scenes/turntable.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, 48, scene.Animation.Frames)
+
+	_, err = LoadSceneYAML("scenes/missing.yaml")
+	assert.Error(t, err)
+}
+
+func TestLoadSceneYAML_images(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scene")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "textures"), 0755))
+	require.NoError(t, newTestTexture().Save(filepath.Join(dir, "textures", "earth.png")))
+
+	yaml := `
+- add: camera
+  width: 10
+  height: 10
+  field-of-view: 1
+- add: light
+  at: [0, 10, 0]
+- add: sphere
+  material:
+    pattern:
+      type: texture-map
+      mapping: spherical
+      uv-pattern:
+        type: image
+        file: textures/earth.png
+        filter: nearest
+        wrap: clamp
+`
+	filename := filepath.Join(dir, "scene.yaml")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(yaml), 0644))
+	scene, err := LoadSceneYAML(filename)
+	require.NoError(t, err)
+
+	// images are loaded relative to the scene file
+	pattern := scene.World.Objects[0].GetMaterial().Pattern.(*TextureMapPattern)
+	image := pattern.UVPattern.(*UVImagePattern)
+	assert.Equal(t, "textures/earth.png", image.File)
+	assert.Equal(t, NearestFilter, image.Filter)
+	assert.Equal(t, ClampWrap, image.Wrap)
+	assert.Equal(t, 2, image.Canvas.Width())
+	assert.True(t, NewColor(0, 0, 1).Equals(image.UVAt(.25, .25)))
+
+	tests := []struct {
+		old, new string
+		expected string
+	}{
+		{"filter: nearest", "filter: trilinear", "16:17: unknown texture filter 'trilinear'"},
+		{"wrap: clamp", "wrap: border", "17:15: unknown texture wrap mode 'border'"},
+		{"        wrap: clamp\n", "        wrap: clamp\n        gamma: 2\n", "18:16: unknown image UV pattern attribute 'gamma'"},
+		{"        file: textures/earth.png\n", "", "14:9: image UV pattern has no file"},
+		{
+			"textures/earth.png", "textures/mars.png",
+			"15:15: open " + filepath.Join(dir, "textures", "mars.png") + ": no such file or directory",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := ParseSceneYAML(filename, []byte(strings.Replace(yaml, test.old, test.new, 1)))
+		assert.EqualError(t, err, filename+":"+test.expected)
+	}
+}
@@ -1,17 +1,39 @@
 package rt
 
+import (
+	"math"
+	"math/rand"
+)
+
 // A Shape is anything that can be rendered.
 type Shape interface {
 	GetMaterial() *Material
+	SetMaterial(material *Material)
 	GetTransform() Transformation
+	SetTransform(transform Transformation)
+	GetMotion() *Motion
+	SetMotion(motion *Motion)
 	Intersect(r *Ray) IntersectionSet
 	NormalAt(p Tuple) Tuple
 }
 
+// A SurfaceSampler is a shape whose surface can be sampled at random. Emissive surface samplers
+// light their surroundings under global illumination.
+type SurfaceSampler interface {
+	Shape
+
+	// SampleSurface returns a random point on the shape's surface at a moment in time, the surface
+	// normal there, and the probability density of choosing the point per unit of surface area.
+	SampleSurface(time float64, rng *rand.Rand) (point Tuple, normal Tuple, pdf float64)
+}
+
 // ShapeProps contains properties common to all shapes.
 type ShapeProps struct {
 	Material  *Material
 	Transform Transformation
+
+	// Motion, if set, moves the shape over time in place of its transform.
+	Motion *Motion
 }
 
 // NewShapeProps creates a new ShapeProps.
@@ -27,13 +49,42 @@ func (sp *ShapeProps) GetMaterial() *Material {
 	return sp.Material
 }
 
+// SetMaterial sets the material properties.
+func (sp *ShapeProps) SetMaterial(material *Material) {
+	sp.Material = material
+}
+
 // GetTransform gets the shape's transformation.
 func (sp *ShapeProps) GetTransform() Transformation {
 	return sp.Transform
 }
 
+// SetTransform sets the shape's transformation.
+func (sp *ShapeProps) SetTransform(transform Transformation) {
+	sp.Transform = transform
+}
+
+// GetMotion gets the shape's motion.
+func (sp *ShapeProps) GetMotion() *Motion {
+	return sp.Motion
+}
+
+// SetMotion sets the shape's motion.
+func (sp *ShapeProps) SetMotion(motion *Motion) {
+	sp.Motion = motion
+}
+
+// Returns the shape's transform at a moment in time.
+func (sp *ShapeProps) transformAt(time float64) Transformation {
+	if sp.Motion != nil {
+		return sp.Motion.TransformAt(time)
+	}
+
+	return sp.Transform
+}
+
 func (sp *ShapeProps) intersect(worldRay *Ray, localIntersectFn func(localRay *Ray) IntersectionSet) IntersectionSet {
-	localRay := worldRay.Transform(sp.Transform.Inverse())
+	localRay := worldRay.Transform(sp.transformAt(worldRay.Time).Inverse())
 	return localIntersectFn(localRay)
 }
 
@@ -44,3 +95,19 @@ func (sp *ShapeProps) normalAt(worldPoint Tuple, localNormalFn func(localPoint T
 	worldNormal[3] = 0
 	return worldNormal.Normalize()
 }
+
+// Transforms a point sampled from the untransformed shape's surface, along with its normal and
+// probability density per unit area, to world space at a moment in time.
+func (sp *ShapeProps) sampleSurface(time float64, localPoint Tuple, localNormal Tuple, localPDF float64) (Tuple, Tuple, float64) {
+	m := sp.transformAt(time)
+	point := m.ApplyTo(localPoint)
+	normal := m.Inverse().Transpose().ApplyTo(localNormal.Normalize())
+	normal[3] = 0
+
+	// a patch of surface with unit normal n grows in area by |det M| |M⁻ᵀn| when transformed by M
+	determinant := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	scale := math.Abs(determinant) * normal.Magnitude()
+	return point, normal.Normalize(), localPDF / scale
+}
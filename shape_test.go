@@ -23,6 +23,20 @@ func TestShapeProps_GetMaterial(t *testing.T) {
 	assert.Equal(t, NewMaterial(), sp.GetMaterial())
 }
 
+func TestShapeProps_SetMaterial(t *testing.T) {
+	sp := NewShapeProps()
+	m := NewMaterial()
+	m.Ambient = 1
+	sp.SetMaterial(m)
+	assert.Equal(t, m, sp.GetMaterial())
+}
+
+func TestShapeProps_SetTransform(t *testing.T) {
+	sp := NewShapeProps()
+	sp.SetTransform(NewTranslation(2, 3, 4))
+	assert.Equal(t, NewTranslation(2, 3, 4), sp.GetTransform())
+}
+
 func TestShapeProps_intersect(t *testing.T) {
 	// with default transform
 	sp := NewShapeProps()
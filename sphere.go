@@ -2,6 +2,7 @@ package rt
 
 import (
 	"math"
+	"math/rand"
 )
 
 // A Sphere represents a sphere.
@@ -43,3 +44,9 @@ func (s *Sphere) NormalAt(point Tuple) Tuple {
 		return localNormal
 	})
 }
+
+// SampleSurface returns a random point on the sphere's surface at a moment in time.
+func (s *Sphere) SampleSurface(time float64, rng *rand.Rand) (Tuple, Tuple, float64) {
+	direction := uniformSampleSphere(rng)
+	return s.sampleSurface(time, Origin().Add(direction), direction, 1/(4*math.Pi))
+}
@@ -2,6 +2,7 @@ package rt
 
 import (
 	"math"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -113,3 +114,31 @@ func TestSphere_NormalAt(t *testing.T) {
 	n = s.NormalAt(NewPoint(0, math.Sqrt2/2, -math.Sqrt2/2))
 	assert.True(t, n.Equals(NewVector(0, .97014, -.24254)))
 }
+
+func TestSphere_SampleSurface(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	s := NewSphere()
+	s.Transform = NewTranslation(1, 2, 3).CombineWith(NewScaling(2, 2, 2))
+	for i := 0; i < 100; i++ {
+		point, normal, pdf := s.SampleSurface(0, rng)
+		assert.InDelta(t, 2, point.Subtract(NewPoint(1, 2, 3)).Magnitude(), EPSILON)
+		assert.True(t, s.NormalAt(point).Equals(normal))
+		assert.InDelta(t, 1/(16*math.Pi), pdf, EPSILON)
+	}
+
+	// on a stretched sphere the density varies, but the mean of its inverse is still the area
+	s = NewSphere()
+	s.Transform = NewRotationZ(math.Pi / 3).CombineWith(NewScaling(1, 1, 3))
+	const samples = 20000
+	sum := 0.0
+	for i := 0; i < samples; i++ {
+		point, normal, pdf := s.SampleSurface(0, rng)
+		assert.True(t, s.NormalAt(point).Equals(normal))
+		sum += 1 / pdf
+	}
+
+	// the area of a prolate spheroid with radii 1, 1, and 3
+	e := math.Sqrt(1 - 1.0/9)
+	area := 2 * math.Pi * (1 + 3*math.Asin(e)/e)
+	assert.InDelta(t, area, sum/samples, area*.01)
+}
@@ -0,0 +1,125 @@
+package rt
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// RenderStats counts the work done by a render, to explain where its time goes.
+type RenderStats struct {
+	// PrimaryRays counts the rays traced from the camera.
+	PrimaryRays int64
+
+	// ShadowRays counts the rays traced toward lights to find whether they're blocked.
+	ShadowRays int64
+
+	// ReflectionRays counts the rays traced as light is reflected from one surface to another,
+	// which only the path tracer does. No material refracts light, so there are no refraction rays.
+	ReflectionRays int64
+
+	// IntersectionTests counts the tests of rays against shapes, by the type of shape.
+	IntersectionTests map[string]int64
+
+	// Tiles holds the time each tile took to render, in the order they were finished.
+	Tiles []TileTime
+}
+
+// A TileTime is the time a tile took to render.
+type TileTime struct {
+	Tile    image.Rectangle
+	Elapsed time.Duration
+}
+
+// NewRenderStats creates a new RenderStats with nothing counted.
+func NewRenderStats() *RenderStats {
+	return &RenderStats{IntersectionTests: map[string]int64{}}
+}
+
+// Rays returns the number of rays of every kind.
+func (s *RenderStats) Rays() int64 {
+	return s.PrimaryRays + s.ShadowRays + s.ReflectionRays
+}
+
+// TotalIntersectionTests returns the number of intersection tests against shapes of every type.
+func (s *RenderStats) TotalIntersectionTests() int64 {
+	total := int64(0)
+	for _, tests := range s.IntersectionTests {
+		total += tests
+	}
+
+	return total
+}
+
+// TotalTileTime returns the time spent rendering tiles, summed across threads.
+func (s *RenderStats) TotalTileTime() time.Duration {
+	total := time.Duration(0)
+	for _, tile := range s.Tiles {
+		total += tile.Elapsed
+	}
+
+	return total
+}
+
+// SlowestTile returns the tile that took the longest to render.
+func (s *RenderStats) SlowestTile() TileTime {
+	var slowest TileTime
+	for _, tile := range s.Tiles {
+		if tile.Elapsed > slowest.Elapsed {
+			slowest = tile
+		}
+	}
+
+	return slowest
+}
+
+// Merge adds the counts of other to s.
+func (s *RenderStats) Merge(other *RenderStats) {
+	s.PrimaryRays += other.PrimaryRays
+	s.ShadowRays += other.ShadowRays
+	s.ReflectionRays += other.ReflectionRays
+	if s.IntersectionTests == nil {
+		s.IntersectionTests = map[string]int64{}
+	}
+
+	for shape, tests := range other.IntersectionTests {
+		s.IntersectionTests[shape] += tests
+	}
+
+	s.Tiles = append(s.Tiles, other.Tiles...)
+}
+
+// The counters are incremented as rays are traced. Each tile counts its work in its own stats,
+// which are merged once the tile is finished, so counting needs no locks. A nil RenderStats
+// counts nothing.
+
+func (s *RenderStats) addPrimaryRay() {
+	if s != nil {
+		s.PrimaryRays++
+	}
+}
+
+func (s *RenderStats) addShadowRay() {
+	if s != nil {
+		s.ShadowRays++
+	}
+}
+
+func (s *RenderStats) addReflectionRay() {
+	if s != nil {
+		s.ReflectionRays++
+	}
+}
+
+func (s *RenderStats) addIntersectionTest(shape Shape) {
+	if s == nil {
+		return
+	}
+
+	name, err := shapeTypeName(shape)
+	if err != nil {
+		name = fmt.Sprintf("%T", shape)
+	}
+
+	s.IntersectionTests[name]++
+}
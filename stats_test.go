@@ -0,0 +1,72 @@
+package rt
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamera_RenderWithStats(t *testing.T) {
+	w := NewDefaultWorld()
+	c := NewCamera(11, 11, math.Pi/2)
+	c.Transform = NewViewTransform(NewPoint(0, 0, -5), Origin(), NewVector(0, 1, 0))
+	c.Samples = 2
+	c.TileSize = 4
+
+	var last *RenderStats
+	image, stats, err := c.RenderWithStats(context.Background(), w, func(p RenderProgress) {
+		last = p.Stats
+	})
+	require.NoError(t, err)
+	assert.Equal(t, c.Render(w), image)
+	assert.Equal(t, stats, last)
+
+	// every primary ray that hits a sphere traces a shadow ray, and each ray is tested against
+	// both spheres
+	assert.Equal(t, int64(11*11*4), stats.PrimaryRays)
+	assert.True(t, stats.ShadowRays > 0 && stats.ShadowRays < stats.PrimaryRays)
+	assert.Zero(t, stats.ReflectionRays)
+	assert.Equal(t, map[string]int64{"sphere": 2 * stats.Rays()}, stats.IntersectionTests)
+	assert.Equal(t, 2*stats.Rays(), stats.TotalIntersectionTests())
+
+	assert.Len(t, stats.Tiles, 9)
+	assert.True(t, stats.TotalTileTime() >= stats.SlowestTile().Elapsed)
+	assert.Contains(t, c.tiles(), stats.SlowestTile().Tile)
+
+	// the counts don't depend on how the tiles are scheduled
+	c.Threads = 1
+	_, again, err := c.RenderWithStats(context.Background(), w, nil)
+	require.NoError(t, err)
+	assert.Equal(t, stats.Rays(), again.Rays())
+	assert.Equal(t, stats.IntersectionTests, again.IntersectionTests)
+}
+
+func TestCamera_RenderWithStats_pathTracer(t *testing.T) {
+	w, c := newCornellBox(8)
+	_, stats, err := c.RenderWithStats(context.Background(), w, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8*8), stats.PrimaryRays)
+	assert.True(t, stats.ReflectionRays > 0)
+	assert.True(t, stats.ShadowRays > 0)
+	assert.Equal(t, int64(len(w.Objects))*stats.Rays(), stats.TotalIntersectionTests())
+}
+
+func TestRenderStats_Merge(t *testing.T) {
+	a := NewRenderStats()
+	a.PrimaryRays = 1
+	a.IntersectionTests["sphere"] = 2
+	b := &RenderStats{ShadowRays: 3, IntersectionTests: map[string]int64{"sphere": 1, "plane": 4}}
+	b.Tiles = []TileTime{{Elapsed: 5}}
+
+	a.Merge(b)
+	assert.Equal(t, int64(4), a.Rays())
+	assert.Equal(t, map[string]int64{"sphere": 3, "plane": 4}, a.IntersectionTests)
+	assert.Equal(t, b.Tiles, a.Tiles)
+
+	var empty RenderStats
+	empty.Merge(b)
+	assert.Equal(t, b.IntersectionTests, empty.IntersectionTests)
+}
@@ -0,0 +1,169 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+)
+
+// A TextureFilter is a way of choosing a color for texture coordinates that fall between the
+// centers of an image's pixels.
+type TextureFilter int
+
+// The available texture filters.
+const (
+	// NearestFilter uses the color of the pixel the coordinates fall within.
+	NearestFilter TextureFilter = iota
+
+	// BilinearFilter blends the colors of the four pixels nearest the coordinates.
+	BilinearFilter
+)
+
+var textureFilterNames = []string{"nearest", "bilinear"}
+
+// String returns the name of the filter.
+func (f TextureFilter) String() string {
+	if f < 0 || int(f) >= len(textureFilterNames) {
+		return fmt.Sprintf("TextureFilter(%d)", int(f))
+	}
+
+	return textureFilterNames[f]
+}
+
+// ParseTextureFilter returns the filter with the given name.
+func ParseTextureFilter(name string) (TextureFilter, error) {
+	for i, n := range textureFilterNames {
+		if n == name {
+			return TextureFilter(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown texture filter '%s'", name)
+}
+
+// A TextureWrap is a way of choosing a pixel for texture coordinates outside of an image.
+type TextureWrap int
+
+// The available texture wrap modes.
+const (
+	// RepeatWrap tiles the image.
+	RepeatWrap TextureWrap = iota
+
+	// ClampWrap extends the pixels at the image's edges.
+	ClampWrap
+
+	// MirrorWrap tiles the image, flipping every other tile.
+	MirrorWrap
+)
+
+var textureWrapNames = []string{"repeat", "clamp", "mirror"}
+
+// String returns the name of the wrap mode.
+func (w TextureWrap) String() string {
+	if w < 0 || int(w) >= len(textureWrapNames) {
+		return fmt.Sprintf("TextureWrap(%d)", int(w))
+	}
+
+	return textureWrapNames[w]
+}
+
+// ParseTextureWrap returns the wrap mode with the given name.
+func ParseTextureWrap(name string) (TextureWrap, error) {
+	for i, n := range textureWrapNames {
+		if n == name {
+			return TextureWrap(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown texture wrap mode '%s'", name)
+}
+
+// Returns the index of the pixel to use for pixel index i of an image n pixels wide.
+func (w TextureWrap) apply(i int, n int) int {
+	switch w {
+	case ClampWrap:
+		if i < 0 {
+			return 0
+		}
+
+		if i >= n {
+			return n - 1
+		}
+
+		return i
+	case MirrorWrap:
+		i %= 2 * n
+		if i < 0 {
+			i += 2 * n
+		}
+
+		if i >= n {
+			return 2*n - 1 - i
+		}
+
+		return i
+	}
+
+	i %= n
+	if i < 0 {
+		i += n
+	}
+
+	return i
+}
+
+// A UVImagePattern is a UV pattern that samples an image, with u running left to right and
+// v running bottom to top.
+type UVImagePattern struct {
+	Canvas *Canvas
+	Filter TextureFilter
+	Wrap   TextureWrap
+
+	// File is the name of the image file the canvas was loaded from, if any. Scene files
+	// refer to the image by this name.
+	File string
+}
+
+// NewUVImagePattern creates a new UVImagePattern that filters bilinearly and repeats the image.
+func NewUVImagePattern(canvas *Canvas) *UVImagePattern {
+	return &UVImagePattern{Canvas: canvas, Filter: BilinearFilter, Wrap: RepeatWrap}
+}
+
+// LoadUVImagePattern creates a new UVImagePattern from an image file.
+func LoadUVImagePattern(filename string) (*UVImagePattern, error) {
+	canvas, err := LoadCanvas(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := NewUVImagePattern(canvas)
+	pattern.File = filename
+	return pattern, nil
+}
+
+// UVAt returns the pattern color at the given texture coordinates.
+func (p *UVImagePattern) UVAt(u float64, v float64) Color {
+	x := u * float64(p.Canvas.Width())
+	y := (1 - v) * float64(p.Canvas.Height())
+	if p.Filter == NearestFilter {
+		return p.pixelAt(int(math.Floor(x)), int(math.Floor(y)))
+	}
+
+	// blend the four pixels whose centers surround the point
+	x, y = x-.5, y-.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := x-x0, y-y0
+	ix, iy := int(x0), int(y0)
+	top := mixColors(p.pixelAt(ix, iy), p.pixelAt(ix+1, iy), tx)
+	bottom := mixColors(p.pixelAt(ix, iy+1), p.pixelAt(ix+1, iy+1), tx)
+	return mixColors(top, bottom, ty)
+}
+
+// Returns the color of the pixel at x, y, wrapping coordinates that are outside the image.
+func (p *UVImagePattern) pixelAt(x int, y int) Color {
+	color := p.Canvas.PixelAt(p.Wrap.apply(x, p.Canvas.Width()), p.Wrap.apply(y, p.Canvas.Height()))
+	if color == nil {
+		return black
+	}
+
+	return color
+}
@@ -0,0 +1,194 @@
+package rt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Returns a 2x2 canvas with a different color in each pixel.
+func newTestTexture() *Canvas {
+	c := NewCanvas(2, 2)
+	c.WritePixel(0, 0, NewColor(1, 0, 0))
+	c.WritePixel(1, 0, NewColor(0, 1, 0))
+	c.WritePixel(0, 1, NewColor(0, 0, 1))
+	c.WritePixel(1, 1, NewColor(1, 1, 1))
+	return c
+}
+
+func TestParseTextureFilter(t *testing.T) {
+	for _, filter := range []TextureFilter{NearestFilter, BilinearFilter} {
+		parsed, err := ParseTextureFilter(filter.String())
+		require.NoError(t, err)
+		assert.Equal(t, filter, parsed)
+	}
+
+	_, err := ParseTextureFilter("trilinear")
+	assert.EqualError(t, err, "unknown texture filter 'trilinear'")
+	assert.Equal(t, "TextureFilter(7)", TextureFilter(7).String())
+}
+
+func TestParseTextureWrap(t *testing.T) {
+	for _, wrap := range []TextureWrap{RepeatWrap, ClampWrap, MirrorWrap} {
+		parsed, err := ParseTextureWrap(wrap.String())
+		require.NoError(t, err)
+		assert.Equal(t, wrap, parsed)
+	}
+
+	_, err := ParseTextureWrap("border")
+	assert.EqualError(t, err, "unknown texture wrap mode 'border'")
+	assert.Equal(t, "TextureWrap(7)", TextureWrap(7).String())
+}
+
+func TestTextureWrap_apply(t *testing.T) {
+	tests := []struct {
+		wrap     TextureWrap
+		indices  []int
+		expected []int
+	}{
+		{RepeatWrap, []int{-4, -3, -1, 0, 2, 3, 4, 7}, []int{2, 0, 2, 0, 2, 0, 1, 1}},
+		{ClampWrap, []int{-4, -1, 0, 2, 3, 7}, []int{0, 0, 0, 2, 2, 2}},
+		{MirrorWrap, []int{-4, -3, -1, 0, 2, 3, 4, 5, 6, 7}, []int{2, 2, 0, 0, 2, 2, 1, 0, 0, 1}},
+	}
+
+	for _, test := range tests {
+		for i, index := range test.indices {
+			assert.Equal(t, test.expected[i], test.wrap.apply(index, 3), "%s %d", test.wrap, index)
+		}
+	}
+}
+
+func TestUVImagePattern_UVAt_nearest(t *testing.T) {
+	p := NewUVImagePattern(newTestTexture())
+	p.Filter = NearestFilter
+	tests := []struct {
+		u, v     float64
+		expected Color
+	}{
+		// v runs from the bottom of the image to the top
+		{.25, .75, NewColor(1, 0, 0)},
+		{.75, .75, NewColor(0, 1, 0)},
+		{.25, .25, NewColor(0, 0, 1)},
+		{.75, .25, NewColor(1, 1, 1)},
+		{.49, .51, NewColor(1, 0, 0)},
+		{0, 1, NewColor(1, 0, 0)},
+
+		// coordinates outside the image repeat it
+		{1.25, 1.75, NewColor(1, 0, 0)},
+		{-.25, .75, NewColor(0, 1, 0)},
+	}
+
+	for _, test := range tests {
+		assert.True(t, test.expected.Equals(p.UVAt(test.u, test.v)), "%v, %v", test.u, test.v)
+	}
+}
+
+func TestUVImagePattern_UVAt_bilinear(t *testing.T) {
+	p := NewUVImagePattern(newTestTexture())
+	p.Wrap = ClampWrap
+	tests := []struct {
+		u, v     float64
+		expected Color
+	}{
+		// pixel centers have the pixel's exact color
+		{.25, .75, NewColor(1, 0, 0)},
+		{.75, .25, NewColor(1, 1, 1)},
+
+		// between centers the colors blend
+		{.5, .75, NewColor(.5, .5, 0)},
+		{.25, .5, NewColor(.5, 0, .5)},
+		{.5, .5, NewColor(.5, .5, .5)},
+		{.375, .75, NewColor(.75, .25, 0)},
+
+		// clamping extends the edges
+		{0, .75, NewColor(1, 0, 0)},
+		{-3, 1.5, NewColor(1, 0, 0)},
+	}
+
+	for _, test := range tests {
+		assert.True(t, test.expected.Equals(p.UVAt(test.u, test.v)), "%v, %v", test.u, test.v)
+	}
+
+	// repeating blends the edges with the opposite side of the image
+	p.Wrap = RepeatWrap
+	assert.True(t, NewColor(.5, .5, 0).Equals(p.UVAt(0, .75)))
+
+	// mirroring blends the edges with themselves
+	p.Wrap = MirrorWrap
+	assert.True(t, NewColor(1, 0, 0).Equals(p.UVAt(0, .75)))
+	assert.True(t, NewColor(1, 0, 0).Equals(p.UVAt(-.25, .75)))
+	assert.True(t, NewColor(0, 1, 0).Equals(p.UVAt(-.75, .75)))
+}
+
+func TestUVImagePattern_textureMap(t *testing.T) {
+	p := NewUVImagePattern(newTestTexture())
+	p.Filter = NearestFilter
+
+	// the top half of a sphere maps to the top row of the image
+	sphere := NewTextureMapPattern(p, SphericalMapping)
+	assert.True(t, NewColor(1, 0, 0).Equals(sphere.At(NewPoint(.5, .5, .7))))
+	assert.True(t, NewColor(1, 1, 1).Equals(sphere.At(NewPoint(-.5, -.5, .7))))
+
+	// the image tiles a plane
+	plane := NewTextureMapPattern(p, PlanarMapping)
+	assert.True(t, NewColor(0, 0, 1).Equals(plane.At(NewPoint(.25, 0, .25))))
+	assert.True(t, NewColor(0, 0, 1).Equals(plane.At(NewPoint(3.25, 0, -1.75))))
+}
+
+func TestUVImagePattern_skybox(t *testing.T) {
+	// a skybox is six images, one for each face of a cube around the scene
+	var faces [6]UVPattern
+	colors := []Color{
+		NewColor(1, 0, 0), NewColor(0, 1, 0), NewColor(0, 0, 1),
+		NewColor(1, 1, 0), NewColor(0, 1, 1), NewColor(1, 0, 1),
+	}
+
+	for i, color := range colors {
+		c := NewCanvas(4, 4)
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				c.WritePixel(x, y, color)
+			}
+		}
+
+		faces[i] = NewUVImagePattern(c)
+	}
+
+	skybox := NewCubeMapPattern(faces[0], faces[1], faces[2], faces[3], faces[4], faces[5])
+	sky := NewSphere()
+	sky.SetTransform(NewScaling(100, 100, 100))
+	sky.Material.Pattern = skybox
+
+	// looking out from inside a large sphere, each direction sees the image for that face
+	directions := []Tuple{
+		NewVector(-1, .2, .1), NewVector(.3, -.2, 1), NewVector(1, 0, 0),
+		NewVector(0, .5, -1), NewVector(.1, 1, .1), NewVector(-.3, -1, .2),
+	}
+
+	for i, direction := range directions {
+		point := Origin().Add(direction.Normalize().Multiply(100))
+		assert.True(t, colors[i].Equals(skybox.AtObject(sky, point)), "%v", direction)
+	}
+}
+
+func TestLoadUVImagePattern(t *testing.T) {
+	dir, err := ioutil.TempDir("", "texture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "texture.png")
+	require.NoError(t, newTestTexture().Save(filename))
+	p, err := LoadUVImagePattern(filename)
+	require.NoError(t, err)
+	assert.Equal(t, filename, p.File)
+	assert.Equal(t, BilinearFilter, p.Filter)
+	assert.Equal(t, RepeatWrap, p.Wrap)
+	assert.True(t, NewColor(0, 0, 1).Equals(p.UVAt(.25, .25)))
+
+	_, err = LoadUVImagePattern(filepath.Join(dir, "missing.png"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package rt
+
+import (
+	"math"
+)
+
+// A ToneMapper compresses high dynamic range colors into the displayable [0, 1] range.
+type ToneMapper interface {
+	Map(color Color) Color
+}
+
+// An ExposureToneMapper scales colors by an exposure value, in stops, and leaves clamping to the output.
+type ExposureToneMapper struct {
+	Exposure float64
+}
+
+// NewExposureToneMapper creates a new ExposureToneMapper.
+func NewExposureToneMapper(exposure float64) *ExposureToneMapper {
+	return &ExposureToneMapper{exposure}
+}
+
+// Map returns the tone mapped color.
+func (tm *ExposureToneMapper) Map(color Color) Color {
+	return color.Multiply(math.Pow(2, tm.Exposure))
+}
+
+// A ReinhardToneMapper compresses luminance using the simple Reinhard operator L / (1 + L).
+type ReinhardToneMapper struct{}
+
+// NewReinhardToneMapper creates a new ReinhardToneMapper.
+func NewReinhardToneMapper() *ReinhardToneMapper {
+	return &ReinhardToneMapper{}
+}
+
+// Map returns the tone mapped color.
+func (tm *ReinhardToneMapper) Map(color Color) Color {
+	return scaleLuminance(color, func(l float64) float64 {
+		return l / (1 + l)
+	})
+}
+
+// An ExtendedReinhardToneMapper is a Reinhard operator that maps the WhitePoint luminance to pure white.
+type ExtendedReinhardToneMapper struct {
+	WhitePoint float64
+}
+
+// NewExtendedReinhardToneMapper creates a new ExtendedReinhardToneMapper.
+func NewExtendedReinhardToneMapper(whitePoint float64) *ExtendedReinhardToneMapper {
+	return &ExtendedReinhardToneMapper{whitePoint}
+}
+
+// Map returns the tone mapped color.
+func (tm *ExtendedReinhardToneMapper) Map(color Color) Color {
+	white2 := tm.WhitePoint * tm.WhitePoint
+	return scaleLuminance(color, func(l float64) float64 {
+		return l * (1 + l/white2) / (1 + l)
+	})
+}
+
+// An ACESToneMapper applies Krzysztof Narkowicz's fit of the ACES filmic curve to each channel.
+type ACESToneMapper struct{}
+
+// NewACESToneMapper creates a new ACESToneMapper.
+func NewACESToneMapper() *ACESToneMapper {
+	return &ACESToneMapper{}
+}
+
+// Map returns the tone mapped color.
+func (tm *ACESToneMapper) Map(color Color) Color {
+	aces := func(x float64) float64 {
+		x = math.Max(x, 0)
+		return clamp((x*(2.51*x+.03))/(x*(2.43*x+.59)+.14), 0, 1)
+	}
+
+	return NewColor(aces(color.Red()), aces(color.Green()), aces(color.Blue()))
+}
+
+// Scales a color so that its luminance becomes the result of fn, preserving its hue.
+func scaleLuminance(color Color, fn func(l float64) float64) Color {
+	l := color.Luminance()
+	if l <= 0 {
+		return NewColor(0, 0, 0)
+	}
+
+	return color.Multiply(fn(l) / l)
+}
@@ -0,0 +1,63 @@
+package rt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExposureToneMapper_Map(t *testing.T) {
+	tm := NewExposureToneMapper(0)
+	assert.True(t, tm.Map(NewColor(.2, .4, .6)).Equals(NewColor(.2, .4, .6)))
+
+	tm = NewExposureToneMapper(1)
+	assert.True(t, tm.Map(NewColor(.2, .4, .6)).Equals(NewColor(.4, .8, 1.2)))
+
+	tm = NewExposureToneMapper(-2)
+	assert.True(t, tm.Map(NewColor(4, 2, 1)).Equals(NewColor(1, .5, .25)))
+}
+
+func TestReinhardToneMapper_Map(t *testing.T) {
+	tm := NewReinhardToneMapper()
+
+	// gray values are compressed by L / (1 + L)
+	assert.True(t, tm.Map(NewColor(1, 1, 1)).Equals(NewColor(.5, .5, .5)))
+	assert.True(t, tm.Map(NewColor(3, 3, 3)).Equals(NewColor(.75, .75, .75)))
+
+	// black stays black
+	assert.True(t, tm.Map(NewColor(0, 0, 0)).Equals(NewColor(0, 0, 0)))
+
+	// hue is preserved
+	c := tm.Map(NewColor(4, 2, 0))
+	assert.True(t, eq(c.Red(), 2*c.Green()))
+	assert.True(t, eq(0, c.Blue()))
+}
+
+func TestExtendedReinhardToneMapper_Map(t *testing.T) {
+	tm := NewExtendedReinhardToneMapper(4)
+
+	// the white point maps to white
+	assert.True(t, tm.Map(NewColor(4, 4, 4)).Equals(NewColor(1, 1, 1)))
+
+	// values below the white point are brighter than with simple Reinhard
+	extended := tm.Map(NewColor(1, 1, 1))
+	simple := NewReinhardToneMapper().Map(NewColor(1, 1, 1))
+	assert.Greater(t, extended.Red(), simple.Red())
+	assert.True(t, extended.Equals(NewColor(.53125, .53125, .53125)))
+}
+
+func TestACESToneMapper_Map(t *testing.T) {
+	tm := NewACESToneMapper()
+
+	assert.True(t, tm.Map(NewColor(0, 0, 0)).Equals(NewColor(0, 0, 0)))
+	assert.True(t, tm.Map(NewColor(-1, 0, 0)).Equals(NewColor(0, 0, 0)))
+
+	// very bright values saturate to white
+	assert.True(t, tm.Map(NewColor(1000, 1000, 1000)).Equals(NewColor(1, 1, 1)))
+
+	// the curve is monotonic
+	c := tm.Map(NewColor(.18, 1, 4))
+	assert.Less(t, c.Red(), c.Green())
+	assert.Less(t, c.Green(), c.Blue())
+	assert.LessOrEqual(t, c.Blue(), 1.0)
+}
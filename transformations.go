@@ -102,6 +102,12 @@ func (t Transformation) Equals(other Transformation) bool {
 	return Matrix(t).Equals(Matrix(other))
 }
 
+// IsInvertable returns true if the transformation can be inverted, which it can't if it
+// flattens space, such as by scaling by 0.
+func (t Transformation) IsInvertable() bool {
+	return Matrix(t).IsInvertable()
+}
+
 // Inverse returns the inverse of this transformation.
 func (t Transformation) Inverse() Transformation {
 	return Transformation(Matrix(t).Inverse())
@@ -0,0 +1,275 @@
+package rt
+
+import (
+	"fmt"
+	"math"
+)
+
+// A UVMapping is a way of mapping a 3D point on a surface to 2D texture coordinates u and v,
+// each between 0 and 1.
+type UVMapping int
+
+// The available UV mappings.
+const (
+	// SphericalMapping wraps the texture around a unit sphere at the origin, with v = 0 at
+	// the south pole and v = 1 at the north pole.
+	SphericalMapping UVMapping = iota
+
+	// PlanarMapping tiles the texture across the XZ plane, repeating every unit.
+	PlanarMapping
+
+	// CylindricalMapping wraps the texture around a cylinder along the Y axis, repeating
+	// every unit of height.
+	CylindricalMapping
+)
+
+var uvMappingNames = []string{"spherical", "planar", "cylindrical"}
+
+// String returns the name of the mapping.
+func (m UVMapping) String() string {
+	if m < 0 || int(m) >= len(uvMappingNames) {
+		return fmt.Sprintf("UVMapping(%d)", int(m))
+	}
+
+	return uvMappingNames[m]
+}
+
+// ParseUVMapping returns the mapping with the given name.
+func ParseUVMapping(name string) (UVMapping, error) {
+	for i, n := range uvMappingNames {
+		if n == name {
+			return UVMapping(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown UV mapping '%s'", name)
+}
+
+// Map returns the texture coordinates of a point.
+func (m UVMapping) Map(point Tuple) (u float64, v float64) {
+	switch m {
+	case PlanarMapping:
+		return PlanarMap(point)
+	case CylindricalMapping:
+		return CylindricalMap(point)
+	}
+
+	return SphericalMap(point)
+}
+
+// Returns the direction in which u increases at a point, or the zero vector where it's undefined.
+func (m UVMapping) tangent(point Tuple) Tuple {
+	if m == PlanarMapping {
+		return NewVector(1, 0, 0)
+	}
+
+	// u increases clockwise around the Y axis, when viewed from above
+	tangent := NewVector(-point.Z(), 0, point.X())
+	if tangent.Magnitude() < EPSILON {
+		return NewVector(0, 0, 0)
+	}
+
+	return tangent.Normalize()
+}
+
+// SphericalMap maps a point on a unit sphere to texture coordinates.
+func SphericalMap(point Tuple) (u float64, v float64) {
+	theta := math.Atan2(point.X(), point.Z())
+	radius := NewVector(point.X(), point.Y(), point.Z()).Magnitude()
+	phi := math.Acos(point.Y() / radius)
+	rawU := theta / (2 * math.Pi)
+	return 1 - (rawU + .5), 1 - phi/math.Pi
+}
+
+// PlanarMap maps a point on the XZ plane to texture coordinates.
+func PlanarMap(point Tuple) (u float64, v float64) {
+	return fmod(point.X(), 1), fmod(point.Z(), 1)
+}
+
+// CylindricalMap maps a point on a cylinder around the Y axis to texture coordinates.
+func CylindricalMap(point Tuple) (u float64, v float64) {
+	theta := math.Atan2(point.X(), point.Z())
+	rawU := theta / (2 * math.Pi)
+	return 1 - (rawU + .5), fmod(point.Y(), 1)
+}
+
+// A CubeFace is one of the six faces of an axis-aligned cube.
+type CubeFace int
+
+// The faces of a cube.
+const (
+	CubeLeft CubeFace = iota
+	CubeFront
+	CubeRight
+	CubeBack
+	CubeUp
+	CubeDown
+)
+
+// CubeFaceNames are the names of the cube faces, in order.
+var CubeFaceNames = []string{"left", "front", "right", "back", "up", "down"}
+
+// CubeMap maps a point on a cube from -1 to 1 along each axis to a face and texture
+// coordinates on that face. Each face is oriented as if viewed from outside the cube, with
+// the up face's top edge toward -Z and the down face's top edge toward +Z. Points that
+// aren't on the cube, such as points on a sphere, are projected onto it from the origin.
+func CubeMap(point Tuple) (face CubeFace, u float64, v float64) {
+	// project the point onto the cube's surface
+	x, y, z := point.X(), point.Y(), point.Z()
+	if coord := math.Max(math.Abs(x), math.Max(math.Abs(y), math.Abs(z))); coord > 0 {
+		x, y, z = x/coord, y/coord, z/coord
+	}
+
+	switch face = cubeFace(point); face {
+	case CubeLeft:
+		return face, fmod(z+1, 2) / 2, fmod(y+1, 2) / 2
+	case CubeFront:
+		return face, fmod(x+1, 2) / 2, fmod(y+1, 2) / 2
+	case CubeRight:
+		return face, fmod(1-z, 2) / 2, fmod(y+1, 2) / 2
+	case CubeBack:
+		return face, fmod(1-x, 2) / 2, fmod(y+1, 2) / 2
+	case CubeUp:
+		return face, fmod(x+1, 2) / 2, fmod(1-z, 2) / 2
+	}
+
+	return face, fmod(x+1, 2) / 2, fmod(z+1, 2) / 2
+}
+
+// Returns the face of a cube a point is on, which is the one its largest coordinate points toward.
+func cubeFace(point Tuple) CubeFace {
+	x, y, z := point.X(), point.Y(), point.Z()
+	coord := math.Max(math.Abs(x), math.Max(math.Abs(y), math.Abs(z)))
+	switch coord {
+	case x:
+		return CubeRight
+	case -x:
+		return CubeLeft
+	case y:
+		return CubeUp
+	case -y:
+		return CubeDown
+	case z:
+		return CubeFront
+	}
+
+	return CubeBack
+}
+
+// Returns a modulo b, which unlike math.Mod is never negative for a positive b.
+func fmod(a float64, b float64) float64 {
+	m := math.Mod(a, b)
+	if m < 0 {
+		m += b
+	}
+
+	return m
+}
+
+// A UVPattern is a 2D pattern defined over texture coordinates.
+type UVPattern interface {
+	UVAt(u float64, v float64) Color
+}
+
+// A UVCheckersPattern is a grid of alternating colors, Width squares across and Height squares high.
+type UVCheckersPattern struct {
+	Width  int
+	Height int
+	A      Color
+	B      Color
+}
+
+// NewUVCheckersPattern creates a new UVCheckersPattern.
+func NewUVCheckersPattern(width int, height int, a Color, b Color) *UVCheckersPattern {
+	return &UVCheckersPattern{width, height, a, b}
+}
+
+// UVAt returns the pattern color at the given texture coordinates.
+func (p *UVCheckersPattern) UVAt(u float64, v float64) Color {
+	u2 := int(math.Floor(u * float64(p.Width)))
+	v2 := int(math.Floor(v * float64(p.Height)))
+	if (u2+v2)%2 == 0 {
+		return p.A
+	}
+
+	return p.B
+}
+
+// A UVAlignCheckPattern is a solid color with a different colored square in each corner,
+// which makes it easy to check how a texture is oriented on a surface.
+type UVAlignCheckPattern struct {
+	Main        Color
+	UpperLeft   Color
+	UpperRight  Color
+	BottomLeft  Color
+	BottomRight Color
+}
+
+// NewUVAlignCheckPattern creates a new UVAlignCheckPattern.
+func NewUVAlignCheckPattern(main Color, upperLeft Color, upperRight Color, bottomLeft Color, bottomRight Color) *UVAlignCheckPattern {
+	return &UVAlignCheckPattern{main, upperLeft, upperRight, bottomLeft, bottomRight}
+}
+
+// UVAt returns the pattern color at the given texture coordinates.
+func (p *UVAlignCheckPattern) UVAt(u float64, v float64) Color {
+	if v > .8 {
+		if u < .2 {
+			return p.UpperLeft
+		}
+
+		if u > .8 {
+			return p.UpperRight
+		}
+	} else if v < .2 {
+		if u < .2 {
+			return p.BottomLeft
+		}
+
+		if u > .8 {
+			return p.BottomRight
+		}
+	}
+
+	return p.Main
+}
+
+// A TextureMapPattern applies a 2D UV pattern to a surface using a UV mapping.
+type TextureMapPattern struct {
+	PatternProps
+	UVPattern UVPattern
+	Mapping   UVMapping
+}
+
+// NewTextureMapPattern creates a new TextureMapPattern.
+func NewTextureMapPattern(uvPattern UVPattern, mapping UVMapping) *TextureMapPattern {
+	pattern := &TextureMapPattern{NewPatternProps(nil, nil), uvPattern, mapping}
+	pattern.p = pattern
+	return pattern
+}
+
+// At returns the pattern color at the given point.
+func (p *TextureMapPattern) At(point Tuple) Color {
+	u, v := p.Mapping.Map(point)
+	return p.UVPattern.UVAt(u, v)
+}
+
+// A CubeMapPattern applies a different UV pattern to each face of a cube.
+type CubeMapPattern struct {
+	PatternProps
+
+	// Faces are the patterns for each face, indexed by CubeFace.
+	Faces [6]UVPattern
+}
+
+// NewCubeMapPattern creates a new CubeMapPattern.
+func NewCubeMapPattern(left UVPattern, front UVPattern, right UVPattern, back UVPattern, up UVPattern, down UVPattern) *CubeMapPattern {
+	pattern := &CubeMapPattern{NewPatternProps(nil, nil), [6]UVPattern{left, front, right, back, up, down}}
+	pattern.p = pattern
+	return pattern
+}
+
+// At returns the pattern color at the given point.
+func (p *CubeMapPattern) At(point Tuple) Color {
+	face, u, v := CubeMap(point)
+	return p.Faces[face].UVAt(u, v)
+}
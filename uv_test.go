@@ -0,0 +1,237 @@
+package rt
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUVMapping(t *testing.T) {
+	assert.Equal(t, "spherical", SphericalMapping.String())
+	assert.Equal(t, "cylindrical", CylindricalMapping.String())
+	assert.Equal(t, "UVMapping(9)", UVMapping(9).String())
+
+	m, err := ParseUVMapping("planar")
+	assert.NoError(t, err)
+	assert.Equal(t, PlanarMapping, m)
+	_, err = ParseUVMapping("conical")
+	assert.EqualError(t, err, "unknown UV mapping 'conical'")
+
+	p := NewPoint(.25, .5, -.75)
+	u, v := PlanarMapping.Map(p)
+	assert.Equal(t, .25, u)
+	assert.Equal(t, .25, v)
+}
+
+func TestSphericalMap(t *testing.T) {
+	tests := []struct {
+		point Tuple
+		u, v  float64
+	}{
+		{NewPoint(0, 0, -1), 0, .5},
+		{NewPoint(1, 0, 0), .25, .5},
+		{NewPoint(0, 0, 1), .5, .5},
+		{NewPoint(-1, 0, 0), .75, .5},
+		{NewPoint(0, 1, 0), .5, 1},
+		{NewPoint(0, -1, 0), .5, 0},
+		{NewPoint(math.Sqrt2/2, math.Sqrt2/2, 0), .25, .75},
+	}
+
+	for _, test := range tests {
+		u, v := SphericalMap(test.point)
+		assert.True(t, eq(test.u, u), test.point.String())
+		assert.True(t, eq(test.v, v), test.point.String())
+	}
+}
+
+func TestPlanarMap(t *testing.T) {
+	tests := []struct {
+		point Tuple
+		u, v  float64
+	}{
+		{NewPoint(.25, 0, .5), .25, .5},
+		{NewPoint(.25, 0, -.25), .25, .75},
+		{NewPoint(.25, .5, -.25), .25, .75},
+		{NewPoint(1.25, 0, .5), .25, .5},
+		{NewPoint(.25, 0, -1.75), .25, .25},
+		{NewPoint(1, 0, -1), 0, 0},
+		{NewPoint(0, 0, 0), 0, 0},
+	}
+
+	for _, test := range tests {
+		u, v := PlanarMap(test.point)
+		assert.True(t, eq(test.u, u), test.point.String())
+		assert.True(t, eq(test.v, v), test.point.String())
+	}
+}
+
+func TestCylindricalMap(t *testing.T) {
+	tests := []struct {
+		point Tuple
+		u, v  float64
+	}{
+		{NewPoint(0, 0, -1), 0, 0},
+		{NewPoint(0, .5, -1), 0, .5},
+		{NewPoint(0, 1, -1), 0, 0},
+		{NewPoint(.70711, .5, -.70711), .125, .5},
+		{NewPoint(1, .5, 0), .25, .5},
+		{NewPoint(.70711, .5, .70711), .375, .5},
+		{NewPoint(0, -.25, 1), .5, .75},
+		{NewPoint(-.70711, .5, .70711), .625, .5},
+		{NewPoint(-1, 1.25, 0), .75, .25},
+		{NewPoint(-.70711, .5, -.70711), .875, .5},
+	}
+
+	for _, test := range tests {
+		u, v := CylindricalMap(test.point)
+		assert.InDelta(t, test.u, u, EPSILON, test.point.String())
+		assert.InDelta(t, test.v, v, EPSILON, test.point.String())
+	}
+}
+
+func TestCubeMap(t *testing.T) {
+	tests := []struct {
+		point Tuple
+		face  CubeFace
+		u, v  float64
+	}{
+		{NewPoint(-1, .5, -.25), CubeLeft, .375, .75},
+		{NewPoint(-1, -.5, .25), CubeLeft, .625, .25},
+		{NewPoint(-.5, .5, 1), CubeFront, .25, .75},
+		{NewPoint(.5, -.5, 1), CubeFront, .75, .25},
+		{NewPoint(1, .5, .25), CubeRight, .375, .75},
+		{NewPoint(1, -.5, -.25), CubeRight, .625, .25},
+		{NewPoint(.5, .5, -1), CubeBack, .25, .75},
+		{NewPoint(-.5, -.5, -1), CubeBack, .75, .25},
+		{NewPoint(-.5, 1, -.5), CubeUp, .25, .75},
+		{NewPoint(.5, 1, .5), CubeUp, .75, .25},
+		{NewPoint(-.5, -1, .5), CubeDown, .25, .75},
+		{NewPoint(.5, -1, -.5), CubeDown, .75, .25},
+	}
+
+	for _, test := range tests {
+		face, u, v := CubeMap(test.point)
+		assert.Equal(t, test.face, face, test.point.String())
+		assert.True(t, eq(test.u, u), test.point.String())
+		assert.True(t, eq(test.v, v), test.point.String())
+	}
+
+	// points off the surface map to the face their largest coordinate points toward
+	face, _, _ := CubeMap(NewPoint(-1.1, -.75, .8))
+	assert.Equal(t, CubeLeft, face)
+
+	// and are projected onto it, so a scaled point maps to the same texture coordinates
+	face, u, v := CubeMap(NewPoint(-2, 1, -.5))
+	assert.Equal(t, CubeLeft, face)
+	assert.True(t, eq(.375, u))
+	assert.True(t, eq(.75, v))
+}
+
+func TestUVCheckersPattern_UVAt(t *testing.T) {
+	p := NewUVCheckersPattern(2, 2, black, white)
+	tests := []struct {
+		u, v     float64
+		expected Color
+	}{
+		{0, 0, black},
+		{.5, 0, white},
+		{0, .5, white},
+		{.5, .5, black},
+		{1, 1, black},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, p.UVAt(test.u, test.v))
+	}
+}
+
+func TestUVAlignCheckPattern_UVAt(t *testing.T) {
+	main := NewColor(1, 1, 1)
+	ul := NewColor(1, 0, 0)
+	ur := NewColor(1, 1, 0)
+	bl := NewColor(0, 1, 0)
+	br := NewColor(0, 1, 1)
+	p := NewUVAlignCheckPattern(main, ul, ur, bl, br)
+
+	assert.Equal(t, main, p.UVAt(.5, .5))
+	assert.Equal(t, ul, p.UVAt(.1, .9))
+	assert.Equal(t, ur, p.UVAt(.9, .9))
+	assert.Equal(t, bl, p.UVAt(.1, .1))
+	assert.Equal(t, br, p.UVAt(.9, .1))
+}
+
+func TestTextureMapPattern_AtObject(t *testing.T) {
+	checkers := NewUVCheckersPattern(16, 8, black, white)
+	p := NewTextureMapPattern(checkers, SphericalMapping)
+	s := NewSphere()
+	tests := []struct {
+		point    Tuple
+		expected Color
+	}{
+		{NewPoint(.4315, .4670, .7719), white},
+		{NewPoint(-.9654, .2552, -.0534), black},
+		{NewPoint(.1039, .7090, .6975), white},
+		{NewPoint(-.4986, -.7856, -.3663), black},
+		{NewPoint(-.0317, -.9395, .3411), black},
+		{NewPoint(.4809, -.7721, .4154), black},
+		{NewPoint(.0285, -.9612, -.2745), black},
+		{NewPoint(-.5734, -.2162, -.7903), white},
+		{NewPoint(.7688, -.1470, .6223), black},
+		{NewPoint(-.7652, .2175, .6060), black},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, p.AtObject(s, test.point), test.point.String())
+	}
+
+	// pattern and object transformations are applied before mapping
+	s.Transform = NewScaling(2, 2, 2)
+	p.SetTransform(NewRotationY(math.Pi))
+	assert.Equal(t, p.At(NewPoint(-.4315, .4670, -.7719)), p.AtObject(s, NewPoint(.863, .934, 1.5438)))
+}
+
+func TestCubeMapPattern_At(t *testing.T) {
+	red := NewColor(1, 0, 0)
+	yellow := NewColor(1, 1, 0)
+	brown := NewColor(1, .5, 0)
+	green := NewColor(0, 1, 0)
+	cyan := NewColor(0, 1, 1)
+	blue := NewColor(0, 0, 1)
+	purple := NewColor(1, 0, 1)
+	left := NewUVAlignCheckPattern(yellow, cyan, red, blue, brown)
+	front := NewUVAlignCheckPattern(cyan, red, yellow, brown, green)
+	right := NewUVAlignCheckPattern(red, yellow, purple, green, white)
+	back := NewUVAlignCheckPattern(green, purple, cyan, white, blue)
+	up := NewUVAlignCheckPattern(brown, cyan, purple, red, yellow)
+	down := NewUVAlignCheckPattern(purple, brown, green, blue, white)
+	p := NewCubeMapPattern(left, front, right, back, up, down)
+
+	tests := []struct {
+		point    Tuple
+		expected Color
+	}{
+		{NewPoint(-1, 0, 0), yellow},
+		{NewPoint(-1, .9, -.9), cyan},
+		{NewPoint(-1, .9, .9), red},
+		{NewPoint(-1, -.9, -.9), blue},
+		{NewPoint(-1, -.9, .9), brown},
+		{NewPoint(0, 0, 1), cyan},
+		{NewPoint(-.9, .9, 1), red},
+		{NewPoint(.9, .9, 1), yellow},
+		{NewPoint(1, 0, 0), red},
+		{NewPoint(1, .9, .9), yellow},
+		{NewPoint(0, 0, -1), green},
+		{NewPoint(.9, .9, -1), purple},
+		{NewPoint(0, 1, 0), brown},
+		{NewPoint(-.9, 1, -.9), cyan},
+		{NewPoint(.9, 1, .9), yellow},
+		{NewPoint(0, -1, 0), purple},
+		{NewPoint(-.9, -1, .9), brown},
+		{NewPoint(.9, -1, -.9), white},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, p.At(test.point), test.point.String())
+	}
+}
@@ -4,6 +4,9 @@ package rt
 type World struct {
 	Light   *PointLight
 	Objects []Shape
+
+	// Environment, if set, is seen by rays that escape the world, which are otherwise black.
+	Environment Environment
 }
 
 // NewWorld creates a new World.
@@ -39,17 +42,27 @@ func (w *World) ColorAt(ray *Ray) Color {
 	xs := w.Intersect(ray)
 	hit := xs.Hit()
 	if hit == nil {
-		return NewColor(0, 0, 0)
+		return w.background(ray)
 	}
 
 	info := hit.PrepareComputations(ray)
 	return w.ShadeHit(info)
 }
 
+// Returns the light arriving along a ray that escapes the world.
+func (w *World) background(ray *Ray) Color {
+	if w.Environment == nil {
+		return NewColor(0, 0, 0)
+	}
+
+	return w.Environment.Radiance(ray.Direction)
+}
+
 // Intersect returns a set of points where a ray intersects objects in the world.
 func (w *World) Intersect(ray *Ray) IntersectionSet {
 	xs := NewIntersectionSet()
 	for _, obj := range w.Objects {
+		ray.stats.addIntersectionTest(obj)
 		xs = xs.Join(obj.Intersect(ray))
 	}
 
@@ -58,19 +71,44 @@ func (w *World) Intersect(ray *Ray) IntersectionSet {
 
 // IsShadowed returns true if the specified point is in a shadow.
 func (w *World) IsShadowed(point Tuple) bool {
-	v := w.Light.Position.Subtract(point)
+	return w.isOccluded(point, w.Light.Position, 0, nil)
+}
+
+// Returns true if any object lies between two points at a moment in time, counting the shadow
+// ray traced between them in stats.
+func (w *World) isOccluded(from Tuple, to Tuple, time float64, stats *RenderStats) bool {
+	v := to.Subtract(from)
 	distance := v.Magnitude()
 	direction := v.Normalize()
 
-	ray := NewRay(point, direction)
+	ray := &Ray{Origin: from, Direction: direction, Time: time, stats: stats}
+	stats.addShadowRay()
 	intersections := w.Intersect(ray)
 
 	hit := intersections.Hit()
-	return hit != nil && hit.T < distance
+	return hit != nil && hit.T < distance-EPSILON
+}
+
+// Returns the emissive objects in the world whose surfaces can be sampled to light others.
+func (w *World) emitters() []SurfaceSampler {
+	var emitters []SurfaceSampler
+	for _, obj := range w.Objects {
+		if sampler, ok := obj.(SurfaceSampler); ok && obj.GetMaterial().IsEmissive() {
+			emitters = append(emitters, sampler)
+		}
+	}
+
+	return emitters
 }
 
 // ShadeHit returns the color generated by lighting based on the provided intersection info.
+// Objects glow with their material's emission even when they're in shadow or the world has no
+// light.
 func (w *World) ShadeHit(info *IntersectionInfo) Color {
-	isShadowed := w.IsShadowed(info.OverPoint)
+	if w.Light == nil {
+		return info.Object.GetMaterial().EmissionAt(info.Object, info.Point)
+	}
+
+	isShadowed := w.isOccluded(info.OverPoint, w.Light.Position, info.Time, info.stats)
 	return info.Object.GetMaterial().Lighting(info.Object, w.Light, info.Point, info.EyeV, info.NormalV, isShadowed)
 }
@@ -116,6 +116,11 @@ func TestWorld_ColorAt(t *testing.T) {
 	c := w.ColorAt(r)
 	assert.True(t, c.Equals(NewColor(0, 0, 0)))
 
+	// ray misses and sees the environment
+	w.Environment = NewSolidEnvironment(NewColor(.2, .4, .6))
+	c = w.ColorAt(r)
+	assert.True(t, c.Equals(NewColor(.2, .4, .6)))
+
 	// ray hits
 	w = NewDefaultWorld()
 	r = NewRay(NewPoint(0, 0, -5), NewVector(0, 0, 1))
@@ -132,3 +137,34 @@ func TestWorld_ColorAt(t *testing.T) {
 	c = w.ColorAt(r)
 	assert.True(t, c.Equals(inner.Material.Color))
 }
+
+func TestWorld_ShadeHit_emission(t *testing.T) {
+	// emissive objects glow in shadow
+	w := NewWorld()
+	w.Light = NewPointLight(NewPoint(0, 0, -10), NewColor(1, 1, 1))
+	s1 := NewSphere()
+	s2 := NewSphere()
+	s2.Transform = NewTranslation(0, 0, 10)
+	s2.Material.Emission = NewColor(1, .5, 0)
+	w.AddObjects(s1, s2)
+	r := NewRay(NewPoint(0, 0, 5), NewVector(0, 0, 1))
+	info := NewIntersection(4, s2).PrepareComputations(r)
+	assert.True(t, NewColor(1.1, .6, .1).Equals(w.ShadeHit(info)))
+
+	// and without a light
+	w.Light = nil
+	assert.True(t, NewColor(1, .5, 0).Equals(w.ShadeHit(info)))
+}
+
+func TestWorld_emitters(t *testing.T) {
+	w := NewDefaultWorld()
+	assert.Empty(t, w.emitters())
+
+	// planes are infinite, so their surfaces can't be sampled
+	plane := NewPlane()
+	plane.Material.Emission = NewColor(1, 1, 1)
+	sphere := NewSphere()
+	sphere.Material.Emission = NewColor(1, 1, 1)
+	w.AddObjects(plane, sphere)
+	assert.Equal(t, []SurfaceSampler{sphere}, w.emitters())
+}